@@ -0,0 +1,490 @@
+package bench
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/search"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// index types Run can build
+const (
+	IndexFlat    = "flat"
+	IndexHNSW    = "hnsw"
+	IndexIVF     = "ivf"
+	IndexDiskANN = "diskann"
+)
+
+// Config says which index to build and how to search it
+type Config struct {
+	// Index is IndexFlat, IndexHNSW, IndexIVF or IndexDiskANN
+	Index string
+
+	// K is how many neighbors every query asks for
+	K int
+
+	// Search lists the values of the search parameter to measure, each on
+	// the same build: ef for HNSW, nprobe for IVF and the candidate list
+	// size for DiskANN. The index's default is measured when it is empty.
+	// Quantized HNSW graphs search with their default ef.
+	Search []int
+
+	HNSW index.HNSWConfig
+
+	// Workers is how many goroutines insert into the HNSW graph, one per
+	// CPU when it is zero
+	Workers int
+
+	// Oversample is how many times k candidates a quantized HNSW graph
+	// gathers before rescoring them
+	Oversample int
+
+	IVF index.IVFConfig
+
+	DiskANN index.DiskANNConfig
+
+	// Dir is where the DiskANN file is written, a temporary directory when
+	// it is empty
+	Dir string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Index:      IndexHNSW,
+		K:          10,
+		HNSW:       index.DefaultHNSWConfig(),
+		Oversample: 4,
+		IVF:        index.DefaultIVFConfig(),
+		DiskANN:    index.DefaultDiskANNConfig(),
+	}
+}
+
+// Report is the outcome of one search setting, written as JSON so runs on
+// different commits can be compared
+type Report struct {
+	Dataset string `json:"dataset"`
+	Metric  string `json:"metric"`
+	Train   int    `json:"train"`
+	Queries int    `json:"queries"`
+	Dims    int    `json:"dims"`
+
+	Index  string         `json:"index"`
+	Params map[string]any `json:"params"`
+	K      int            `json:"k"`
+	Search int            `json:"search,omitempty"`
+
+	// Recall is the share of the true k nearest neighbors found, averaged
+	// over the queries
+	Recall float64 `json:"recall"`
+	QPS    float64 `json:"qps"`
+
+	// latencies of single queries, in milliseconds
+	MeanLatency float64 `json:"mean_latency_ms"`
+	P50Latency  float64 `json:"p50_latency_ms"`
+	P99Latency  float64 `json:"p99_latency_ms"`
+
+	BuildSeconds float64 `json:"build_seconds"`
+
+	// MemoryBytes is how much the heap grew by building the index
+	MemoryBytes int64 `json:"memory_bytes"`
+
+	// DiskBytes is the size of the index file of a DiskANN index
+	DiskBytes int64 `json:"disk_bytes,omitempty"`
+
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+	Time      string `json:"time"`
+}
+
+// searcher answers a query with the indexes of the train vectors it finds
+type searcher interface {
+	search(query []float32, k int, param int) ([]int, error)
+	close() error
+}
+
+// Run builds the index config describes from the dataset's train vectors,
+// then runs every query once per search setting and reports each
+func Run(dataset *Dataset, config Config) ([]Report, error) {
+	if config.K <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", config.K)
+	}
+	for i, neighbors := range dataset.Neighbors[:len(dataset.Test)] {
+		if len(neighbors) < config.K {
+			return nil, fmt.Errorf("query %d has %d true neighbors, fewer than k=%d", i, len(neighbors), config.K)
+		}
+	}
+
+	// the indexes rank by Euclidean distance, which on unit vectors ranks
+	// by angle
+	train, test := dataset.Train, dataset.Test
+	if dataset.Metric == MetricAngular {
+		train, test = normalized(train), normalized(test)
+	}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	s, params, err := build(train, config)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = s.close() }()
+	buildTime := time.Since(start)
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	memory := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	if memory < 0 {
+		memory = 0
+	}
+
+	var diskBytes int64
+	if disk, ok := s.(*diskSearcher); ok {
+		if info, err := os.Stat(disk.path); err == nil {
+			diskBytes = info.Size()
+		}
+	}
+
+	settings := config.Search
+	if len(settings) == 0 {
+		settings = []int{0}
+	}
+	reports := make([]Report, 0, len(settings))
+	for _, setting := range settings {
+		report := Report{
+			Dataset:      dataset.Name,
+			Metric:       dataset.Metric,
+			Train:        len(train),
+			Queries:      len(test),
+			Dims:         dataset.Dims(),
+			Index:        config.Index,
+			Params:       params,
+			K:            config.K,
+			Search:       setting,
+			BuildSeconds: buildTime.Seconds(),
+			MemoryBytes:  memory,
+			DiskBytes:    diskBytes,
+			Commit:       commit(),
+			GoVersion:    runtime.Version(),
+			Time:         time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := measure(s, test, dataset.Neighbors, setting, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	runtime.KeepAlive(s)
+
+	return reports, nil
+}
+
+// measure runs every query one after another, timing each
+func measure(s searcher, test [][]float32, neighbors [][]int, setting int, report *Report) error {
+	latencies := make([]float64, len(test))
+	found := 0
+	start := time.Now()
+	for q, query := range test {
+		queryStart := time.Now()
+		results, err := s.search(query, report.K, setting)
+		if err != nil {
+			return fmt.Errorf("query %d failed: %v", q, err)
+		}
+		latencies[q] = float64(time.Since(queryStart)) / float64(time.Millisecond)
+
+		truth := make(map[int]bool, report.K)
+		for _, id := range neighbors[q][:report.K] {
+			truth[id] = true
+		}
+		for _, id := range results {
+			if truth[id] {
+				found++
+				delete(truth, id)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	report.Recall = float64(found) / float64(len(test)*report.K)
+	report.QPS = float64(len(test)) / elapsed.Seconds()
+	sort.Float64s(latencies)
+	report.MeanLatency = float64(elapsed) / float64(time.Millisecond) / float64(len(test))
+	report.P50Latency = percentile(latencies, 0.5)
+	report.P99Latency = percentile(latencies, 0.99)
+	return nil
+}
+
+// percentile picks the p-th quantile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// commit is the VCS revision the binary was built from, if recorded
+func commit() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// build creates the index and returns the parameters it was built with
+func build(train [][]float32, config Config) (searcher, map[string]any, error) {
+	switch config.Index {
+	case IndexFlat:
+		return newFlatSearcher(train), map[string]any{}, nil
+	case IndexHNSW:
+		return buildHNSW(train, config)
+	case IndexIVF:
+		return buildIVF(train, config)
+	case IndexDiskANN:
+		return buildDiskANN(train, config)
+	default:
+		return nil, nil, fmt.Errorf("unknown index type %q", config.Index)
+	}
+}
+
+// flatSearcher scans every vector, from one contiguous array
+type flatSearcher struct {
+	dims    int
+	vectors []float32
+}
+
+func newFlatSearcher(train [][]float32) *flatSearcher {
+	dims := len(train[0])
+	vectors := make([]float32, 0, len(train)*dims)
+	for _, vector := range train {
+		vectors = append(vectors, vector...)
+	}
+	return &flatSearcher{dims: dims, vectors: vectors}
+}
+
+func (f *flatSearcher) search(query []float32, k int, _ int) ([]int, error) {
+	nearest := make([]int, 0, k+1)
+	distances := make([]float64, 0, k+1)
+	for i := 0; i*f.dims < len(f.vectors); i++ {
+		distance := squaredL2(query, f.vectors[i*f.dims:(i+1)*f.dims])
+		if len(nearest) == k && distance >= distances[k-1] {
+			continue
+		}
+		at := sort.Search(len(distances), func(j int) bool { return distances[j] > distance })
+		nearest = append(nearest[:at], append([]int{i}, nearest[at:]...)...)
+		distances = append(distances[:at], append([]float64{distance}, distances[at:]...)...)
+		if len(nearest) > k {
+			nearest, distances = nearest[:k], distances[:k]
+		}
+	}
+	return nearest, nil
+}
+
+func (f *flatSearcher) close() error {
+	return nil
+}
+
+type hnswSearcher struct {
+	graph *index.HNSW
+	train [][]float32
+	// oversample is set for quantized graphs, which rescore candidates
+	// with the train vectors
+	oversample int
+}
+
+func buildHNSW(train [][]float32, config Config) (searcher, map[string]any, error) {
+	hnswConfig := config.HNSW
+	if hnswConfig.Quantization == index.QuantizationInt8 {
+		scalar, err := search.TrainScalar(train)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not train int8 quantization: %v", err)
+		}
+		hnswConfig.Scalar = scalar
+	}
+
+	graph := index.NewHNSW(hnswConfig)
+	if err := graph.InsertBatch(ids(len(train)), train, config.Workers); err != nil {
+		return nil, nil, err
+	}
+
+	params := map[string]any{
+		"m":               hnswConfig.M,
+		"ef_construction": hnswConfig.EfConstruction,
+		"quantization":    hnswConfig.Quantization,
+	}
+	s := &hnswSearcher{graph: graph, train: train}
+	if hnswConfig.Quantization != index.QuantizationNone {
+		s.oversample = max(config.Oversample, 1)
+		params["oversample"] = s.oversample
+	}
+	return s, params, nil
+}
+
+func (h *hnswSearcher) search(query []float32, k int, ef int) ([]int, error) {
+	var results index.SearchResults
+	var err error
+	switch {
+	case h.oversample > 0:
+		results, err = h.graph.SearchRescored(query, k, h.oversample, func(id string) ([]float32, bool) {
+			i, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, false
+			}
+			return h.train[i], true
+		})
+	case ef > 0:
+		results, err = h.graph.SearchWithAccuracy(query, k, ef)
+	default:
+		results, err = h.graph.Search(query, k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return positions(results)
+}
+
+func (h *hnswSearcher) close() error {
+	return nil
+}
+
+type ivfSearcher struct {
+	ivf    *index.IVF
+	nprobe int
+}
+
+func buildIVF(train [][]float32, config Config) (searcher, map[string]any, error) {
+	ivfConfig := config.IVF
+	// the whole train set is added before training
+	ivfConfig.TrainAfter = 0
+	ivf := index.NewIVF(ivfConfig)
+	for i, id := range ids(len(train)) {
+		if err := ivf.Add(id, train[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := ivf.Train(); err != nil {
+		return nil, nil, fmt.Errorf("could not train ivf: %v", err)
+	}
+
+	params := map[string]any{
+		"nlists":       ivfConfig.NLists,
+		"pq_subspaces": ivfConfig.PQ.Subspaces,
+	}
+	return &ivfSearcher{ivf: ivf, nprobe: ivfConfig.NProbe}, params, nil
+}
+
+func (s *ivfSearcher) search(query []float32, k int, nprobe int) ([]int, error) {
+	if nprobe <= 0 {
+		nprobe = s.nprobe
+	}
+	results, err := s.ivf.Search(query, k, nprobe)
+	if err != nil {
+		return nil, err
+	}
+	return positions(results)
+}
+
+func (s *ivfSearcher) close() error {
+	return nil
+}
+
+type diskSearcher struct {
+	disk *index.DiskANN
+	path string
+	// dir is removed on close when Run created it
+	dir  string
+	beam int
+}
+
+func buildDiskANN(train [][]float32, config Config) (searcher, map[string]any, error) {
+	dir, temporary := config.Dir, ""
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "ghastly-bench"); err != nil {
+			return nil, nil, fmt.Errorf("could not create a directory for the index: %v", err)
+		}
+		temporary = dir
+	}
+
+	path := filepath.Join(dir, "vectors.diskann")
+	disk, err := index.BuildDiskANN(path, ids(len(train)), train, config.DiskANN)
+	if err != nil {
+		if temporary != "" {
+			_ = os.RemoveAll(temporary)
+		}
+		return nil, nil, err
+	}
+
+	params := map[string]any{
+		"degree":       config.DiskANN.Degree,
+		"build_list":   config.DiskANN.BuildList,
+		"alpha":        config.DiskANN.Alpha,
+		"beam_width":   config.DiskANN.BeamWidth,
+		"pq_subspaces": config.DiskANN.PQ.Subspaces,
+	}
+	return &diskSearcher{disk: disk, path: path, dir: temporary, beam: config.DiskANN.BeamWidth}, params, nil
+}
+
+func (d *diskSearcher) search(query []float32, k int, list int) ([]int, error) {
+	var results index.SearchResults
+	var err error
+	if list > 0 {
+		results, err = d.disk.SearchWithBeam(query, k, list, d.beam)
+	} else {
+		results, err = d.disk.Search(query, k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return positions(results)
+}
+
+func (d *diskSearcher) close() error {
+	err := d.disk.Close()
+	if d.dir != "" {
+		_ = os.RemoveAll(d.dir)
+	}
+	return err
+}
+
+// ids names the train vectors by their position
+func ids(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+	}
+	return names
+}
+
+// positions turns results back into train positions
+func positions(results index.SearchResults) ([]int, error) {
+	found := make([]int, len(results))
+	for i, result := range results {
+		position, err := strconv.Atoi(result.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected id %q", result.ID)
+		}
+		found[i] = position
+	}
+	return found, nil
+}
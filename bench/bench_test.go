@@ -0,0 +1,156 @@
+package bench
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type BenchTestSuite struct {
+	suite.Suite
+	dataset *Dataset
+}
+
+func (s *BenchTestSuite) SetupTest() {
+	rng := rand.New(rand.NewSource(3))
+	vectors := func(n int) [][]float32 {
+		rows := make([][]float32, n)
+		for i := range rows {
+			rows[i] = make([]float32, 16)
+			for d := range rows[i] {
+				rows[i][d] = float32(rng.NormFloat64())
+			}
+		}
+		return rows
+	}
+	s.dataset = &Dataset{Name: "gaussian", Train: vectors(1000), Test: vectors(50), Metric: MetricEuclidean}
+	s.dataset.Neighbors = s.dataset.GroundTruth(10)
+}
+
+// writeVecs writes rows in the .fvecs, .bvecs or .ivecs format
+func writeVecs(path string, rows [][]float64) error {
+	b := make([]byte, 0)
+	for _, row := range rows {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(row)))
+		for _, v := range row {
+			switch filepath.Ext(path) {
+			case ".fvecs":
+				b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v)))
+			case ".bvecs":
+				b = append(b, byte(v))
+			default:
+				b = binary.LittleEndian.AppendUint32(b, uint32(int32(v)))
+			}
+		}
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func (s *BenchTestSuite) TestLoadVecs() {
+	dir := s.T().TempDir()
+	base := [][]float64{{0, 0}, {10, 10}, {1, 1}, {250, 3}}
+	require.NoError(s.T(), writeVecs(filepath.Join(dir, "base.fvecs"), base))
+	require.NoError(s.T(), writeVecs(filepath.Join(dir, "base.bvecs"), base))
+	require.NoError(s.T(), writeVecs(filepath.Join(dir, "query.fvecs"), [][]float64{{0.9, 0.9}}))
+	require.NoError(s.T(), writeVecs(filepath.Join(dir, "gt.ivecs"), [][]float64{{2, 0, 1}}))
+
+	vectors, err := ReadVecs(filepath.Join(dir, "base.bvecs"), 0)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), [][]float32{{0, 0}, {10, 10}, {1, 1}, {250, 3}}, vectors)
+
+	dataset, err := LoadVecs(filepath.Join(dir, "base.fvecs"), filepath.Join(dir, "query.fvecs"), filepath.Join(dir, "gt.ivecs"), 0, "l2")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "base", dataset.Name)
+	assert.Len(s.T(), dataset.Train, 4)
+	assert.Equal(s.T(), [][]int{{2, 0, 1}}, dataset.Neighbors)
+
+	// cutting the base short computes the ground truth instead
+	dataset, err = LoadVecs(filepath.Join(dir, "base.fvecs"), filepath.Join(dir, "query.fvecs"), filepath.Join(dir, "gt.ivecs"), 3, "l2")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), dataset.Train, 3)
+	assert.Equal(s.T(), [][]int{{2, 0, 1}}, dataset.Neighbors)
+
+	// by angle {10, 10} matches the query as well as {1, 1} does
+	dataset, err = LoadVecs(filepath.Join(dir, "base.fvecs"), filepath.Join(dir, "query.fvecs"), "", 0, "angular")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []int{1, 2}, dataset.Neighbors[0][:2])
+
+	_, err = LoadVecs(filepath.Join(dir, "base.fvecs"), filepath.Join(dir, "query.fvecs"), "", 0, "hamming")
+	assert.Error(s.T(), err)
+	_, err = ReadVecs(filepath.Join(dir, "gt.ivecs"), 0)
+	assert.Error(s.T(), err)
+}
+
+func (s *BenchTestSuite) TestRun() {
+	config := DefaultConfig()
+	config.HNSW.M = 12
+	config.IVF.NLists = 16
+	config.DiskANN.Degree = 16
+	config.DiskANN.BuildList = 40
+	config.DiskANN.SearchList = 40
+	config.DiskANN.PQ.Subspaces = 4
+	config.Dir = s.T().TempDir()
+
+	for _, test := range []struct {
+		index  string
+		search []int
+		recall float64
+	}{
+		{IndexFlat, nil, 1},
+		{IndexHNSW, []int{16, 64}, 0.9},
+		{IndexIVF, []int{2, 16}, 0.99},
+		{IndexDiskANN, []int{40}, 0.85},
+	} {
+		config.Index = test.index
+		config.Search = test.search
+		reports, err := Run(s.dataset, config)
+		require.NoError(s.T(), err, test.index)
+		require.Len(s.T(), reports, max(len(test.search), 1))
+
+		last := reports[len(reports)-1]
+		assert.Equal(s.T(), test.index, last.Index)
+		assert.Equal(s.T(), 1000, last.Train)
+		assert.Equal(s.T(), 50, last.Queries)
+		assert.Equal(s.T(), 16, last.Dims)
+		assert.GreaterOrEqual(s.T(), last.Recall, test.recall, test.index)
+		assert.Greater(s.T(), last.QPS, 0.0)
+		assert.LessOrEqual(s.T(), last.P50Latency, last.P99Latency)
+		// a wider search doesn't find fewer neighbors
+		assert.GreaterOrEqual(s.T(), last.Recall, reports[0].Recall)
+		if test.index == IndexDiskANN {
+			assert.Greater(s.T(), last.DiskBytes, int64(0))
+		}
+
+		encoded, err := json.Marshal(reports)
+		require.NoError(s.T(), err)
+		assert.Contains(s.T(), string(encoded), `"recall"`)
+	}
+
+	config.Index = IndexHNSW
+	config.Search = nil
+	config.HNSW.Quantization = index.QuantizationInt8
+	reports, err := Run(s.dataset, config)
+	require.NoError(s.T(), err)
+	assert.GreaterOrEqual(s.T(), reports[0].Recall, 0.8)
+	assert.Equal(s.T(), 4, reports[0].Params["oversample"])
+
+	config.Index = "lsh"
+	_, err = Run(s.dataset, config)
+	assert.Error(s.T(), err)
+	config.Index = IndexFlat
+	config.K = 20
+	_, err = Run(s.dataset, config)
+	assert.Error(s.T(), err)
+}
+
+func TestBenchSuite(t *testing.T) {
+	suite.Run(t, new(BenchTestSuite))
+}
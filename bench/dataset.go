@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// MetricEuclidean ranks neighbors by L2 distance
+	MetricEuclidean = "euclidean"
+
+	// MetricAngular ranks neighbors by the angle between the vectors
+	MetricAngular = "angular"
+)
+
+// Dataset is a set of vectors to index and queries to run against them
+type Dataset struct {
+	Name string
+
+	// Train holds the vectors the index is built from
+	Train [][]float32
+
+	// Test holds the queries
+	Test [][]float32
+
+	// Neighbors[i] are the indexes into Train of the nearest neighbors of
+	// Test[i], nearest first
+	Neighbors [][]int
+
+	// Metric is MetricEuclidean or MetricAngular
+	Metric string
+}
+
+// LoadHDF5 reads a dataset in the ann-benchmarks format: an HDF5 file with
+// train, test and neighbors datasets and the metric in its distance
+// attribute
+func LoadHDF5(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat %s: %v", path, err)
+	}
+	h5, err := openHDF5(file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	dataset := &Dataset{Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	if dataset.Train, err = h5.floats("train"); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if dataset.Test, err = h5.floats("test"); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if dataset.Neighbors, err = h5.ints("neighbors"); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	distance, err := h5.attribute("distance")
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if distance == "" && strings.Contains(dataset.Name, MetricAngular) {
+		distance = MetricAngular
+	}
+	if dataset.Metric, err = parseMetric(distance); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	return dataset, dataset.validate()
+}
+
+// LoadVecs reads a dataset in the TEXMEX format of SIFT and GIST: base
+// vectors and queries in .fvecs or .bvecs files, and the ground truth in
+// an .ivecs file. Only the first limit base vectors are read when limit is
+// positive. Without a ground truth file, or when limit cuts the base
+// vectors short, the ground truth is computed exactly with metric.
+func LoadVecs(base, queries, groundTruth string, limit int, metric string) (*Dataset, error) {
+	metric, err := parseMetric(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset := &Dataset{
+		Name:   strings.TrimSuffix(filepath.Base(base), filepath.Ext(base)),
+		Metric: metric,
+	}
+	if dataset.Train, err = ReadVecs(base, limit); err != nil {
+		return nil, err
+	}
+	if dataset.Test, err = ReadVecs(queries, 0); err != nil {
+		return nil, err
+	}
+	if groundTruth != "" && limit <= 0 {
+		if dataset.Neighbors, err = ReadIvecs(groundTruth, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		dataset.Neighbors = dataset.GroundTruth(100)
+	}
+
+	return dataset, dataset.validate()
+}
+
+// parseMetric accepts the metric names ann-benchmarks and the search
+// package use
+func parseMetric(metric string) (string, error) {
+	switch strings.ToLower(metric) {
+	case "", MetricEuclidean, "l2":
+		return MetricEuclidean, nil
+	case MetricAngular, "cosine":
+		return MetricAngular, nil
+	default:
+		return "", fmt.Errorf("unsupported metric %q", metric)
+	}
+}
+
+func (d *Dataset) validate() error {
+	if len(d.Train) == 0 || len(d.Test) == 0 {
+		return fmt.Errorf("dataset %s has %d train and %d test vectors", d.Name, len(d.Train), len(d.Test))
+	}
+	if len(d.Neighbors) < len(d.Test) {
+		return fmt.Errorf("dataset %s has neighbors for %d of %d queries", d.Name, len(d.Neighbors), len(d.Test))
+	}
+	dim := len(d.Train[0])
+	for _, vectors := range [][][]float32{d.Train, d.Test} {
+		for i, vector := range vectors {
+			if len(vector) != dim {
+				return fmt.Errorf("dataset %s mixes %d and %d dimensions at vector %d", d.Name, dim, len(vector), i)
+			}
+		}
+	}
+	return nil
+}
+
+// SetMetric changes the metric, computing the ground truth again when it
+// differs
+func (d *Dataset) SetMetric(metric string) error {
+	metric, err := parseMetric(metric)
+	if err != nil {
+		return err
+	}
+	if metric != d.Metric {
+		d.Metric = metric
+		d.Neighbors = d.GroundTruth(100)
+	}
+	return nil
+}
+
+// Dims is the dimension of the vectors
+func (d *Dataset) Dims() int {
+	return len(d.Train[0])
+}
+
+// Limit keeps only the first n queries when n is positive
+func (d *Dataset) Limit(n int) {
+	if n > 0 && n < len(d.Test) {
+		d.Test = d.Test[:n]
+		d.Neighbors = d.Neighbors[:n]
+	}
+}
+
+// GroundTruth finds the k nearest train vectors of every query exactly,
+// spreading the queries over every CPU
+func (d *Dataset) GroundTruth(k int) [][]int {
+	if k > len(d.Train) {
+		k = len(d.Train)
+	}
+	train, test := d.Train, d.Test
+	if d.Metric == MetricAngular {
+		train, test = normalized(train), normalized(test)
+	}
+
+	neighbors := make([][]int, len(test))
+	queries := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range queries {
+				// the k nearest so far, nearest first
+				nearest := make([]int, 0, k+1)
+				distances := make([]float64, 0, k+1)
+				for i, vector := range train {
+					distance := squaredL2(test[q], vector)
+					if len(nearest) == k && distance >= distances[k-1] {
+						continue
+					}
+					at := sort.Search(len(distances), func(j int) bool { return distances[j] > distance })
+					nearest = append(nearest[:at], append([]int{i}, nearest[at:]...)...)
+					distances = append(distances[:at], append([]float64{distance}, distances[at:]...)...)
+					if len(nearest) > k {
+						nearest, distances = nearest[:k], distances[:k]
+					}
+				}
+				neighbors[q] = nearest
+			}
+		}()
+	}
+	for q := range test {
+		queries <- q
+	}
+	close(queries)
+	wg.Wait()
+
+	return neighbors
+}
+
+// normalized returns unit length copies of vectors, whose Euclidean order
+// is their angular order
+func normalized(vectors [][]float32) [][]float32 {
+	unit := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		unit[i] = search.Normalize(append([]float32(nil), vector...))
+	}
+	return unit
+}
+
+func squaredL2(a, b []float32) float64 {
+	sum := 0.0
+	for i := range a {
+		diff := float64(a[i]) - float64(b[i])
+		sum += diff * diff
+	}
+	return sum
+}
@@ -0,0 +1,825 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// hdf5Signature starts the superblock, at offset 0 or a power of two
+// from 512 on
+var hdf5Signature = []byte{0x89, 'H', 'D', 'F', '\r', '\n', 0x1a, '\n'}
+
+// object header message types
+const (
+	hdf5Dataspace    = 0x01
+	hdf5LinkInfo     = 0x02
+	hdf5Datatype     = 0x03
+	hdf5Link         = 0x06
+	hdf5Layout       = 0x08
+	hdf5Attribute    = 0x0c
+	hdf5Continuation = 0x10
+	hdf5SymbolTable  = 0x11
+)
+
+// datatype classes
+const (
+	hdf5FixedPoint = 0
+	hdf5Float      = 1
+	hdf5String     = 3
+	hdf5VarLength  = 9
+)
+
+// hdf5File reads the subset of HDF5 the ann-benchmarks datasets use: the
+// root group's members, contiguous or compact numeric datasets and string
+// attributes. Chunked and compressed datasets aren't supported.
+type hdf5File struct {
+	r    io.ReaderAt
+	size int64
+	base uint64
+
+	offsetSize int
+	lengthSize int
+
+	// root is the address of the root group's object header
+	root uint64
+}
+
+// hdf5Message is one message of an object header
+type hdf5Message struct {
+	kind  uint16
+	flags uint8
+	data  []byte
+}
+
+// hdf5Type is the part of a datatype needed to decode numbers and strings
+type hdf5Type struct {
+	class     int
+	size      int
+	signed    bool
+	bigEndian bool
+	// varString is set for variable length strings
+	varString bool
+}
+
+// hdf5Dataset is a dataset's shape, type and where its data is
+type hdf5Dataset struct {
+	dims    []uint64
+	typ     hdf5Type
+	address uint64
+	// compact holds the data of a compact dataset
+	compact []byte
+}
+
+func openHDF5(r io.ReaderAt, size int64) (*hdf5File, error) {
+	f := &hdf5File{r: r, size: size}
+
+	offset := int64(0)
+	for {
+		if offset+int64(len(hdf5Signature)) > size {
+			return nil, fmt.Errorf("not an HDF5 file")
+		}
+		signature := make([]byte, len(hdf5Signature))
+		if _, err := r.ReadAt(signature, offset); err != nil {
+			return nil, fmt.Errorf("could not read superblock: %v", err)
+		}
+		if bytes.Equal(signature, hdf5Signature) {
+			break
+		}
+		if offset == 0 {
+			offset = 512
+		} else {
+			offset *= 2
+		}
+	}
+
+	superblock := make([]byte, 256)
+	n, err := r.ReadAt(superblock, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("could not read superblock: %v", err)
+	}
+	c := &hdf5Cursor{b: superblock[:n], pos: 8}
+	version := c.u8()
+	switch version {
+	case 0, 1:
+		c.skip(4)
+		f.offsetSize, f.lengthSize = int(c.u8()), int(c.u8())
+		c.skip(1 + 2 + 2 + 4)
+		if version == 1 {
+			c.skip(4)
+		}
+		f.base = f.offset(c)
+		c.skip(3 * f.offsetSize)
+		// the root group's symbol table entry
+		c.skip(f.offsetSize)
+		f.root = f.offset(c)
+	case 2, 3:
+		f.offsetSize, f.lengthSize = int(c.u8()), int(c.u8())
+		c.skip(1)
+		f.base = f.offset(c)
+		c.skip(2 * f.offsetSize)
+		f.root = f.offset(c)
+	default:
+		return nil, fmt.Errorf("unsupported superblock version %d", version)
+	}
+	if c.err != nil {
+		return nil, fmt.Errorf("superblock is truncated")
+	}
+	if f.offsetSize < 1 || f.offsetSize > 8 || f.lengthSize < 1 || f.lengthSize > 8 {
+		return nil, fmt.Errorf("unsupported address sizes %d and %d", f.offsetSize, f.lengthSize)
+	}
+	if f.base == 0 {
+		f.base = uint64(offset)
+	}
+
+	return f, nil
+}
+
+// floats reads the two dimensional numeric dataset at name in the root
+// group as rows of float32
+func (f *hdf5File) floats(name string) ([][]float32, error) {
+	rows := make([][]float32, 0)
+	err := f.rows(name, func(row []float64) {
+		vector := make([]float32, len(row))
+		for i, v := range row {
+			vector[i] = float32(v)
+		}
+		rows = append(rows, vector)
+	})
+	return rows, err
+}
+
+// ints reads the two dimensional integer dataset at name in the root group
+func (f *hdf5File) ints(name string) ([][]int, error) {
+	rows := make([][]int, 0)
+	err := f.rows(name, func(row []float64) {
+		ints := make([]int, len(row))
+		for i, v := range row {
+			ints[i] = int(v)
+		}
+		rows = append(rows, ints)
+	})
+	return rows, err
+}
+
+// rows calls fn with every row of the two dimensional dataset at name,
+// reusing the row between calls
+func (f *hdf5File) rows(name string, fn func(row []float64)) error {
+	address, err := f.member(name)
+	if err != nil {
+		return err
+	}
+	dataset, err := f.dataset(address)
+	if err != nil {
+		return fmt.Errorf("could not read dataset %s: %v", name, err)
+	}
+	if len(dataset.dims) != 2 {
+		return fmt.Errorf("dataset %s has %d dimensions, not 2", name, len(dataset.dims))
+	}
+	if dataset.typ.class != hdf5FixedPoint && dataset.typ.class != hdf5Float {
+		return fmt.Errorf("dataset %s doesn't hold numbers", name)
+	}
+
+	count, width := int(dataset.dims[0]), int(dataset.dims[1])
+	rowSize := width * dataset.typ.size
+	var r io.Reader
+	if dataset.compact != nil {
+		r = bytes.NewReader(dataset.compact)
+	} else {
+		r = bufio.NewReaderSize(io.NewSectionReader(f.r, int64(f.base+dataset.address), int64(count*rowSize)), 1<<20)
+	}
+
+	raw := make([]byte, rowSize)
+	row := make([]float64, width)
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return fmt.Errorf("could not read row %d of dataset %s: %v", i, name, err)
+		}
+		for j := range row {
+			row[j] = dataset.typ.number(raw[j*dataset.typ.size:])
+		}
+		fn(row)
+	}
+	return nil
+}
+
+// attribute reads the string attribute name of the root group, or "" when
+// it has none
+func (f *hdf5File) attribute(name string) (string, error) {
+	messages, err := f.messages(f.root)
+	if err != nil {
+		return "", err
+	}
+	for _, message := range messages {
+		if message.kind != hdf5Attribute {
+			continue
+		}
+		attrName, typ, data, err := f.parseAttribute(message.data)
+		if err != nil {
+			return "", fmt.Errorf("could not read attribute: %v", err)
+		}
+		if attrName != name {
+			continue
+		}
+		switch {
+		case typ.class == hdf5String:
+			if len(data) < typ.size {
+				return "", fmt.Errorf("attribute %s is truncated", name)
+			}
+			return string(bytes.TrimRight(data[:typ.size], "\x00 ")), nil
+		case typ.varString:
+			return f.varString(data)
+		default:
+			return "", fmt.Errorf("attribute %s isn't a string", name)
+		}
+	}
+	return "", nil
+}
+
+// parseAttribute splits an attribute message into its name, type and data
+func (f *hdf5File) parseAttribute(b []byte) (string, hdf5Type, []byte, error) {
+	c := &hdf5Cursor{b: b}
+	version := c.u8()
+	c.skip(1)
+	nameSize, typeSize, spaceSize := int(c.u16()), int(c.u16()), int(c.u16())
+	pad := func(n int) int { return n }
+	switch version {
+	case 1:
+		// version 1 pads every field to a multiple of 8 bytes
+		pad = func(n int) int { return (n + 7) &^ 7 }
+	case 2:
+	case 3:
+		c.skip(1)
+	default:
+		return "", hdf5Type{}, nil, fmt.Errorf("unsupported attribute version %d", version)
+	}
+	name := string(bytes.TrimRight(c.next(pad(nameSize))[:nameSize], "\x00"))
+	typ, err := parseType(c.next(pad(typeSize)))
+	if err != nil {
+		return "", hdf5Type{}, nil, err
+	}
+	c.skip(pad(spaceSize))
+	if c.err != nil {
+		return "", hdf5Type{}, nil, fmt.Errorf("attribute message is truncated")
+	}
+	return name, typ, b[c.pos:], nil
+}
+
+// varString reads the variable length string whose reference is data:
+// its length, then the global heap collection and index of its bytes
+func (f *hdf5File) varString(data []byte) (string, error) {
+	c := &hdf5Cursor{b: data}
+	length := int(c.u32())
+	collection := f.offset(c)
+	index := c.u32()
+	if c.err != nil {
+		return "", fmt.Errorf("string reference is truncated")
+	}
+
+	header, err := f.read(collection, 8+f.lengthSize)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(header[:4], []byte("GCOL")) {
+		return "", fmt.Errorf("no global heap at %d", collection)
+	}
+	size := f.length(&hdf5Cursor{b: header, pos: 8})
+	heap, err := f.read(collection, int(size))
+	if err != nil {
+		return "", err
+	}
+
+	c = &hdf5Cursor{b: heap, pos: 8 + f.lengthSize}
+	for c.err == nil {
+		objectIndex := uint32(c.u16())
+		c.skip(2 + 4)
+		objectSize := int(f.length(c))
+		object := c.next((objectSize + 7) &^ 7)
+		if c.err != nil || objectIndex == 0 {
+			break
+		}
+		if objectIndex == index {
+			if length > objectSize {
+				length = objectSize
+			}
+			return string(object[:length]), nil
+		}
+	}
+	return "", fmt.Errorf("global heap object %d is missing", index)
+}
+
+// member finds the object header address of name in the root group
+func (f *hdf5File) member(name string) (uint64, error) {
+	messages, err := f.messages(f.root)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		c := &hdf5Cursor{b: message.data}
+		switch message.kind {
+		case hdf5SymbolTable:
+			btree, heap := f.offset(c), f.offset(c)
+			if c.err != nil {
+				return 0, fmt.Errorf("symbol table message is truncated")
+			}
+			names, err := f.localHeap(heap)
+			if err != nil {
+				return 0, err
+			}
+			address, found, err := f.searchGroup(btree, names, name)
+			if err != nil || found {
+				return address, err
+			}
+		case hdf5Link:
+			linkName, address, hard := f.parseLink(c)
+			if c.err != nil {
+				return 0, fmt.Errorf("link message is truncated")
+			}
+			if hard && linkName == name {
+				return address, nil
+			}
+		case hdf5LinkInfo:
+			c.skip(1)
+			flags := c.u8()
+			if flags&1 != 0 {
+				c.skip(8)
+			}
+			if heap := f.offset(c); c.err == nil && !f.undefined(heap) {
+				return 0, fmt.Errorf("groups with dense link storage are not supported")
+			}
+		}
+	}
+	return 0, fmt.Errorf("no dataset %s", name)
+}
+
+// parseLink reads a link message, reporting whether it is a hard link
+func (f *hdf5File) parseLink(c *hdf5Cursor) (string, uint64, bool) {
+	c.skip(1)
+	flags := c.u8()
+	linkType := uint8(0)
+	if flags&0x08 != 0 {
+		linkType = c.u8()
+	}
+	if flags&0x04 != 0 {
+		c.skip(8)
+	}
+	if flags&0x10 != 0 {
+		c.skip(1)
+	}
+	nameLength := int(c.uint(1 << (flags & 3)))
+	name := string(c.next(nameLength))
+	if linkType != 0 {
+		return name, 0, false
+	}
+	return name, f.offset(c), true
+}
+
+// searchGroup walks the version 1 B-tree of a group's symbol table for
+// name, whose symbols name themselves by offsets into names
+func (f *hdf5File) searchGroup(address uint64, names []byte, name string) (uint64, bool, error) {
+	headerSize := 8 + 2*f.offsetSize
+	header, err := f.read(address, headerSize)
+	if err != nil {
+		return 0, false, err
+	}
+	if !bytes.Equal(header[:4], []byte("TREE")) || header[4] != 0 {
+		return 0, false, fmt.Errorf("no group B-tree at %d", address)
+	}
+	level := header[5]
+	entries := int(binary.LittleEndian.Uint16(header[6:]))
+
+	// keys and children alternate, starting and ending with a key
+	body, err := f.read(address+uint64(headerSize), entries*(f.lengthSize+f.offsetSize)+f.lengthSize)
+	if err != nil {
+		return 0, false, err
+	}
+	c := &hdf5Cursor{b: body}
+	for i := 0; i < entries; i++ {
+		c.skip(f.lengthSize)
+		child := f.offset(c)
+		var found bool
+		var object uint64
+		if level > 0 {
+			object, found, err = f.searchGroup(child, names, name)
+		} else {
+			object, found, err = f.searchSymbols(child, names, name)
+		}
+		if err != nil || found {
+			return object, found, err
+		}
+	}
+	return 0, false, nil
+}
+
+// searchSymbols looks for name in a symbol table node
+func (f *hdf5File) searchSymbols(address uint64, names []byte, name string) (uint64, bool, error) {
+	header, err := f.read(address, 8)
+	if err != nil {
+		return 0, false, err
+	}
+	if !bytes.Equal(header[:4], []byte("SNOD")) {
+		return 0, false, fmt.Errorf("no symbol table node at %d", address)
+	}
+	count := int(binary.LittleEndian.Uint16(header[6:]))
+	entrySize := 2*f.offsetSize + 24
+	body, err := f.read(address+8, count*entrySize)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i := 0; i < count; i++ {
+		c := &hdf5Cursor{b: body, pos: i * entrySize}
+		nameOffset := f.offset(c)
+		object := f.offset(c)
+		if nameOffset >= uint64(len(names)) {
+			return 0, false, fmt.Errorf("symbol name offset %d is outside the heap", nameOffset)
+		}
+		symbol := names[nameOffset:]
+		if end := bytes.IndexByte(symbol, 0); end >= 0 {
+			symbol = symbol[:end]
+		}
+		if string(symbol) == name {
+			return object, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// localHeap reads the data segment of the local heap at address
+func (f *hdf5File) localHeap(address uint64) ([]byte, error) {
+	header, err := f.read(address, 8+2*f.lengthSize+f.offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], []byte("HEAP")) {
+		return nil, fmt.Errorf("no local heap at %d", address)
+	}
+	c := &hdf5Cursor{b: header, pos: 8}
+	size := f.length(c)
+	c.skip(f.lengthSize)
+	return f.read(f.offset(c), int(size))
+}
+
+// dataset reads the shape, type and layout of the dataset whose object
+// header is at address
+func (f *hdf5File) dataset(address uint64) (*hdf5Dataset, error) {
+	messages, err := f.messages(address)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset := &hdf5Dataset{}
+	var haveSpace, haveType, haveLayout bool
+	for _, message := range messages {
+		if message.flags&0x02 != 0 && (message.kind == hdf5Datatype || message.kind == hdf5Dataspace) {
+			return nil, fmt.Errorf("shared datatypes and dataspaces are not supported")
+		}
+		switch message.kind {
+		case hdf5Dataspace:
+			if dataset.dims, err = f.parseSpace(message.data); err != nil {
+				return nil, err
+			}
+			haveSpace = true
+		case hdf5Datatype:
+			if dataset.typ, err = parseType(message.data); err != nil {
+				return nil, err
+			}
+			haveType = true
+		case hdf5Layout:
+			if err := f.parseLayout(message.data, dataset); err != nil {
+				return nil, err
+			}
+			haveLayout = true
+		}
+	}
+	if !haveSpace || !haveType || !haveLayout {
+		return nil, fmt.Errorf("object at %d is not a dataset", address)
+	}
+
+	elements := uint64(1)
+	for _, dim := range dataset.dims {
+		elements *= dim
+	}
+	size := elements * uint64(dataset.typ.size)
+	switch {
+	case dataset.compact != nil:
+		if uint64(len(dataset.compact)) < size {
+			return nil, fmt.Errorf("compact data is truncated")
+		}
+	case f.undefined(dataset.address):
+		return nil, fmt.Errorf("dataset has no data written")
+	case f.base+dataset.address+size > uint64(f.size):
+		return nil, fmt.Errorf("dataset data runs past the end of the file")
+	}
+	return dataset, nil
+}
+
+// parseSpace reads the dimensions of a dataspace message
+func (f *hdf5File) parseSpace(b []byte) ([]uint64, error) {
+	c := &hdf5Cursor{b: b}
+	version := c.u8()
+	rank := int(c.u8())
+	c.skip(1)
+	switch version {
+	case 1:
+		c.skip(5)
+	case 2:
+		c.skip(1)
+	default:
+		return nil, fmt.Errorf("unsupported dataspace version %d", version)
+	}
+	dims := make([]uint64, rank)
+	for i := range dims {
+		dims[i] = f.length(c)
+	}
+	if c.err != nil {
+		return nil, fmt.Errorf("dataspace message is truncated")
+	}
+	return dims, nil
+}
+
+// parseLayout reads where a data layout message puts the data
+func (f *hdf5File) parseLayout(b []byte, dataset *hdf5Dataset) error {
+	c := &hdf5Cursor{b: b}
+	version := c.u8()
+	switch version {
+	case 1, 2:
+		rank := int(c.u8())
+		class := c.u8()
+		c.skip(5)
+		switch class {
+		case 0:
+			c.skip(4 * rank)
+			size := int(c.u32())
+			dataset.compact = c.next(size)
+		case 1:
+			dataset.address = f.offset(c)
+		default:
+			return fmt.Errorf("chunked datasets are not supported")
+		}
+	case 3, 4:
+		switch c.u8() {
+		case 0:
+			size := int(c.u16())
+			dataset.compact = c.next(size)
+		case 1:
+			dataset.address = f.offset(c)
+		default:
+			return fmt.Errorf("chunked and virtual datasets are not supported")
+		}
+	default:
+		return fmt.Errorf("unsupported layout version %d", version)
+	}
+	if c.err != nil {
+		return fmt.Errorf("layout message is truncated")
+	}
+	return nil
+}
+
+// parseType reads a datatype message
+func parseType(b []byte) (hdf5Type, error) {
+	if len(b) < 8 {
+		return hdf5Type{}, fmt.Errorf("datatype message is truncated")
+	}
+	typ := hdf5Type{
+		class: int(b[0] & 0x0f),
+		size:  int(binary.LittleEndian.Uint32(b[4:])),
+	}
+	switch typ.class {
+	case hdf5FixedPoint:
+		typ.bigEndian = b[1]&0x01 != 0
+		typ.signed = b[1]&0x08 != 0
+		if typ.size != 1 && typ.size != 2 && typ.size != 4 && typ.size != 8 {
+			return hdf5Type{}, fmt.Errorf("unsupported %d byte integers", typ.size)
+		}
+	case hdf5Float:
+		typ.bigEndian = b[1]&0x01 != 0
+		if typ.size != 4 && typ.size != 8 {
+			return hdf5Type{}, fmt.Errorf("unsupported %d byte floats", typ.size)
+		}
+	case hdf5VarLength:
+		typ.varString = b[1]&0x0f == 1
+	}
+	return typ, nil
+}
+
+// number decodes the number at the start of b
+func (t hdf5Type) number(b []byte) float64 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if t.bigEndian {
+		order = binary.BigEndian
+	}
+	if t.class == hdf5Float {
+		if t.size == 4 {
+			return float64(math.Float32frombits(order.Uint32(b)))
+		}
+		return math.Float64frombits(order.Uint64(b))
+	}
+
+	var bits uint64
+	switch t.size {
+	case 1:
+		bits = uint64(b[0])
+	case 2:
+		bits = uint64(order.Uint16(b))
+	case 4:
+		bits = uint64(order.Uint32(b))
+	default:
+		bits = order.Uint64(b)
+	}
+	if t.signed {
+		// sign extend from the top bit of the integer
+		shift := 64 - 8*t.size
+		return float64(int64(bits<<shift) >> shift)
+	}
+	return float64(bits)
+}
+
+// messages reads every message of the object header at address,
+// following continuation blocks
+func (f *hdf5File) messages(address uint64) ([]hdf5Message, error) {
+	prefix, err := f.read(address, 4)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(prefix, []byte("OHDR")) {
+		return f.messagesV2(address)
+	}
+	if prefix[0] != 1 {
+		return nil, fmt.Errorf("unsupported object header version %d at %d", prefix[0], address)
+	}
+
+	header, err := f.read(address, 16)
+	if err != nil {
+		return nil, err
+	}
+	remaining := int(binary.LittleEndian.Uint16(header[2:]))
+	blocks := [][2]uint64{{address + 16, uint64(binary.LittleEndian.Uint32(header[8:]))}}
+
+	messages := make([]hdf5Message, 0, remaining)
+	for len(blocks) > 0 && remaining > 0 {
+		block, err := f.read(blocks[0][0], int(blocks[0][1]))
+		if err != nil {
+			return nil, err
+		}
+		blocks = blocks[1:]
+
+		c := &hdf5Cursor{b: block}
+		for remaining > 0 && len(block)-c.pos >= 8 {
+			message := hdf5Message{kind: c.u16()}
+			size := int(c.u16())
+			message.flags = c.u8()
+			c.skip(3)
+			message.data = c.next(size)
+			if c.err != nil {
+				return nil, fmt.Errorf("object header at %d is truncated", address)
+			}
+			remaining--
+			if message.kind == hdf5Continuation {
+				dc := &hdf5Cursor{b: message.data}
+				blocks = append(blocks, [2]uint64{f.offset(dc), f.length(dc)})
+				continue
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// messagesV2 reads a version 2 object header
+func (f *hdf5File) messagesV2(address uint64) ([]hdf5Message, error) {
+	prefix, err := f.read(address, 6)
+	if err != nil {
+		return nil, err
+	}
+	if prefix[4] != 2 {
+		return nil, fmt.Errorf("unsupported object header version %d at %d", prefix[4], address)
+	}
+	flags := prefix[5]
+	skip := 0
+	if flags&0x20 != 0 {
+		skip += 16
+	}
+	if flags&0x10 != 0 {
+		skip += 4
+	}
+	sizeBytes := 1 << (flags & 3)
+	sizeField, err := f.read(address+6+uint64(skip), sizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	start := address + 6 + uint64(skip+sizeBytes)
+	blocks := [][2]uint64{{start, (&hdf5Cursor{b: sizeField}).uint(sizeBytes)}}
+
+	// messages carry their creation order when attribute order is tracked
+	headerSize := 4
+	if flags&0x04 != 0 {
+		headerSize = 6
+	}
+	messages := make([]hdf5Message, 0)
+	for len(blocks) > 0 {
+		block, err := f.read(blocks[0][0], int(blocks[0][1]))
+		if err != nil {
+			return nil, err
+		}
+		blocks = blocks[1:]
+
+		c := &hdf5Cursor{b: block}
+		for len(block)-c.pos >= headerSize {
+			message := hdf5Message{kind: uint16(c.u8())}
+			size := int(c.u16())
+			message.flags = c.u8()
+			c.skip(headerSize - 4)
+			message.data = c.next(size)
+			if c.err != nil {
+				return nil, fmt.Errorf("object header at %d is truncated", address)
+			}
+			if message.kind == hdf5Continuation {
+				dc := &hdf5Cursor{b: message.data}
+				// continuation blocks have a signature and a checksum
+				next, length := f.offset(dc), f.length(dc)
+				if length < 8 {
+					return nil, fmt.Errorf("object header at %d has a broken continuation", address)
+				}
+				blocks = append(blocks, [2]uint64{next + 4, length - 8})
+				continue
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// read reads n bytes at address, relative to the base address
+func (f *hdf5File) read(address uint64, n int) ([]byte, error) {
+	if n < 0 || f.base+address+uint64(n) > uint64(f.size) {
+		return nil, fmt.Errorf("%d bytes at %d run past the end of the file", n, address)
+	}
+	b := make([]byte, n)
+	if _, err := f.r.ReadAt(b, int64(f.base+address)); err != nil {
+		return nil, fmt.Errorf("could not read %d bytes at %d: %v", n, address, err)
+	}
+	return b, nil
+}
+
+func (f *hdf5File) offset(c *hdf5Cursor) uint64 {
+	return c.uint(f.offsetSize)
+}
+
+func (f *hdf5File) length(c *hdf5Cursor) uint64 {
+	return c.uint(f.lengthSize)
+}
+
+// undefined reports whether address is the all ones undefined address
+func (f *hdf5File) undefined(address uint64) bool {
+	return address == uint64(math.MaxUint64)>>(64-8*f.offsetSize)
+}
+
+// hdf5Cursor decodes little-endian fields from b. Reading past the end
+// yields zeros and sets err.
+type hdf5Cursor struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (c *hdf5Cursor) next(n int) []byte {
+	if n < 0 || c.pos+n > len(c.b) {
+		c.err = io.ErrUnexpectedEOF
+		c.pos = len(c.b)
+		return make([]byte, max(n, 0))
+	}
+	b := c.b[c.pos : c.pos+n]
+	c.pos += n
+	return b
+}
+
+func (c *hdf5Cursor) skip(n int) {
+	c.next(n)
+}
+
+func (c *hdf5Cursor) u8() uint8 {
+	return c.next(1)[0]
+}
+
+func (c *hdf5Cursor) u16() uint16 {
+	return binary.LittleEndian.Uint16(c.next(2))
+}
+
+func (c *hdf5Cursor) u32() uint32 {
+	return binary.LittleEndian.Uint32(c.next(4))
+}
+
+// uint reads an n byte unsigned integer
+func (c *hdf5Cursor) uint(n int) uint64 {
+	b := c.next(n)
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
@@ -0,0 +1,313 @@
+package bench
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// hdf5Writer lays out the HDF5 structures the reader understands, with
+// 8 byte addresses and lengths and a version 0 superblock
+type hdf5Writer struct {
+	buf []byte
+}
+
+const hdf5Undefined = math.MaxUint64
+
+// alloc reserves n bytes, 8 byte aligned, and returns their address
+func (w *hdf5Writer) alloc(n int) uint64 {
+	for len(w.buf)%8 != 0 {
+		w.buf = append(w.buf, 0)
+	}
+	address := uint64(len(w.buf))
+	w.buf = append(w.buf, make([]byte, n)...)
+	return address
+}
+
+// write allocates and fills a block
+func (w *hdf5Writer) write(b []byte) uint64 {
+	address := w.alloc(len(b))
+	copy(w.buf[address:], b)
+	return address
+}
+
+// le encodes fields little-endian
+func le(fields ...any) []byte {
+	b := make([]byte, 0)
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint8:
+			b = append(b, v)
+		case uint16:
+			b = binary.LittleEndian.AppendUint16(b, v)
+		case uint32:
+			b = binary.LittleEndian.AppendUint32(b, v)
+		case uint64:
+			b = binary.LittleEndian.AppendUint64(b, v)
+		case int:
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		case string:
+			b = append(b, v...)
+		case []byte:
+			b = append(b, v...)
+		default:
+			panic("unsupported field")
+		}
+	}
+	return b
+}
+
+func pad8(b []byte) []byte {
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// float32Type and the others are datatype messages
+func float32Type() []byte {
+	return le(uint8(0x11), uint8(0x20), uint8(31), uint8(0), uint32(4),
+		uint16(0), uint16(32), uint8(23), uint8(8), uint8(0), uint8(23), uint32(127))
+}
+
+func float64Type() []byte {
+	return le(uint8(0x11), uint8(0x20), uint8(63), uint8(0), uint32(8),
+		uint16(0), uint16(64), uint8(52), uint8(11), uint8(0), uint8(52), uint32(1023))
+}
+
+func intType(size int) []byte {
+	return le(uint8(0x10), uint8(0x08), uint8(0), uint8(0), uint32(size), uint16(0), uint16(8*size))
+}
+
+func varStringType() []byte {
+	return le(uint8(0x19), uint8(0x01), uint8(0x01), uint8(0), uint32(16), intType(1))
+}
+
+func fixedStringType(size int) []byte {
+	return le(uint8(0x13), uint8(0x00), uint8(0), uint8(0), uint32(size))
+}
+
+func dataspace(dims ...int) []byte {
+	b := le(uint8(1), uint8(len(dims)), uint8(0), uint8(0), uint32(0))
+	for _, dim := range dims {
+		b = append(b, le(dim)...)
+	}
+	return b
+}
+
+func contiguous(address uint64, size int) []byte {
+	return le(uint8(3), uint8(1), address, size)
+}
+
+// v1Header writes a version 1 object header holding messages, moving the
+// ones from split on into a continuation block
+func (w *hdf5Writer) v1Header(messages [][2]any, split int) uint64 {
+	encode := func(messages [][2]any) []byte {
+		b := make([]byte, 0)
+		for _, message := range messages {
+			data := pad8(append([]byte(nil), message[1].([]byte)...))
+			b = append(b, le(uint16(message[0].(int)), uint16(len(data)), uint8(0), uint8(0), uint8(0), uint8(0), data)...)
+		}
+		return b
+	}
+
+	first := messages
+	count := len(messages)
+	if split < len(messages) {
+		rest := encode(messages[split:])
+		next := w.write(rest)
+		first = append(append([][2]any(nil), messages[:split]...), [2]any{0x10, le(next, len(rest))})
+		count++
+	}
+	body := encode(first)
+	return w.write(append(le(uint8(1), uint8(0), uint16(count), uint32(1), uint32(len(body)), uint32(0)), body...))
+}
+
+// v2Header writes a version 2 object header, with a zero checksum
+func (w *hdf5Writer) v2Header(messages [][2]any) uint64 {
+	body := make([]byte, 0)
+	for _, message := range messages {
+		data := message[1].([]byte)
+		body = append(body, le(uint8(message[0].(int)), uint16(len(data)), uint8(0), data)...)
+	}
+	return w.write(append(le("OHDR", uint8(2), uint8(0x02), uint32(len(body))), append(body, 0, 0, 0, 0)...))
+}
+
+// dataset writes rows as a contiguous dataset of typ, encoding each value
+// with encode
+func (w *hdf5Writer) dataset(typ []byte, rows int, cols int, values []byte, v2 bool) uint64 {
+	data := w.write(values)
+	messages := [][2]any{
+		{0x01, dataspace(rows, cols)},
+		{0x03, typ},
+		{0x08, contiguous(data, len(values))},
+	}
+	if v2 {
+		return w.v2Header(messages)
+	}
+	return w.v1Header(messages, len(messages))
+}
+
+func (w *hdf5Writer) superblock(root uint64) {
+	copy(w.buf, le(hdf5Signature, uint8(0), uint8(0), uint8(0), uint8(0), uint8(0), uint8(8), uint8(8), uint8(0),
+		uint16(4), uint16(16), uint32(0),
+		uint64(0), uint64(hdf5Undefined), uint64(len(w.buf)), uint64(hdf5Undefined),
+		uint64(0), root, uint32(0), uint32(0), make([]byte, 16)))
+}
+
+type HDF5TestSuite struct {
+	suite.Suite
+	train     [][]float32
+	test      [][]float32
+	neighbors [][]int
+}
+
+func (s *HDF5TestSuite) SetupTest() {
+	s.train = [][]float32{{1, 2, 3}, {4, 5, 6}, {-7, 8.5, 9}, {0, 0, 1}}
+	s.test = [][]float32{{1, 1, 1}, {-1, 2, -3}}
+	s.neighbors = [][]int{{3, 0}, {0, 3}}
+}
+
+func (s *HDF5TestSuite) floats(rows [][]float32, wide bool) []byte {
+	b := make([]byte, 0)
+	for _, row := range rows {
+		for _, v := range row {
+			if wide {
+				b = le(b, math.Float64bits(float64(v)))
+			} else {
+				b = le(b, math.Float32bits(v))
+			}
+		}
+	}
+	return b
+}
+
+func (s *HDF5TestSuite) ints(rows [][]int, size int) []byte {
+	b := make([]byte, 0)
+	for _, row := range rows {
+		for _, v := range row {
+			if size == 4 {
+				b = le(b, uint32(int32(v)))
+			} else {
+				b = le(b, uint64(int64(v)))
+			}
+		}
+	}
+	return b
+}
+
+func (s *HDF5TestSuite) save(w *hdf5Writer) string {
+	path := filepath.Join(s.T().TempDir(), "test-3-euclidean.hdf5")
+	require.NoError(s.T(), os.WriteFile(path, w.buf, 0644))
+	return path
+}
+
+func (s *HDF5TestSuite) TestSymbolTableGroup() {
+	// the layout h5py writes by default: a symbol table root group, version
+	// 1 object headers and a variable length string attribute
+	w := &hdf5Writer{}
+	w.alloc(96)
+
+	datasets := []uint64{
+		w.dataset(intType(8), 2, 2, s.ints(s.neighbors, 8), false),
+		w.dataset(float32Type(), 2, 3, s.floats(s.test, false), false),
+		w.dataset(float32Type(), 4, 3, s.floats(s.train, false), false),
+	}
+	// names sorted, as the B-tree keeps them
+	names := []string{"neighbors", "test", "train"}
+	heapData := []byte{0}
+	offsets := make([]uint64, len(names))
+	for i, name := range names {
+		offsets[i] = uint64(len(heapData))
+		heapData = append(heapData, name...)
+		heapData = append(heapData, 0)
+	}
+	heapData = pad8(heapData)
+	heapAddress := w.write(heapData)
+	heap := w.write(le("HEAP", uint8(0), uint8(0), uint8(0), uint8(0), len(heapData), uint64(hdf5Undefined), heapAddress))
+
+	symbols := le("SNOD", uint8(1), uint8(0), uint16(len(names)))
+	for i := range names {
+		symbols = append(symbols, le(offsets[i], datasets[i], uint32(0), uint32(0), make([]byte, 16))...)
+	}
+	snod := w.write(symbols)
+	btree := w.write(le("TREE", uint8(0), uint8(0), uint16(1), uint64(hdf5Undefined), uint64(hdf5Undefined),
+		uint64(0), snod, offsets[len(offsets)-1]))
+
+	value := "euclidean"
+	object := pad8(le(uint16(1), uint16(1), uint32(0), len(value), value))
+	free := le(uint16(0), uint16(0), uint32(0), 16)
+	collection := w.write(append(le("GCOL", uint8(1), uint8(0), uint8(0), uint8(0), 16+len(object)+len(free)+16), append(object, free...)...))
+	w.alloc(16)
+
+	attribute := le(uint8(1), uint8(0), uint16(9), uint16(len(varStringType())), uint16(8),
+		pad8([]byte("distance\x00")), pad8(varStringType()), dataspace(),
+		uint32(len(value)), collection, uint32(1))
+	root := w.v1Header([][2]any{
+		{0x11, le(btree, heap)},
+		{0x0c, attribute},
+	}, 1)
+	w.superblock(root)
+
+	dataset, err := LoadHDF5(s.save(w))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "test-3-euclidean", dataset.Name)
+	assert.Equal(s.T(), MetricEuclidean, dataset.Metric)
+	assert.Equal(s.T(), s.train, dataset.Train)
+	assert.Equal(s.T(), s.test, dataset.Test)
+	assert.Equal(s.T(), s.neighbors, dataset.Neighbors)
+}
+
+func (s *HDF5TestSuite) TestLinkGroup() {
+	// the newer layout: a version 2 root object header with link messages
+	w := &hdf5Writer{}
+	w.alloc(96)
+
+	link := func(name string, address uint64) []byte {
+		return le(uint8(1), uint8(0), uint8(len(name)), name, address)
+	}
+	attribute := le(uint8(3), uint8(0), uint16(9), uint16(8), uint16(8), uint8(0),
+		"distance\x00", fixedStringType(8), dataspace(), "angular\x00")
+	root := w.v2Header([][2]any{
+		{0x0c, attribute},
+		{0x06, link("train", w.dataset(float64Type(), 4, 3, s.floats(s.train, true), true))},
+		{0x06, link("test", w.dataset(float32Type(), 2, 3, s.floats(s.test, false), true))},
+		{0x06, link("neighbors", w.dataset(intType(4), 2, 2, s.ints(s.neighbors, 4), true))},
+	})
+	w.superblock(root)
+
+	dataset, err := LoadHDF5(s.save(w))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), MetricAngular, dataset.Metric)
+	assert.Equal(s.T(), s.train, dataset.Train)
+	assert.Equal(s.T(), s.test, dataset.Test)
+	assert.Equal(s.T(), s.neighbors, dataset.Neighbors)
+}
+
+func (s *HDF5TestSuite) TestInvalid() {
+	path := filepath.Join(s.T().TempDir(), "broken.hdf5")
+	require.NoError(s.T(), os.WriteFile(path, []byte("not hdf5"), 0644))
+	_, err := LoadHDF5(path)
+	assert.Error(s.T(), err)
+
+	// a dataset whose data runs past the end of the file
+	w := &hdf5Writer{}
+	w.alloc(96)
+	train := w.v2Header([][2]any{
+		{0x01, dataspace(1000, 3)},
+		{0x03, float32Type()},
+		{0x08, contiguous(96, 12000)},
+	})
+	w.superblock(w.v2Header([][2]any{{0x06, le(uint8(1), uint8(0), uint8(5), "train", train)}}))
+	_, err = LoadHDF5(s.save(w))
+	assert.Error(s.T(), err)
+}
+
+func TestHDF5Suite(t *testing.T) {
+	suite.Run(t, new(HDF5TestSuite))
+}
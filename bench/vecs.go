@@ -0,0 +1,90 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ReadVecs reads the vectors of an .fvecs or .bvecs file, where every
+// vector is its dimension as a little-endian int32 followed by that many
+// float32 or byte components. It stops after limit vectors when limit is
+// positive.
+func ReadVecs(path string, limit int) ([][]float32, error) {
+	var width int
+	switch filepath.Ext(path) {
+	case ".fvecs":
+		width = 4
+	case ".bvecs":
+		width = 1
+	default:
+		return nil, fmt.Errorf("%s is not an .fvecs or .bvecs file", path)
+	}
+
+	vectors := make([][]float32, 0)
+	err := readVecs(path, width, limit, func(row []byte, dim int) {
+		vector := make([]float32, dim)
+		for i := range vector {
+			if width == 4 {
+				vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(row[4*i:]))
+			} else {
+				vector[i] = float32(row[i])
+			}
+		}
+		vectors = append(vectors, vector)
+	})
+	return vectors, err
+}
+
+// ReadIvecs reads the rows of an .ivecs file, which ground truth files use
+// for the indexes of each query's nearest neighbors
+func ReadIvecs(path string, limit int) ([][]int, error) {
+	rows := make([][]int, 0)
+	err := readVecs(path, 4, limit, func(row []byte, dim int) {
+		ints := make([]int, dim)
+		for i := range ints {
+			ints[i] = int(int32(binary.LittleEndian.Uint32(row[4*i:])))
+		}
+		rows = append(rows, ints)
+	})
+	return rows, err
+}
+
+// readVecs calls fn with the raw components of every vector in path
+func readVecs(path string, width int, limit int, fn func(row []byte, dim int)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	r := bufio.NewReaderSize(file, 1<<20)
+	header := make([]byte, 4)
+	row := make([]byte, 0)
+	for count := 0; limit <= 0 || count < limit; count++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("could not read vector %d of %s: %v", count, path, err)
+		}
+		dim := int(int32(binary.LittleEndian.Uint32(header)))
+		if dim <= 0 {
+			return fmt.Errorf("vector %d of %s has dimension %d", count, path, dim)
+		}
+		if cap(row) < dim*width {
+			row = make([]byte, dim*width)
+		}
+		row = row[:dim*width]
+		if _, err := io.ReadFull(r, row); err != nil {
+			return fmt.Errorf("could not read vector %d of %s: %v", count, path, err)
+		}
+		fn(row, dim)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	StrategyTokens    = "tokens"
+	StrategySentences = "sentences"
+	StrategyMarkdown  = "markdown"
+)
+
+// Config controls how documents are split before embedding. Sizes are
+// counted in whitespace separated tokens, which stays comfortably below
+// the subword token count models enforce for their context windows.
+type Config struct {
+	// Strategy is "tokens", "sentences" or "markdown"
+	Strategy string `json:"strategy"`
+
+	// Size is the maximum number of tokens in a chunk
+	Size int `json:"size"`
+
+	// Overlap is how many tokens of the end of one chunk are repeated at
+	// the start of the next, so context isn't lost at the boundaries
+	Overlap int `json:"overlap"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Strategy: StrategySentences,
+		Size:     256,
+		Overlap:  32,
+	}
+}
+
+func (c Config) validate() error {
+	if c.Size <= 0 {
+		return fmt.Errorf("chunk size must be positive, got %d", c.Size)
+	}
+	if c.Overlap < 0 || c.Overlap >= c.Size {
+		return fmt.Errorf("chunk overlap must be between 0 and the chunk size %d, got %d", c.Size, c.Overlap)
+	}
+	return nil
+}
+
+// Split breaks text into chunks according to cfg. Empty text yields no
+// chunks.
+func Split(text string, cfg Config) ([]string, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Strategy {
+	case StrategyTokens:
+		return byTokens(strings.Fields(text), cfg), nil
+	case StrategySentences:
+		return bySentences(text, cfg), nil
+	case StrategyMarkdown:
+		return byMarkdown(text, cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy %s", cfg.Strategy)
+	}
+}
+
+// byTokens is a sliding window of Size tokens that advances by Size-Overlap
+func byTokens(tokens []string, cfg Config) []string {
+	chunks := make([]string, 0)
+	step := cfg.Size - cfg.Overlap
+
+	for start := 0; start < len(tokens); start += step {
+		end := start + cfg.Size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunks = append(chunks, strings.Join(tokens[start:end], " "))
+		if end == len(tokens) {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// bySentences packs whole sentences into chunks of at most Size tokens and
+// starts each chunk with the trailing sentences of the previous one that
+// fit in Overlap. Sentences longer than Size fall back to token windows.
+func bySentences(text string, cfg Config) []string {
+	chunks := make([]string, 0)
+	var current [][]string
+	currentLen := 0
+	// fresh counts the sentences in current that no chunk holds yet
+	fresh := 0
+
+	flush := func() {
+		if fresh == 0 {
+			return
+		}
+		parts := make([]string, len(current))
+		for i, sentence := range current {
+			parts[i] = strings.Join(sentence, " ")
+		}
+		chunks = append(chunks, strings.Join(parts, " "))
+
+		// carry over trailing sentences for overlap
+		var carried [][]string
+		carriedLen := 0
+		for i := len(current) - 1; i >= 0; i-- {
+			if carriedLen+len(current[i]) > cfg.Overlap {
+				break
+			}
+			carried = append([][]string{current[i]}, carried...)
+			carriedLen += len(current[i])
+		}
+		current, currentLen, fresh = carried, carriedLen, 0
+	}
+
+	for _, sentence := range sentences(text) {
+		tokens := strings.Fields(sentence)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if len(tokens) > cfg.Size {
+			flush()
+			current, currentLen = nil, 0
+			chunks = append(chunks, byTokens(tokens, cfg)...)
+			continue
+		}
+
+		if currentLen+len(tokens) > cfg.Size {
+			flush()
+			// the overlap alone may still leave no room
+			for currentLen+len(tokens) > cfg.Size {
+				currentLen -= len(current[0])
+				current = current[1:]
+			}
+		}
+		current = append(current, tokens)
+		currentLen += len(tokens)
+		fresh++
+	}
+	flush()
+
+	return chunks
+}
+
+// sentences splits on ., ! and ? followed by whitespace, and on blank lines
+func sentences(text string) []string {
+	result := make([]string, 0)
+	runes := []rune(text)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		atBreak := false
+		switch {
+		case r == '.' || r == '!' || r == '?':
+			atBreak = i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+		case r == '\n':
+			atBreak = i+1 < len(runes) && runes[i+1] == '\n'
+		}
+
+		if atBreak {
+			if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+				result = append(result, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// byMarkdown starts a new chunk at every heading and prefixes each chunk
+// with its heading so it stays meaningful on its own. Sections longer than
+// Size are split further by sentences.
+func byMarkdown(text string, cfg Config) []string {
+	chunks := make([]string, 0)
+	heading := ""
+	var body []string
+
+	flush := func() {
+		section := strings.TrimSpace(strings.Join(body, "\n"))
+		body = nil
+		if section == "" {
+			return
+		}
+
+		budget := cfg
+		prefix := ""
+		if heading != "" {
+			prefix = heading + "\n"
+			headingLen := len(strings.Fields(heading))
+			if headingLen < budget.Size-budget.Overlap {
+				budget.Size -= headingLen
+			}
+		}
+		for _, piece := range bySentences(section, budget) {
+			chunks = append(chunks, prefix+piece)
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && isHeading(trimmed) {
+			flush()
+			heading = trimmed
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	return chunks
+}
+
+func isHeading(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	return level > 0 && level <= 6 && (len(line) == level || line[level] == ' ')
+}
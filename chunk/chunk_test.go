@@ -0,0 +1,77 @@
+package chunk
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ChunkTestSuite struct {
+	suite.Suite
+}
+
+func (s *ChunkTestSuite) TestTokens() {
+	text := "one two three four five six seven"
+	chunks, err := Split(text, Config{Strategy: StrategyTokens, Size: 3, Overlap: 1})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{
+		"one two three",
+		"three four five",
+		"five six seven",
+	}, chunks)
+
+	chunks, err = Split("one two", Config{Strategy: StrategyTokens, Size: 3})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"one two"}, chunks)
+}
+
+func (s *ChunkTestSuite) TestSentences() {
+	text := "Cats purr. Dogs bark loudly! Fish swim?\n\nBirds sing"
+	chunks, err := Split(text, Config{Strategy: StrategySentences, Size: 5, Overlap: 2})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{
+		"Cats purr. Dogs bark loudly!",
+		"Fish swim? Birds sing",
+	}, chunks)
+
+	// overlapping sentences are repeated at the start of the next chunk
+	chunks, err = Split("A b. C d. E f.", Config{Strategy: StrategySentences, Size: 4, Overlap: 2})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"A b. C d.", "C d. E f."}, chunks)
+
+	// sentences longer than a chunk fall back to token windows
+	long := strings.Repeat("word ", 6) + "end."
+	chunks, err = Split(long, Config{Strategy: StrategySentences, Size: 4})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"word word word word", "word word end."}, chunks)
+}
+
+func (s *ChunkTestSuite) TestMarkdown() {
+	text := "# Title\nIntro text.\n\n## Setup\nInstall it. Run it.\n```\n# not a heading\n```\n"
+	chunks, err := Split(text, Config{Strategy: StrategyMarkdown, Size: 64})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), chunks, 2)
+	assert.Equal(s.T(), "# Title\nIntro text.", chunks[0])
+	assert.True(s.T(), strings.HasPrefix(chunks[1], "## Setup\n"))
+	assert.Contains(s.T(), chunks[1], "# not a heading")
+}
+
+func (s *ChunkTestSuite) TestInvalidConfig() {
+	_, err := Split("text", Config{Strategy: StrategyTokens, Size: 0})
+	assert.Error(s.T(), err)
+
+	_, err = Split("text", Config{Strategy: StrategyTokens, Size: 4, Overlap: 4})
+	assert.Error(s.T(), err)
+
+	_, err = Split("text", Config{Strategy: "paragraphs", Size: 4})
+	assert.Error(s.T(), err)
+
+	chunks, err := Split("", DefaultConfig())
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), chunks)
+}
+
+func TestChunkSuite(t *testing.T) {
+	suite.Run(t, new(ChunkTestSuite))
+}
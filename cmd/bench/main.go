@@ -0,0 +1,111 @@
+// Bench builds an index from a dataset and reports its recall, QPS, build
+// time and memory as JSON, e.g.
+//
+//	bench -hdf5 glove-100-angular.hdf5 -index hnsw -search 16,64,256
+//	bench -base sift_base.fvecs -query sift_query.fvecs -gt sift_groundtruth.ivecs -index ivf -nlists 1024
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/bench"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	config := bench.DefaultConfig()
+
+	hdf5 := flag.String("hdf5", "", "ann-benchmarks HDF5 dataset")
+	base := flag.String("base", "", ".fvecs or .bvecs base vectors")
+	query := flag.String("query", "", ".fvecs or .bvecs queries")
+	groundTruth := flag.String("gt", "", ".ivecs ground truth, computed exactly when missing")
+	limit := flag.Int("limit", 0, "read only this many base vectors")
+	metric := flag.String("metric", "", "euclidean or angular, overriding the dataset's")
+	queries := flag.Int("queries", 0, "run only this many queries")
+	out := flag.String("out", "", "write the JSON report here instead of stdout")
+	searchList := flag.String("search", "", "comma separated ef, nprobe or DiskANN list sizes to measure")
+
+	flag.StringVar(&config.Index, "index", config.Index, "flat, hnsw, ivf or diskann")
+	flag.IntVar(&config.K, "k", config.K, "neighbors per query")
+
+	flag.IntVar(&config.HNSW.M, "m", config.HNSW.M, "HNSW neighbors per node")
+	flag.IntVar(&config.HNSW.EfConstruction, "ef-construction", config.HNSW.EfConstruction, "HNSW candidate list size while building")
+	flag.StringVar(&config.HNSW.Quantization, "quantization", config.HNSW.Quantization, "HNSW quantization, int8 or binary")
+	flag.IntVar(&config.Oversample, "oversample", config.Oversample, "candidates per result a quantized HNSW graph rescores")
+	flag.IntVar(&config.Workers, "workers", config.Workers, "HNSW insert goroutines, one per CPU when 0")
+
+	flag.IntVar(&config.IVF.NLists, "nlists", config.IVF.NLists, "IVF lists")
+	flag.IntVar(&config.IVF.NProbe, "nprobe", config.IVF.NProbe, "IVF lists probed by default")
+	flag.IntVar(&config.IVF.PQ.Subspaces, "ivf-pq", config.IVF.PQ.Subspaces, "IVF-PQ subspaces, plain IVF when 0")
+
+	flag.IntVar(&config.DiskANN.Degree, "degree", config.DiskANN.Degree, "DiskANN graph degree")
+	flag.IntVar(&config.DiskANN.BuildList, "build-list", config.DiskANN.BuildList, "DiskANN candidate list size while building")
+	flag.Float64Var(&config.DiskANN.Alpha, "alpha", config.DiskANN.Alpha, "DiskANN pruning alpha")
+	flag.IntVar(&config.DiskANN.BeamWidth, "beam", config.DiskANN.BeamWidth, "DiskANN nodes read per search step")
+	flag.IntVar(&config.DiskANN.PQ.Subspaces, "diskann-pq", config.DiskANN.PQ.Subspaces, "DiskANN PQ subspaces")
+	flag.StringVar(&config.Dir, "dir", "", "directory for the DiskANN file, a temporary one when empty")
+	flag.Parse()
+
+	settings, err := parseInts(*searchList)
+	if err != nil {
+		log.Fatalf("Invalid -search: %v", err)
+	}
+	config.Search = settings
+
+	var dataset *bench.Dataset
+	switch {
+	case *hdf5 != "":
+		dataset, err = bench.LoadHDF5(*hdf5)
+		if err == nil && *metric != "" {
+			err = dataset.SetMetric(*metric)
+		}
+	case *base != "" && *query != "":
+		dataset, err = bench.LoadVecs(*base, *query, *groundTruth, *limit, *metric)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+	dataset.Limit(*queries)
+
+	log.Printf("Benchmarking %s on %s: %d vectors of %d dimensions, %d queries",
+		config.Index, dataset.Name, len(dataset.Train), dataset.Dims(), len(dataset.Test))
+	reports, err := bench.Run(dataset, config)
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+
+	encoded, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode report: %v", err)
+	}
+	encoded = append(encoded, '\n')
+	if *out == "" {
+		_, _ = os.Stdout.Write(encoded)
+		return
+	}
+	if err := os.WriteFile(*out, encoded, 0644); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+func parseInts(list string) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+	values := make([]int, 0)
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
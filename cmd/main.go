@@ -26,8 +26,17 @@ func getPort(defaultPort string) string {
 }
 
 func main() {
-	// Initialize the database
-	db, err := db2.OpenDB(db2.DefaultConfig())
+	// Initialize the database, from a config file if one is given
+	cfg := db2.DefaultConfig()
+	if path := os.Getenv("GHASTLY_CONFIG"); path != "" {
+		var err error
+		cfg, err = db2.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	db, err := db2.OpenDB(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
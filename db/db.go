@@ -1,13 +1,31 @@
 package db
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/ahhcash/ghastlydb/chunk"
 	"github.com/ahhcash/ghastlydb/embed"
-	"github.com/ahhcash/ghastlydb/embed/local/colbert"
-	"github.com/ahhcash/ghastlydb/embed/nvidia"
+	"github.com/ahhcash/ghastlydb/embed/cache"
+	"github.com/ahhcash/ghastlydb/embed/ollama"
 	"github.com/ahhcash/ghastlydb/embed/openai"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/rerank"
+	"github.com/ahhcash/ghastlydb/search"
 	"github.com/ahhcash/ghastlydb/storage"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// built-in embedding providers register themselves with embed.Register
+	_ "github.com/ahhcash/ghastlydb/embed/hash"
+	_ "github.com/ahhcash/ghastlydb/embed/local/colbert"
+	_ "github.com/ahhcash/ghastlydb/embed/local/onnx"
+	_ "github.com/ahhcash/ghastlydb/embed/nvidia"
+
+	// as are the built-in rerankers with rerank.Register
+	_ "github.com/ahhcash/ghastlydb/rerank/cohere"
+	_ "github.com/ahhcash/ghastlydb/rerank/crossencoder"
 )
 
 type DBConfig struct {
@@ -15,24 +33,102 @@ type DBConfig struct {
 	MemtableSize   int
 	Metric         string
 	EmbeddingModel string
+
+	// EmbeddingOptions configures the provider named by EmbeddingModel. It is
+	// either that provider's option struct (e.g. openai.Config) or, when read
+	// from a config file, a JSON object decoded on top of its defaults.
+	// Nil uses the provider defaults.
+	EmbeddingOptions any
+
+	// OpenAI configures the "openai" embedding model when EmbeddingOptions
+	// is nil.
+	//
+	// Deprecated: set EmbeddingOptions to an openai.Config instead.
+	OpenAI openai.Config
+
+	// Ollama configures the "ollama" embedding model when EmbeddingOptions
+	// is nil.
+	//
+	// Deprecated: set EmbeddingOptions to an ollama.Config instead.
+	Ollama ollama.Config
+
+	// EmbeddingCache keeps computed vectors on disk so re-putting the same
+	// value or repeating a query doesn't pay for another embedding call.
+	// Collections pointing at the same path share one cache and must
+	// give it the same limits.
+	EmbeddingCache cache.Config
+
+	// Chunking controls how PutDocument splits long documents
+	Chunking chunk.Config
+
+	// Reranker names a registered reranker ("cohere", "jina", "local")
+	// that re-scores the top RerankTopN results of every search. Empty
+	// disables reranking. RerankerOptions configures it like
+	// EmbeddingOptions does the embedding model.
+	Reranker        string
+	RerankerOptions any
+	RerankTopN      int
+
+	// Index is "flat" (the default) to scan every vector on search, or
+	// "ivf" to keep an inverted-file index, configured by IVF, that only
+	// scans the posting lists nearest the query. Setting IVF.PQ.Subspaces
+	// makes it IVF-PQ, which keeps product quantization codes instead of
+	// vectors and re-scores the best Rescore matches at full precision.
+	// "pq" scans such codes for every vector (flat PQ), with the same
+	// re-scoring; IVF.PQ configures the codec.
+	// "hnsw" keeps an HNSW graph in memory, whose nodes Quantization
+	// ("int8" or "binary") can shrink to codes; the graph then gathers
+	// Oversample times more matches and re-ranks them at full precision.
+	// "diskann" keeps a graph index, configured by DiskANN, on disk with
+	// only compressed vectors in memory. TrainIndex builds it from the
+	// stored vectors, holding at most DiskANN.ShardSize of them in memory
+	// at once; later writes are searched in a small in-memory graph until
+	// the next build.
+	// Both graphs find neighbors by l2 distance, which ranks like cosine
+	// only on normalized vectors and can miss the best dot products, so
+	// they reject the "dot" metric.
+	Index        string
+	IVF          index.IVFConfig
+	Rescore      int
+	Quantization string
+	Oversample   int
+	DiskANN      index.DiskANNConfig
+
+	// Precision is how vectors are written to disk: "float32" (the
+	// default), or "float16" and "bfloat16" to halve that again. Opening a
+	// directory written in an older format or another precision rewrites
+	// its SSTables.
+	Precision string
 }
 
 type DB struct {
 	store    *storage.Store
+	cache    *cache.Cache
+	model    *dimensionGuard
+	reranker rerank.Reranker
 	DBConfig DBConfig
+
+	jobs     map[string]*DedupJob
+	jobsLock sync.Mutex
 }
 
-func initializeEmbeddingModel(model string) (embed.Embedder, error) {
-	switch model {
-	case "openai":
-		return openai.NewOpenAIEmbedder()
-	case "nvidia":
-		return nvidia.LoadNvidiaEmbedder()
-	case "colbert":
-		return colbert.NewColBERTEmbedder()
-	default:
-		return nil, fmt.Errorf("embedding model %s not supported", model)
+func initializeEmbeddingModel(cfg DBConfig) (embed.Embedder, error) {
+	return embed.New(cfg.EmbeddingModel, embeddingOptions(cfg))
+}
+
+// embeddingOptions are the options of the configured provider, taken from
+// the deprecated per-provider fields when EmbeddingOptions is unset
+func embeddingOptions(cfg DBConfig) any {
+	if cfg.EmbeddingOptions != nil {
+		return cfg.EmbeddingOptions
+	}
+	switch {
+	case cfg.EmbeddingModel == "openai" && cfg.OpenAI != (openai.Config{}):
+		return cfg.OpenAI
+	case cfg.EmbeddingModel == "ollama" && cfg.Ollama != (ollama.Config{}):
+		return cfg.Ollama
 	}
+	return nil
 }
 
 func DefaultConfig() DBConfig {
@@ -41,43 +137,206 @@ func DefaultConfig() DBConfig {
 		MemtableSize:   64 * 1024 * 1024,
 		Metric:         "cosine",
 		EmbeddingModel: "openai",
+		OpenAI:         openai.DefaultConfig(),
+		Ollama:         ollama.DefaultConfig(),
+		Chunking:       chunk.DefaultConfig(),
+		RerankTopN:     50,
+		Index:          storage.IndexFlat,
+		IVF:            index.DefaultIVFConfig(),
+		Rescore:        100,
+		Oversample:     4,
+		DiskANN:        index.DefaultDiskANNConfig(),
+		Precision:      storage.PrecisionFloat32,
+	}
+}
+
+// LoadConfig reads a JSON config file on top of DefaultConfig. Field names
+// match DBConfig, and "EmbeddingOptions" is passed to whichever provider
+// "EmbeddingModel" names, including ones registered by library users.
+func LoadConfig(path string) (DBConfig, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DBConfig{}, fmt.Errorf("could not read config file %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return DBConfig{}, fmt.Errorf("could not parse config file %s: %v", path, err)
 	}
+
+	return cfg, nil
 }
 
 func OpenDB(cfg DBConfig) (*DB, error) {
 	if err := os.MkdirAll(cfg.Path, 0755); err != nil {
 		return nil, fmt.Errorf("could not create db directory at %s: %v", cfg.Path, err)
 	}
-	model, err := initializeEmbeddingModel(cfg.EmbeddingModel)
+	model, err := initializeEmbeddingModel(cfg)
 	if err != nil {
-		fmt.Printf("could not initialize embedding model: %v", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("could not initialize embedding model: %v", err)
 	}
 
-	store := storage.NewStore(cfg.MemtableSize, cfg.Path, model)
-
-	return &DB{
-		store:    store,
-		DBConfig: cfg,
-	}, nil
+	return openWithModel(cfg, model)
 }
 
 func OpenDBWithEmbedder(cfg DBConfig, embedder embed.Embedder) (*DB, error) {
 	if err := os.MkdirAll(cfg.Path, 0755); err != nil {
 		return nil, fmt.Errorf("could not create db directory at %s: %v", cfg.Path, err)
 	}
-	store := storage.NewStore(cfg.MemtableSize, cfg.Path, embedder)
+
+	return openWithModel(cfg, embedder)
+}
+
+func openWithModel(cfg DBConfig, model embed.Embedder) (*DB, error) {
+	info := modelInfo(cfg, model)
+
+	storeOptions := storage.DefaultStoreOptions()
+	if cfg.Metric == storage.MetricMaxSim {
+		if _, ok := model.(embed.MultiVectorEmbedder); !ok {
+			return nil, fmt.Errorf("metric %s needs a multi-vector embedding model such as colbert, %s only produces single vectors",
+				storage.MetricMaxSim, info.Name)
+		}
+		storeOptions.MultiVector = true
+	}
+	switch cfg.Index {
+	case storage.IndexFlat, "":
+	case storage.IndexIVF, storage.IndexPQ:
+		storeOptions.Index = cfg.Index
+		storeOptions.IVF = cfg.IVF
+		if storeOptions.IVF.NLists == 0 {
+			storeOptions.IVF = index.DefaultIVFConfig()
+		}
+		storeOptions.Rescore = cfg.Rescore
+	case storage.IndexHNSW:
+		switch cfg.Quantization {
+		case index.QuantizationNone, index.QuantizationInt8, index.QuantizationBinary:
+		default:
+			return nil, fmt.Errorf("unknown quantization %s", cfg.Quantization)
+		}
+		storeOptions.Index = storage.IndexHNSW
+		storeOptions.HNSW.Quantization = cfg.Quantization
+		if cfg.Oversample > 0 {
+			storeOptions.Oversample = cfg.Oversample
+		}
+	case storage.IndexDiskANN:
+		storeOptions.Index = storage.IndexDiskANN
+		storeOptions.DiskANN = cfg.DiskANN
+		if storeOptions.DiskANN.Degree == 0 {
+			storeOptions.DiskANN = index.DefaultDiskANNConfig()
+		}
+	default:
+		return nil, fmt.Errorf("unknown index %s", cfg.Index)
+	}
+	if err := storage.CheckGraphMetric(storeOptions.Index, cfg.Metric); err != nil {
+		return nil, err
+	}
+	switch cfg.Precision {
+	case storage.PrecisionFloat32, "":
+		storeOptions.Precision = storage.PrecisionFloat32
+	case storage.PrecisionFloat16, storage.PrecisionBFloat16:
+		storeOptions.Precision = cfg.Precision
+	default:
+		return nil, fmt.Errorf("unknown vector precision %s", cfg.Precision)
+	}
+
+	stamp, err := readModelStamp(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if stamp == nil {
+		// SSTables without a stamp were written before there was one, as
+		// version 1 entries that are migrated below
+		format := storage.FormatVersion
+		if tables, _ := filepath.Glob(filepath.Join(cfg.Path, "*.sst")); len(tables) > 0 {
+			format = 1
+		}
+		stamp = &modelStamp{
+			Model:     info,
+			CreatedAt: time.Now().UnixMilli(),
+			Format:    format,
+			Precision: storeOptions.Precision,
+		}
+		if err := writeModelStamp(cfg.Path, *stamp); err != nil {
+			return nil, err
+		}
+	} else if err := checkModel(cfg.Path, stamp.Model, info); err != nil {
+		return nil, err
+	}
+	if stamp.Format < storage.FormatVersion || stamp.Precision != storeOptions.Precision {
+		if _, err := storage.Migrate(cfg.Path, storeOptions.Precision); err != nil {
+			return nil, fmt.Errorf("could not migrate %s: %v", cfg.Path, err)
+		}
+		stamp.Format = storage.FormatVersion
+		stamp.Precision = storeOptions.Precision
+		if err := writeModelStamp(cfg.Path, *stamp); err != nil {
+			return nil, err
+		}
+	}
+	if stamp.Model.Dimensions == 0 && info.Dimensions != 0 {
+		stamp.Model.Dimensions = info.Dimensions
+		if err := writeModelStamp(cfg.Path, *stamp); err != nil {
+			return nil, err
+		}
+	}
+
+	var reranker rerank.Reranker
+	if cfg.Reranker != "" {
+		reranker, err = rerank.New(cfg.Reranker, cfg.RerankerOptions)
+		if err != nil {
+			return nil, fmt.Errorf("could not initialize reranker: %v", err)
+		}
+	}
+
+	var embeddingCache *cache.Cache
+	if cfg.EmbeddingCache.Path != "" {
+		embeddingCache, err = cache.Open(cfg.EmbeddingCache)
+		if err != nil {
+			return nil, fmt.Errorf("could not open embedding cache: %v", err)
+		}
+		model = cache.Wrap(model, embeddingCache, info)
+	}
+
+	guard := &dimensionGuard{
+		embedder: model,
+		dir:      cfg.Path,
+		stamp:    *stamp,
+	}
+	store := storage.NewStoreWithOptions(cfg.MemtableSize, cfg.Path, guard, storeOptions)
 
 	return &DB{
 		store:    store,
+		cache:    embeddingCache,
+		model:    guard,
+		reranker: reranker,
 		DBConfig: cfg,
 	}, nil
 }
 
+// modelInfo identifies the embedding model, falling back to the configured
+// name for embedders that can't describe themselves
+func modelInfo(cfg DBConfig, model embed.Embedder) embed.ModelInfo {
+	if describer, ok := model.(embed.Describer); ok {
+		return describer.ModelInfo()
+	}
+	return embed.ModelInfo{Name: cfg.EmbeddingModel}
+}
+
 func (db *DB) Put(key string, value string) error {
 	return db.store.Put(key, value)
 }
 
+// PutSparse stores value with a sparse vector computed by the caller, such
+// as SPLADE term weights, next to its dense embedding
+func (db *DB) PutSparse(key string, value string, sparse search.SparseVector) error {
+	return db.store.PutSparse(key, value, sparse)
+}
+
+// PutWithOptions stores value together with a sparse vector and metadata
+func (db *DB) PutWithOptions(key string, value string, opts storage.PutOptions) error {
+	return db.store.PutWithOptions(key, value, opts)
+}
+
 func (db *DB) Delete(key string) error {
 	return db.store.Delete(key)
 }
@@ -97,5 +356,123 @@ func (db *DB) Exists(key string) bool {
 }
 
 func (db *DB) Search(query string) ([]storage.Result, error) {
-	return db.store.Search(query, db.DBConfig.Metric)
+	return db.SearchWithOptions(query, DefaultSearchOptions())
+}
+
+// SearchWithMetric overrides the configured metric for one query. "maxsim"
+// is only available when the database was opened with it.
+func (db *DB) SearchWithMetric(query string, metric string) ([]storage.Result, error) {
+	return db.SearchWithOptions(query, SearchOptions{Metric: metric})
+}
+
+const (
+	SearchModeVector  = "vector"
+	SearchModeKeyword = "keyword"
+	SearchModeHybrid  = "hybrid"
+	SearchModeSparse  = "sparse"
+)
+
+type SearchOptions struct {
+	// Metric overrides the configured metric
+	Metric string
+
+	// Mode is "vector" (the default), "keyword" for BM25 over values,
+	// "hybrid" to fuse both rankings as configured by Hybrid, or "sparse"
+	// to rank by Sparse alone
+	Mode string
+
+	// Sparse is a sparse query vector. In vector mode it is fused with the
+	// dense query as configured by Hybrid.
+	Sparse search.SparseVector
+
+	Hybrid storage.HybridOptions
+
+	// Rerank adjusts the configured reranker for this query
+	Rerank RerankOptions
+
+	// MMR re-ranks the results for diversity when set
+	MMR *MMROptions
+
+	// NProbe overrides how many posting lists an IVF index scans, trading
+	// speed for recall
+	NProbe int
+}
+
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		Mode:   SearchModeVector,
+		Hybrid: storage.DefaultHybridOptions(),
+	}
+}
+
+func (db *DB) SearchWithOptions(query string, opts SearchOptions) ([]storage.Result, error) {
+	metric := opts.Metric
+	if metric == "" {
+		metric = db.DBConfig.Metric
+	}
+
+	var results []storage.Result
+	var err error
+	switch opts.Mode {
+	case SearchModeVector, "":
+		if opts.Sparse.Len() > 0 {
+			results, err = db.store.SearchDenseSparse(query, opts.Sparse, metric, opts.Hybrid)
+		} else {
+			results, err = db.store.SearchWithProbes(query, metric, opts.NProbe)
+		}
+	case SearchModeKeyword:
+		results, err = db.store.SearchKeyword(query)
+	case SearchModeHybrid:
+		results, err = db.store.SearchHybrid(query, metric, opts.Hybrid)
+	case SearchModeSparse:
+		results, err = db.store.SearchSparse(opts.Sparse)
+	default:
+		return nil, fmt.Errorf("unknown search mode %s", opts.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// only plain vector search keeps l2 distances, fused, keyword and
+	// sparse scores are always higher-is-better
+	plainVector := (opts.Mode == SearchModeVector || opts.Mode == "") && opts.Sparse.Len() == 0
+	if !plainVector {
+		metric = ""
+	}
+
+	if db.reranker != nil && !opts.Rerank.Disabled && query != "" {
+		results, err = db.rerank(query, results, metric, opts.Rerank)
+		if err != nil {
+			return nil, err
+		}
+		metric = ""
+	}
+
+	if opts.MMR != nil {
+		results = db.diversify(results, metric, *opts.MMR)
+	}
+
+	return results, nil
+}
+
+// TrainIndex trains the IVF index on the vectors stored so far, or retrains
+// it once they have drifted from the ones it was trained on. It (re)builds
+// HNSW graphs and disk indexes.
+func (db *DB) TrainIndex() error {
+	return db.store.TrainIndex()
+}
+
+// Model reports the embedding model stamped into the database, including
+// its dimensions once the first vector has been stored.
+func (db *DB) Model() embed.ModelInfo {
+	return db.model.ModelInfo()
+}
+
+// EmbeddingCacheStats reports embedding cache activity. The second return
+// value is false when the cache is disabled.
+func (db *DB) EmbeddingCacheStats() (cache.Stats, bool) {
+	if db.cache == nil {
+		return cache.Stats{}, false
+	}
+	return db.cache.Stats(), true
 }
@@ -1,13 +1,28 @@
 package db
 
 import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/chunk"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/embed/cache"
+	"github.com/ahhcash/ghastlydb/embed/ollama"
+	"github.com/ahhcash/ghastlydb/index"
 	"github.com/ahhcash/ghastlydb/mocks"
+	"github.com/ahhcash/ghastlydb/rerank"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"math"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 type DBTestSuite struct {
@@ -42,6 +57,27 @@ func (s *DBTestSuite) TestDefaultConfig() {
 	assert.Equal(s.T(), "cosine", cfg.Metric)
 }
 
+func (s *DBTestSuite) TestDeprecatedEmbeddingFields() {
+	// the per-provider fields still configure their provider, unless
+	// EmbeddingOptions is set
+	cfg := DefaultConfig()
+	cfg.OpenAI.Model = "text-embedding-3-large"
+	assert.Equal(s.T(), cfg.OpenAI, embeddingOptions(cfg))
+
+	cfg.EmbeddingModel = "ollama"
+	cfg.Ollama.Model = "mxbai-embed-large"
+	assert.Equal(s.T(), cfg.Ollama, embeddingOptions(cfg))
+
+	cfg.EmbeddingOptions = ollama.Config{Model: "all-minilm"}
+	assert.Equal(s.T(), cfg.EmbeddingOptions, embeddingOptions(cfg))
+
+	cfg = DBConfig{EmbeddingModel: "ollama"}
+	assert.Nil(s.T(), embeddingOptions(cfg))
+
+	// the default leaves the host to $OLLAMA_HOST
+	assert.Empty(s.T(), DefaultConfig().Ollama.BaseURL)
+}
+
 func (s *DBTestSuite) TestOpenDB() {
 	cfg := DBConfig{
 		Metric:         "dot",
@@ -55,6 +91,56 @@ func (s *DBTestSuite) TestOpenDB() {
 	assert.NotNil(s.T(), database)
 }
 
+func (s *DBTestSuite) TestOpenDBUnknownModel() {
+	cfg := DBConfig{
+		Metric:         "cosine",
+		EmbeddingModel: "does-not-exist",
+		MemtableSize:   1024,
+		Path:           s.testPath,
+	}
+
+	database, err := OpenDB(cfg)
+	assert.Error(s.T(), err)
+	assert.Nil(s.T(), database)
+}
+
+func (s *DBTestSuite) TestOpenDBWithRegisteredEmbedder() {
+	type constantOptions struct {
+		Value float64
+	}
+	embed.Register("db-test-constant", func() constantOptions {
+		return constantOptions{Value: 1}
+	}, func(options constantOptions) (embed.Embedder, error) {
+		mockEmbedder := &mocks.MockEmbedder{}
+		mockEmbedder.On("Embed", mock.AnythingOfType("string")).Return(
+			[]float64{options.Value, options.Value},
+			nil,
+		)
+		return mockEmbedder, nil
+	})
+
+	configPath := s.testPath + "/config.json"
+	require.NoError(s.T(), os.MkdirAll(s.testPath, 0755))
+	require.NoError(s.T(), os.WriteFile(configPath, []byte(`{
+		"Path": "`+s.testPath+`",
+		"MemtableSize": 1024,
+		"EmbeddingModel": "db-test-constant",
+		"EmbeddingOptions": {"Value": 0.5}
+	}`), 0644))
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "cosine", cfg.Metric)
+
+	database, err := OpenDB(cfg)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.Put("key", "value"))
+	entry, exists := database.store.Get("key")
+	require.True(s.T(), exists)
+	assert.Equal(s.T(), []float32{0.5, 0.5}, entry.Vector)
+}
+
 func (s *DBTestSuite) TestOpenDBWithEmbedder() {
 	mockEmbedder := mocks.MockEmbedder{}
 	mockEmbedder.On("Embed", mock.AnythingOfType("string")).Return(
@@ -161,6 +247,887 @@ func (s *DBTestSuite) TestSearch() {
 	assert.NotEmpty(s.T(), results)
 }
 
+func (s *DBTestSuite) TestEmbeddingCache() {
+	mockEmbedder := mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", mock.AnythingOfType("string")).Return(
+		[]float64{0.43324, 0.4324532, 0.432424},
+		nil,
+	)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "openai",
+		EmbeddingCache: cache.Config{Path: s.testPath + "/embedding_cache"},
+	}
+
+	database, err := OpenDBWithEmbedder(cfg, &mockEmbedder)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.Put("key1", "same document"))
+	require.NoError(s.T(), database.Put("key2", "same document"))
+	_, err = database.Search("same document")
+	require.NoError(s.T(), err)
+
+	mockEmbedder.AssertNumberOfCalls(s.T(), "Embed", 1)
+
+	stats, enabled := database.EmbeddingCacheStats()
+	assert.True(s.T(), enabled)
+	assert.Equal(s.T(), int64(2), stats.Hits)
+	assert.Equal(s.T(), int64(1), stats.Misses)
+}
+
+func (s *DBTestSuite) TestModelStampedOnCreate() {
+	mockEmbedder := mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", mock.AnythingOfType("string")).Return(
+		[]float64{0.1, 0.2, 0.3},
+		nil,
+	)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "openai",
+	}
+
+	database, err := OpenDBWithEmbedder(cfg, &mockEmbedder)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "openai", database.Model().Name)
+	assert.Equal(s.T(), 0, database.Model().Dimensions)
+
+	require.NoError(s.T(), database.Put("key", "value"))
+	assert.Equal(s.T(), 3, database.Model().Dimensions)
+
+	// reopening with the same model picks the stamp back up
+	reopened, err := OpenDBWithEmbedder(cfg, &mockEmbedder)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, reopened.Model().Dimensions)
+
+	// a different model is refused
+	cfg.EmbeddingModel = "nvidia"
+	_, err = OpenDBWithEmbedder(cfg, &mockEmbedder)
+	require.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "created with embedding model openai (3 dimensions)")
+}
+
+func (s *DBTestSuite) TestRejectsMismatchedDimensions() {
+	mockEmbedder := mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "short").Return([]float64{0.1, 0.2}, nil)
+	mockEmbedder.On("Embed", mock.AnythingOfType("string")).Return([]float64{0.1, 0.2, 0.3}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "openai",
+	}
+
+	database, err := OpenDBWithEmbedder(cfg, &mockEmbedder)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.Put("key1", "value"))
+	assert.Error(s.T(), database.Put("key2", "short"))
+	assert.False(s.T(), database.Exists("key2"))
+
+	_, err = database.Search("short")
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestMaxSimRequiresMultiVectorModel() {
+	mockEmbedder := mocks.MockEmbedder{}
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "maxsim",
+		EmbeddingModel: "openai",
+	}
+
+	_, err := OpenDBWithEmbedder(cfg, &mockEmbedder)
+	assert.Error(s.T(), err)
+
+	multiEmbedder := mocks.MockMultiVectorEmbedder{}
+	multiEmbedder.On("EmbedMulti", mock.AnythingOfType("string")).Return([][]float64{{1, 0}, {0, 1}}, nil)
+
+	database, err := OpenDBWithEmbedder(cfg, &multiEmbedder)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), database.Put("key", "value"))
+
+	results, err := database.Search("query")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 1)
+	assert.InDelta(s.T(), 2.0, results[0].Score, 0.000001)
+
+	// single-vector metrics still work on the pooled vector
+	multiEmbedder.On("Embed", mock.AnythingOfType("string")).Return([]float64{1, 0}, nil)
+	results, err = database.SearchWithMetric("query", "cosine")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 1)
+	assert.InDelta(s.T(), 0.7071067, results[0].Score, 0.00001)
+}
+
+func (s *DBTestSuite) TestHashEmbedder() {
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "hash",
+	}
+
+	database, err := OpenDB(cfg)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 384, database.Model().Dimensions)
+
+	testData := map[string]string{
+		"go":     "Go is a statically typed compiled programming language",
+		"pasta":  "Boil the pasta in salted water for ten minutes",
+		"python": "Python is a dynamically typed programming language",
+	}
+	for k, v := range testData {
+		require.NoError(s.T(), database.Put(k, v))
+	}
+
+	results, err := database.Search("how long to boil pasta")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 3)
+	assert.Equal(s.T(), "pasta", results[0].Key)
+
+	results, err = database.Search("typed programming languages")
+	require.NoError(s.T(), err)
+	assert.NotEqual(s.T(), "pasta", results[0].Key)
+	assert.Equal(s.T(), "pasta", results[2].Key)
+}
+
+func (s *DBTestSuite) TestPutDocument() {
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "hash",
+		Chunking:       chunk.Config{Strategy: chunk.StrategySentences, Size: 12},
+	}
+
+	database, err := OpenDB(cfg)
+	require.NoError(s.T(), err)
+
+	guide := "Boil the pasta in salted water for ten minutes. " +
+		"Drain it and keep a cup of the cooking water. " +
+		"Go is a statically typed compiled programming language."
+	require.NoError(s.T(), database.PutDocument("guide", guide))
+	require.NoError(s.T(), database.Put("python", "Python is a dynamically typed programming language"))
+
+	value, err := database.Get("guide")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), guide, value)
+
+	documents, err := database.SearchDocuments("how long to boil pasta", "", 1)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), documents, 2)
+	assert.Equal(s.T(), "guide", documents[0].Key)
+	assert.Equal(s.T(), guide, documents[0].Value)
+	require.Len(s.T(), documents[0].Chunks, 1)
+	assert.Equal(s.T(), "Boil the pasta in salted water for ten minutes.", documents[0].Chunks[0].Value)
+	assert.Equal(s.T(), "guide", documents[0].Chunks[0].Parent)
+	assert.Equal(s.T(), "python", documents[1].Key)
+
+	documents, err = database.SearchDocuments("typed programming language", "", 0)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), documents[0].Chunks, 1)
+	for _, document := range documents {
+		if document.Key == "guide" {
+			assert.Len(s.T(), document.Chunks, 3)
+		}
+	}
+
+	require.NoError(s.T(), database.Delete("guide"))
+	results, err := database.Search("boil pasta")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 1)
+
+	assert.Error(s.T(), database.PutDocument("empty", "  "))
+}
+
+func (s *DBTestSuite) TestHybridSearch() {
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "hash",
+	}
+
+	database, err := OpenDB(cfg)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.Put("timeout", "The request timed out waiting for the upstream server"))
+	require.NoError(s.T(), database.Put("quota", "Error QX-7731 means the account quota is exhausted"))
+	require.NoError(s.T(), database.Put("pasta", "Boil the pasta in salted water"))
+
+	opts := DefaultSearchOptions()
+	opts.Mode = SearchModeKeyword
+	results, err := database.SearchWithOptions("qx-7731", opts)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 1)
+	assert.Equal(s.T(), "quota", results[0].Key)
+
+	opts.Mode = SearchModeHybrid
+	results, err = database.SearchWithOptions("what does qx-7731 mean", opts)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "quota", results[0].Key)
+
+	opts.Hybrid.Fusion = storage.FusionWeighted
+	results, err = database.SearchWithOptions("what does qx-7731 mean", opts)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "quota", results[0].Key)
+
+	opts.Mode = "fuzzy"
+	_, err = database.SearchWithOptions("qx-7731", opts)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestSparseSearch() {
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "hash",
+	}
+
+	database, err := OpenDB(cfg)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.PutSparse("a", "first document",
+		search.SparseVector{Indices: []uint32{7, 3}, Values: []float64{0.5, 2}}))
+	require.NoError(s.T(), database.PutSparse("b", "second document",
+		search.SparseVector{Indices: []uint32{3}, Values: []float64{0.1}}))
+	require.NoError(s.T(), database.Put("c", "third document"))
+
+	opts := DefaultSearchOptions()
+	opts.Mode = SearchModeSparse
+	opts.Sparse = search.SparseVector{Indices: []uint32{3}, Values: []float64{1}}
+	results, err := database.SearchWithOptions("", opts)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 2)
+	assert.Equal(s.T(), "a", results[0].Key)
+	assert.InDelta(s.T(), 2.0, results[0].Score, 0.000001)
+
+	// dense and sparse together rank every document
+	opts.Mode = SearchModeVector
+	results, err = database.SearchWithOptions("first document", opts)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+	assert.Equal(s.T(), "a", results[0].Key)
+
+	err = database.PutSparse("d", "fourth", search.SparseVector{Indices: []uint32{1, 1}, Values: []float64{1, 2}})
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestDiversityAndGrouping() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "install with go get").Return([]float64{0.95, 0.31, 0}, nil)
+	mockEmbedder.On("Embed", "install using go get").Return([]float64{0.94, 0.34, 0}, nil)
+	mockEmbedder.On("Embed", "install from the release page").Return([]float64{0.8, -0.6, 0}, nil)
+	mockEmbedder.On("Embed", "unrelated").Return([]float64{0, 0, 1}, nil)
+	mockEmbedder.On("Embed", "how to install").Return([]float64{1, 0, 0}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+
+	put := func(key, value, source string) {
+		require.NoError(s.T(), database.PutWithOptions(key, value, storage.PutOptions{
+			Metadata: map[string]string{"source": source},
+		}))
+	}
+	put("a", "install with go get", "docs")
+	put("b", "install using go get", "docs")
+	put("c", "install from the release page", "blog")
+	put("d", "unrelated", "blog")
+
+	results, err := database.Search("how to install")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"a", "b", "c", "d"}, keys(results))
+	assert.Equal(s.T(), "docs", results[0].Metadata["source"])
+
+	opts := DefaultSearchOptions()
+	mmr := DefaultMMROptions()
+	opts.MMR = &mmr
+	results, err = database.SearchWithOptions("how to install", opts)
+	require.NoError(s.T(), err)
+	// the near copy of the best match drops to the end
+	assert.Equal(s.T(), []string{"a", "c", "d", "b"}, keys(results))
+
+	groups := GroupByField(results, "source", 1)
+	require.Len(s.T(), groups, 2)
+	assert.Equal(s.T(), "docs", groups[0].Value)
+	assert.Equal(s.T(), []string{"a"}, keys(groups[0].Results))
+	assert.Equal(s.T(), "blog", groups[1].Value)
+	assert.Equal(s.T(), []string{"c"}, keys(groups[1].Results))
+}
+
+// lengthReranker prefers short documents, which no embedding would
+type lengthReranker struct{}
+
+func (lengthReranker) Rerank(_ string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	for i, document := range documents {
+		scores[i] = 1 / float64(len(document))
+	}
+	return scores, nil
+}
+
+func (s *DBTestSuite) TestRerank() {
+	rerank.Register("db-test-length", func() struct{} { return struct{}{} }, func(struct{}) (rerank.Reranker, error) {
+		return lengthReranker{}, nil
+	})
+
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "a long and very relevant answer").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "medium answer").Return([]float64{0.8, 0.6}, nil)
+	mockEmbedder.On("Embed", "short").Return([]float64{0, 1}, nil)
+	mockEmbedder.On("Embed", "question").Return([]float64{1, 0}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+		Reranker:       "db-test-length",
+		RerankTopN:     2,
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), database.Put("long", "a long and very relevant answer"))
+	require.NoError(s.T(), database.Put("medium", "medium answer"))
+	require.NoError(s.T(), database.Put("short", "short"))
+
+	// only the two best vector matches are reranked
+	results, err := database.Search("question")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"medium", "long"}, keys(results))
+	assert.InDelta(s.T(), 1.0/13, results[0].Score, 0.000001)
+
+	opts := DefaultSearchOptions()
+	opts.Rerank.TopN = 3
+	results, err = database.SearchWithOptions("question", opts)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"short", "medium", "long"}, keys(results))
+
+	opts.Rerank.Disabled = true
+	results, err = database.SearchWithOptions("question", opts)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"long", "medium", "short"}, keys(results))
+
+	cfg.Reranker = "missing"
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestRecommend() {
+	vectors := map[string][]float64{
+		"cat":    {1, 0, 0},
+		"kitten": {0.9, 0.1, 0},
+		"lion":   {0.7, 0, 0.7},
+		"dog":    {0, 1, 0},
+		"puppy":  {0.1, 0.9, 0},
+	}
+	mockEmbedder := &mocks.MockEmbedder{}
+	for text, vector := range vectors {
+		mockEmbedder.On("Embed", text).Return(vector, nil)
+	}
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	for _, key := range []string{"cat", "kitten", "lion", "dog", "puppy"} {
+		require.NoError(s.T(), database.Put(key, key))
+	}
+	mockEmbedder.Calls = nil
+
+	results, err := database.Recommend(RecommendOptions{Positive: []string{"cat"}})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"kitten", "lion", "puppy", "dog"}, keys(results))
+	// stored vectors are reused, nothing is embedded again
+	mockEmbedder.AssertNotCalled(s.T(), "Embed", mock.Anything)
+
+	results, err = database.Recommend(RecommendOptions{
+		Positive: []string{"cat"},
+		Negative: []string{"kitten"},
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "lion", results[0].Key)
+	assert.NotContains(s.T(), keys(results), "kitten")
+
+	results, err = database.Recommend(RecommendOptions{
+		Positive: []string{"cat", "dog"},
+		Negative: []string{"lion"},
+		Strategy: RecommendBestScore,
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"kitten", "puppy"}, keys(results))
+	assert.InDelta(s.T(), 0.99388, results[0].Score, 0.0001)
+
+	// under l2 both strategies put the smallest distances first and score
+	// by distance
+	results, err = database.Recommend(RecommendOptions{Positive: []string{"cat"}, Metric: "l2"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"kitten", "lion", "puppy", "dog"}, keys(results))
+	assert.InDelta(s.T(), search.L2([]float32{1, 0, 0}, []float32{0.9, 0.1, 0}), results[0].Score, 1e-6)
+
+	results, err = database.Recommend(RecommendOptions{
+		Positive: []string{"cat", "lion"},
+		Strategy: RecommendBestScore,
+		Metric:   "l2",
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"kitten", "puppy", "dog"}, keys(results))
+	assert.InDelta(s.T(), search.L2([]float32{1, 0, 0}, []float32{0.9, 0.1, 0}), results[0].Score, 1e-6)
+	// dog is scored by lion, the closer of the two examples
+	assert.InDelta(s.T(), search.L2([]float32{0.7, 0, 0.7}, []float32{0, 1, 0}), results[2].Score, 1e-6)
+
+	// candidates closer to a negative example are left out
+	results, err = database.Recommend(RecommendOptions{
+		Positive: []string{"dog"},
+		Negative: []string{"cat"},
+		Strategy: RecommendBestScore,
+		Metric:   "l2",
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"puppy"}, keys(results))
+
+	_, err = database.Recommend(RecommendOptions{})
+	assert.Error(s.T(), err)
+	_, err = database.Recommend(RecommendOptions{Positive: []string{"unicorn"}})
+	assert.Error(s.T(), err)
+	_, err = database.Recommend(RecommendOptions{Positive: []string{"cat"}, Strategy: "random"})
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestSearchRadius() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	for i, text := range []string{"p0", "p1", "p2", "p3"} {
+		mockEmbedder.On("Embed", text).Return([]float64{float64(i), 1}, nil)
+	}
+	mockEmbedder.On("Embed", "far").Return([]float64{10, 1}, nil)
+	mockEmbedder.On("Embed", "origin").Return([]float64{0, 1}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "l2",
+		EmbeddingModel: "mock",
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	for _, key := range []string{"far", "p3", "p1", "p0", "p2"} {
+		require.NoError(s.T(), database.Put(key, key))
+	}
+
+	page, err := database.SearchRadius("origin", RadiusOptions{Radius: 2.5, Limit: 2})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"p0", "p1"}, keys(page.Results))
+	assert.Equal(s.T(), 3, page.Total)
+	assert.Equal(s.T(), 2, page.NextOffset)
+
+	page, err = database.SearchRadius("origin", RadiusOptions{Radius: 2.5, Offset: page.NextOffset, Limit: 2})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"p2"}, keys(page.Results))
+	assert.Equal(s.T(), 0, page.NextOffset)
+
+	// for cosine the radius is a minimum similarity
+	page, err = database.SearchRadiusVector([]float64{0, 1}, RadiusOptions{Radius: 0.7, Metric: "cosine"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"p0", "p1"}, keys(page.Results))
+
+	page, err = database.SearchRadius("origin", RadiusOptions{Radius: 2.5, Offset: 10})
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), page.Results)
+	assert.Equal(s.T(), 3, page.Total)
+
+	_, err = database.SearchRadius("origin", RadiusOptions{Metric: storage.MetricMaxSim})
+	assert.Error(s.T(), err)
+
+	// an HNSW store walks its graph, or scans when asked to be exact
+	cfg.Path = filepath.Join(s.testPath, "hnsw")
+	cfg.Index = storage.IndexHNSW
+	graphed, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	for _, key := range []string{"far", "p3", "p1", "p0", "p2"} {
+		require.NoError(s.T(), graphed.Put(key, key))
+	}
+	for _, exact := range []bool{false, true} {
+		page, err = graphed.SearchRadius("origin", RadiusOptions{Radius: 2.5, Exact: exact})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{"p0", "p1", "p2"}, keys(page.Results))
+	}
+}
+
+func (s *DBTestSuite) TestDedup() {
+	vectors := map[string][]float64{
+		"original": {1, 0, 0},
+		"copy":     {0.99, 0.05, 0},
+		"reprint":  {0.98, 0.1, 0},
+		"other":    {0, 1, 0},
+		"third":    {0, 0, 1},
+		"third2":   {0, 0.02, 1},
+	}
+	mockEmbedder := &mocks.MockEmbedder{}
+	for text, vector := range vectors {
+		mockEmbedder.On("Embed", text).Return(vector, nil)
+	}
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024 * 1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), database.PutWithOptions("a", "original", storage.PutOptions{Metadata: map[string]string{"source": "x"}}))
+	require.NoError(s.T(), database.PutWithOptions("a2", "copy", storage.PutOptions{Metadata: map[string]string{"source": "y", "lang": "en"}}))
+	require.NoError(s.T(), database.Put("a3", "reprint"))
+	require.NoError(s.T(), database.Put("b", "other"))
+	require.NoError(s.T(), database.Put("c", "third"))
+	require.NoError(s.T(), database.Put("c2", "third2"))
+
+	phases := make(map[string]bool)
+	opts := DefaultDedupOptions()
+	opts.Progress = func(p DedupProgress) {
+		phases[p.Phase] = true
+	}
+	report, err := database.Dedup(context.Background(), opts)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 6, report.Scanned)
+	require.Len(s.T(), report.Clusters, 2)
+	assert.Equal(s.T(), "a", report.Clusters[0].Keep)
+	assert.ElementsMatch(s.T(), []string{"a2", "a3"}, report.Clusters[0].Duplicates)
+	assert.NotEmpty(s.T(), report.Clusters[0].Pairs)
+	assert.Equal(s.T(), "c", report.Clusters[1].Keep)
+	assert.Equal(s.T(), []string{"c2"}, report.Clusters[1].Duplicates)
+	assert.Equal(s.T(), 0, report.Removed)
+	assert.True(s.T(), phases[DedupPhaseComparing])
+	assert.True(s.T(), database.Exists("a2"))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = database.Dedup(cancelled, DefaultDedupOptions())
+	assert.ErrorIs(s.T(), err, context.Canceled)
+
+	opts = DefaultDedupOptions()
+	opts.Action = DedupActionMerge
+	job := database.StartDedup(opts)
+	found, exists := database.DedupJob(job.ID)
+	require.True(s.T(), exists)
+	report, err = found.Wait()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, report.Removed)
+	assert.Equal(s.T(), DedupJobDone, job.Status().State)
+
+	assert.False(s.T(), database.Exists("a2"))
+	assert.False(s.T(), database.Exists("c2"))
+	assert.True(s.T(), database.Exists("b"))
+	entry, _ := database.store.Get("a")
+	assert.Equal(s.T(), map[string]string{"source": "x", "lang": "en"}, entry.Metadata)
+
+	// finished jobs are dropped once they are older than the retention
+	defer func(retention time.Duration) { dedupJobRetention = retention }(dedupJobRetention)
+	dedupJobRetention = 0
+	next := database.StartDedup(DefaultDedupOptions())
+	_, err = next.Wait()
+	require.NoError(s.T(), err)
+	_, exists = database.DedupJob(job.ID)
+	assert.False(s.T(), exists)
+	_, exists = database.DedupJob(next.ID)
+	assert.True(s.T(), exists)
+
+	opts.Threshold = 2
+	_, err = database.Dedup(context.Background(), opts)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestDedupLargeCluster() {
+	// 40 near-copies of one vector among 500 random ones
+	rng := rand.New(rand.NewSource(3))
+	random := func() []float64 {
+		vector := make([]float64, 16)
+		for i := range vector {
+			vector[i] = rng.NormFloat64()
+		}
+		return vector
+	}
+	base := random()
+
+	mockEmbedder := &mocks.MockEmbedder{}
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024 * 1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	for i := 0; i < 500; i++ {
+		text := fmt.Sprintf("random %d", i)
+		mockEmbedder.On("Embed", text).Return(random(), nil)
+		require.NoError(s.T(), database.Put(fmt.Sprintf("r%03d", i), text))
+	}
+	copies := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		vector := make([]float64, len(base))
+		for j := range vector {
+			vector[j] = base[j] + 0.01*rng.NormFloat64()
+		}
+		text := fmt.Sprintf("copy %d", i)
+		mockEmbedder.On("Embed", text).Return(vector, nil)
+		key := fmt.Sprintf("c%02d", i)
+		require.NoError(s.T(), database.Put(key, text))
+		copies = append(copies, key)
+	}
+
+	opts := DefaultDedupOptions()
+	opts.Threshold = 0.98
+	report, err := database.Dedup(context.Background(), opts)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), report.Clusters, 1)
+	cluster := report.Clusters[0]
+	assert.ElementsMatch(s.T(), copies, append([]string{cluster.Keep}, cluster.Duplicates...))
+	// every pair of copies is found, not just enough of them to join the
+	// cluster
+	assert.Len(s.T(), cluster.Pairs, 40*39/2)
+}
+
+func (s *DBTestSuite) TestIVFIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "left").Return([]float64{-1, 0}, nil)
+	mockEmbedder.On("Embed", "more left").Return([]float64{-1, 0.1}, nil)
+	mockEmbedder.On("Embed", "right").Return([]float64{1, 0}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+		Index:          storage.IndexIVF,
+		IVF:            index.DefaultIVFConfig(),
+	}
+	cfg.IVF.NLists = 2
+	cfg.IVF.NProbe = 1
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), database.Put("l", "left"))
+	require.NoError(s.T(), database.Put("ml", "more left"))
+	require.NoError(s.T(), database.Put("r", "right"))
+	require.NoError(s.T(), database.TrainIndex())
+
+	results, err := database.Search("left")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"l", "ml"}, keys(results))
+
+	opts := DefaultSearchOptions()
+	opts.NProbe = 2
+	results, err = database.SearchWithOptions("left", opts)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+
+	cfg.Index = "lsh"
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestHNSWQuantization() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "left").Return([]float64{-1, 0}, nil)
+	mockEmbedder.On("Embed", "more left").Return([]float64{-1, 0.1}, nil)
+	mockEmbedder.On("Embed", "right").Return([]float64{1, 0}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+		Index:          storage.IndexHNSW,
+		Quantization:   index.QuantizationInt8,
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), database.Put("l", "left"))
+	require.NoError(s.T(), database.Put("ml", "more left"))
+	require.NoError(s.T(), database.Put("r", "right"))
+	require.NoError(s.T(), database.TrainIndex())
+
+	results, err := database.Search("left")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"l", "ml", "r"}, keys(results))
+
+	cfg.Quantization = "int4"
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestDiskANNIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "left").Return([]float64{-1, 0}, nil)
+	mockEmbedder.On("Embed", "more left").Return([]float64{-1, 0.1}, nil)
+	mockEmbedder.On("Embed", "right").Return([]float64{1, 0}, nil)
+
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+		Index:          storage.IndexDiskANN,
+	}
+	database, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), database.Put("l", "left"))
+	require.NoError(s.T(), database.Put("r", "right"))
+	require.NoError(s.T(), database.TrainIndex())
+	require.NoError(s.T(), database.Put("ml", "more left"))
+
+	results, err := database.Search("left")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"l", "ml", "r"}, keys(results))
+
+	// graph indexes search by l2 distance, which doesn't rank dot products
+	for _, graph := range []string{storage.IndexHNSW, storage.IndexDiskANN} {
+		cfg.Index = graph
+		cfg.Metric = "dot"
+		cfg.Path = s.T().TempDir()
+		_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+		assert.Error(s.T(), err, graph)
+	}
+}
+
+func (s *DBTestSuite) TestMigrateOnOpen() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	_, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	stamp, err := readModelStamp(s.testPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), storage.FormatVersion, stamp.Format)
+	assert.Equal(s.T(), storage.PrecisionFloat32, stamp.Precision)
+
+	// a directory from before the format was stamped, with a table of
+	// version 1 entries holding float64 vectors
+	stamp.Format = 0
+	stamp.Precision = ""
+	require.NoError(s.T(), writeModelStamp(s.testPath, *stamp))
+	value := []byte{0}
+	value = binary.LittleEndian.AppendUint64(value, 1)
+	value = binary.LittleEndian.AppendUint32(value, 3)
+	value = append(value, "old"...)
+	value = binary.LittleEndian.AppendUint32(value, 2)
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(0.25))
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(-1))
+	record := binary.LittleEndian.AppendUint32(nil, 1)
+	record = append(record, 'k')
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(value)))
+	record = append(record, value...)
+	path := filepath.Join(s.testPath, "old.sst")
+	require.NoError(s.T(), os.WriteFile(path, record, 0644))
+
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	stamp, err = readModelStamp(s.testPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), storage.FormatVersion, stamp.Format)
+
+	sstable, err := storage.OpenSSTable(path)
+	require.NoError(s.T(), err)
+	defer func() { _ = sstable.Close() }()
+	entry, exists, err := sstable.Get("k")
+	require.NoError(s.T(), err)
+	require.True(s.T(), exists)
+	assert.Equal(s.T(), "old", entry.Value)
+	assert.Equal(s.T(), []float32{0.25, -1}, entry.Vector)
+	info, err := os.Stat(path)
+	require.NoError(s.T(), err)
+	assert.Less(s.T(), info.Size(), int64(len(record)))
+
+	cfg.Precision = "float8"
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	assert.Error(s.T(), err)
+}
+
+func (s *DBTestSuite) TestMigrateUnstampedOnOpen() {
+	// a directory written before model.json existed, holding only a
+	// table of version 1 entries with float64 vectors
+	require.NoError(s.T(), os.MkdirAll(s.testPath, 0755))
+	value := []byte{0}
+	value = binary.LittleEndian.AppendUint64(value, 1)
+	value = binary.LittleEndian.AppendUint32(value, 3)
+	value = append(value, "old"...)
+	value = binary.LittleEndian.AppendUint32(value, 2)
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(0.25))
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(-1))
+	record := binary.LittleEndian.AppendUint32(nil, 1)
+	record = append(record, 'k')
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(value)))
+	record = append(record, value...)
+	path := filepath.Join(s.testPath, "old.sst")
+	require.NoError(s.T(), os.WriteFile(path, record, 0644))
+
+	mockEmbedder := &mocks.MockEmbedder{}
+	cfg := DBConfig{
+		Path:           s.testPath,
+		MemtableSize:   1024,
+		Metric:         "cosine",
+		EmbeddingModel: "mock",
+	}
+	_, err := OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	stamp, err := readModelStamp(s.testPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), storage.FormatVersion, stamp.Format)
+	info, err := os.Stat(path)
+	require.NoError(s.T(), err)
+	assert.Less(s.T(), info.Size(), int64(len(record)))
+
+	sstable, err := storage.OpenSSTable(path)
+	require.NoError(s.T(), err)
+	defer func() { _ = sstable.Close() }()
+	entry, exists, err := sstable.Get("k")
+	require.NoError(s.T(), err)
+	require.True(s.T(), exists)
+	assert.Equal(s.T(), "old", entry.Value)
+	assert.Equal(s.T(), []float32{0.25, -1}, entry.Vector)
+
+	// a new directory is stamped current without migrating
+	require.NoError(s.T(), os.RemoveAll(s.testPath))
+	_, err = OpenDBWithEmbedder(cfg, mockEmbedder)
+	require.NoError(s.T(), err)
+	stamp, err = readModelStamp(s.testPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), storage.FormatVersion, stamp.Format)
+}
+
+func keys(results []storage.Result) []string {
+	keys := make([]string, len(results))
+	for i, result := range results {
+		keys[i] = result.Key
+	}
+	return keys
+}
+
 func TestDBSuite(t *testing.T) {
 	suite.Run(t, new(DBTestSuite))
 }
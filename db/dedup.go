@@ -0,0 +1,473 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
+	"github.com/google/uuid"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DedupActionReport only reports duplicates
+	DedupActionReport = "report"
+
+	// DedupActionDelete deletes every duplicate but the kept entry
+	DedupActionDelete = "delete"
+
+	// DedupActionMerge deletes the duplicates like DedupActionDelete, after
+	// copying their metadata fields onto the kept entry where it has none
+	DedupActionMerge = "merge"
+)
+
+const (
+	DedupPhaseScanning  = "scanning"
+	DedupPhaseIndexing  = "indexing"
+	DedupPhaseComparing = "comparing"
+	DedupPhaseResolving = "resolving"
+)
+
+type DedupOptions struct {
+	// Threshold is the cosine similarity at which two entries count as
+	// duplicates. Similarity is always cosine, whatever the configured
+	// metric, since near-copies differ in length more than in direction.
+	Threshold float64
+
+	// Action is DedupActionReport (the default), DedupActionDelete or
+	// DedupActionMerge
+	Action string
+
+	// Ef is how many candidates seed each radius walk over the index
+	Ef int
+
+	// Progress, when set, is called as the job advances
+	Progress func(DedupProgress)
+}
+
+func DefaultDedupOptions() DedupOptions {
+	return DedupOptions{
+		Threshold: 0.95,
+		Action:    DedupActionReport,
+		Ef:        64,
+	}
+}
+
+// DedupProgress reports how far a dedup job got within its current phase
+type DedupProgress struct {
+	Phase string `json:"phase"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// DuplicatePair is two entries at least as similar as the threshold
+type DuplicatePair struct {
+	A          string  `json:"a"`
+	B          string  `json:"b"`
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateCluster is a group of entries joined by duplicate pairs. Keep is
+// the oldest of them, the one that survives a delete or merge.
+type DuplicateCluster struct {
+	Keep       string          `json:"keep"`
+	Duplicates []string        `json:"duplicates"`
+	Pairs      []DuplicatePair `json:"pairs"`
+}
+
+type DedupReport struct {
+	// Scanned counts the entries compared, chunks and entries without a
+	// vector are left out
+	Scanned  int                `json:"scanned"`
+	Clusters []DuplicateCluster `json:"clusters"`
+
+	// Removed counts the duplicates deleted or merged away
+	Removed int `json:"removed"`
+}
+
+type dedupEntry struct {
+	key       string
+	vector    []float32
+	timestamp int64
+	metadata  map[string]string
+}
+
+// Dedup finds near-duplicate entries and optionally removes them. Instead
+// of comparing every pair, it builds an HNSW index over the normalized
+// vectors and walks the radius around each entry, so a few duplicates may
+// be missed in exchange for scaling to large collections. Cancelling ctx
+// stops the job between entries, and nothing is deleted unless it had
+// already reached the resolving phase.
+func (db *DB) Dedup(ctx context.Context, opts DedupOptions) (DedupReport, error) {
+	switch opts.Action {
+	case DedupActionReport, DedupActionDelete, DedupActionMerge, "":
+	default:
+		return DedupReport{}, fmt.Errorf("unknown dedup action %s", opts.Action)
+	}
+	if opts.Threshold <= 0 || opts.Threshold > 1 {
+		return DedupReport{}, fmt.Errorf("dedup threshold must be in (0, 1], got %v", opts.Threshold)
+	}
+	if opts.Ef <= 0 {
+		opts.Ef = DefaultDedupOptions().Ef
+	}
+	progress := func(phase string, done int, total int) {
+		if opts.Progress != nil {
+			opts.Progress(DedupProgress{Phase: phase, Done: done, Total: total})
+		}
+	}
+
+	progress(DedupPhaseScanning, 0, 0)
+	entries := make([]dedupEntry, 0)
+	err := db.store.Scan(func(key string, entry storage.Entry) {
+		// chunks belong to their document and go with it
+		if entry.Parent != "" || len(entry.Vector) == 0 {
+			return
+		}
+		vector := search.Normalize(append([]float32(nil), entry.Vector...))
+		if search.Dot(vector, vector) == 0 {
+			return
+		}
+		entries = append(entries, dedupEntry{
+			key:       key,
+			vector:    vector,
+			timestamp: entry.Timestamp,
+			metadata:  entry.Metadata,
+		})
+	})
+	if err != nil {
+		return DedupReport{}, err
+	}
+	progress(DedupPhaseScanning, len(entries), len(entries))
+
+	// duplicates are exactly what the diversity heuristic prunes apart
+	config := index.DefaultHNSWConfig()
+	config.KeepCloseNeighbors = true
+	hnsw := index.NewHNSW(config)
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return DedupReport{}, err
+		}
+		if err := hnsw.Insert(entry.key, entry.vector); err != nil {
+			return DedupReport{}, fmt.Errorf("could not index %s: %v", entry.key, err)
+		}
+		progress(DedupPhaseIndexing, i+1, len(entries))
+	}
+
+	// on unit vectors ||a - b||^2 = 2 - 2 cos(a, b)
+	radius := math.Sqrt(2 - 2*opts.Threshold)
+
+	clusters := newUnionFind()
+	pairs := make([]DuplicatePair, 0)
+	seen := make(map[[2]string]bool)
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return DedupReport{}, err
+		}
+		matches, err := hnsw.SearchRadius(entry.vector, radius, opts.Ef)
+		if err != nil {
+			return DedupReport{}, err
+		}
+		for _, match := range matches {
+			if match.ID == entry.key {
+				continue
+			}
+			// graph edges are one way after pruning, so a pair may only be
+			// found from one of its ends
+			a, b := entry.key, match.ID
+			if b < a {
+				a, b = b, a
+			}
+			if seen[[2]string{a, b}] {
+				continue
+			}
+			seen[[2]string{a, b}] = true
+			pairs = append(pairs, DuplicatePair{
+				A:          a,
+				B:          b,
+				Similarity: 1 - match.Distance*match.Distance/2,
+			})
+			clusters.union(a, b)
+		}
+		progress(DedupPhaseComparing, i+1, len(entries))
+	}
+
+	report := DedupReport{
+		Scanned:  len(entries),
+		Clusters: buildClusters(entries, pairs, clusters),
+	}
+	if opts.Action == DedupActionReport || opts.Action == "" {
+		return report, nil
+	}
+
+	byKey := make(map[string]dedupEntry, len(entries))
+	for _, entry := range entries {
+		byKey[entry.key] = entry
+	}
+	for i, cluster := range report.Clusters {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if opts.Action == DedupActionMerge {
+			if err := db.mergeMetadata(cluster, byKey); err != nil {
+				return report, err
+			}
+		}
+		for _, key := range cluster.Duplicates {
+			// the entry may have been deleted while the job ran
+			if !db.Exists(key) {
+				continue
+			}
+			if err := db.Delete(key); err != nil {
+				return report, fmt.Errorf("could not delete duplicate %s: %v", key, err)
+			}
+			report.Removed++
+		}
+		progress(DedupPhaseResolving, i+1, len(report.Clusters))
+	}
+
+	return report, nil
+}
+
+// mergeMetadata fills in the kept entry's missing metadata fields from its
+// duplicates, older duplicates first
+func (db *DB) mergeMetadata(cluster DuplicateCluster, byKey map[string]dedupEntry) error {
+	merged := make(map[string]string)
+	for k, v := range byKey[cluster.Keep].metadata {
+		merged[k] = v
+	}
+	changed := false
+	for _, key := range cluster.Duplicates {
+		for k, v := range byKey[key].metadata {
+			if _, exists := merged[k]; !exists {
+				merged[k] = v
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := db.store.SetMetadata(cluster.Keep, merged); err != nil {
+		return fmt.Errorf("could not merge metadata into %s: %v", cluster.Keep, err)
+	}
+	return nil
+}
+
+// buildClusters groups the pairs by connected component. Members are
+// ordered oldest first, and the oldest is kept.
+func buildClusters(entries []dedupEntry, pairs []DuplicatePair, clusters *unionFind) []DuplicateCluster {
+	members := make(map[string][]dedupEntry)
+	for _, entry := range entries {
+		if clusters.has(entry.key) {
+			root := clusters.find(entry.key)
+			members[root] = append(members[root], entry)
+		}
+	}
+	pairsOf := make(map[string][]DuplicatePair)
+	for _, pair := range pairs {
+		root := clusters.find(pair.A)
+		pairsOf[root] = append(pairsOf[root], pair)
+	}
+
+	result := make([]DuplicateCluster, 0, len(members))
+	for root, group := range members {
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].timestamp == group[j].timestamp {
+				return group[i].key < group[j].key
+			}
+			return group[i].timestamp < group[j].timestamp
+		})
+		cluster := DuplicateCluster{
+			Keep:       group[0].key,
+			Duplicates: make([]string, 0, len(group)-1),
+			Pairs:      pairsOf[root],
+		}
+		for _, entry := range group[1:] {
+			cluster.Duplicates = append(cluster.Duplicates, entry.key)
+		}
+		result = append(result, cluster)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Keep < result[j].Keep
+	})
+
+	return result
+}
+
+// unionFind tracks connected components over keys
+type unionFind struct {
+	parent map[string]string
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[string]string)}
+}
+
+func (u *unionFind) has(key string) bool {
+	_, exists := u.parent[key]
+	return exists
+}
+
+func (u *unionFind) find(key string) string {
+	if _, exists := u.parent[key]; !exists {
+		u.parent[key] = key
+	}
+	for u.parent[key] != key {
+		// path halving
+		u.parent[key] = u.parent[u.parent[key]]
+		key = u.parent[key]
+	}
+	return key
+}
+
+func (u *unionFind) union(a string, b string) {
+	rootA, rootB := u.find(a), u.find(b)
+	if rootA != rootB {
+		u.parent[rootB] = rootA
+	}
+}
+
+const (
+	DedupJobRunning   = "running"
+	DedupJobDone      = "done"
+	DedupJobFailed    = "failed"
+	DedupJobCancelled = "cancelled"
+)
+
+// dedupJobRetention is how long a finished job can still be looked up
+// before StartDedup drops it
+var dedupJobRetention = time.Hour
+
+// DedupJob is a Dedup running in the background
+type DedupJob struct {
+	ID string
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	lock     sync.Mutex
+	state    string
+	progress DedupProgress
+	report   DedupReport
+	err      error
+	finished time.Time
+}
+
+// DedupStatus is a snapshot of a DedupJob. Report is set once it is done.
+type DedupStatus struct {
+	ID       string        `json:"id"`
+	State    string        `json:"state"`
+	Progress DedupProgress `json:"progress"`
+	Report   *DedupReport  `json:"report,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// StartDedup runs Dedup in the background. The job can be looked up again
+// by its ID with DedupJob until an hour after it finished.
+func (db *DB) StartDedup(opts DedupOptions) *DedupJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &DedupJob{
+		ID:     uuid.NewString(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		state:  DedupJobRunning,
+	}
+
+	progress := opts.Progress
+	opts.Progress = func(p DedupProgress) {
+		job.lock.Lock()
+		job.progress = p
+		job.lock.Unlock()
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	db.jobsLock.Lock()
+	if db.jobs == nil {
+		db.jobs = make(map[string]*DedupJob)
+	}
+	for id, old := range db.jobs {
+		if old.expired() {
+			delete(db.jobs, id)
+		}
+	}
+	db.jobs[job.ID] = job
+	db.jobsLock.Unlock()
+
+	go func() {
+		defer close(job.done)
+		defer cancel()
+
+		report, err := db.Dedup(ctx, opts)
+
+		job.lock.Lock()
+		defer job.lock.Unlock()
+		job.report = report
+		job.err = err
+		job.finished = time.Now()
+		switch {
+		case err == nil:
+			job.state = DedupJobDone
+		case ctx.Err() != nil:
+			job.state = DedupJobCancelled
+		default:
+			job.state = DedupJobFailed
+		}
+	}()
+
+	return job
+}
+
+// DedupJob finds a job started with StartDedup
+func (db *DB) DedupJob(id string) (*DedupJob, bool) {
+	db.jobsLock.Lock()
+	defer db.jobsLock.Unlock()
+
+	job, exists := db.jobs[id]
+	return job, exists
+}
+
+// expired reports whether the job finished more than dedupJobRetention ago
+func (j *DedupJob) expired() bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.state != DedupJobRunning && time.Since(j.finished) > dedupJobRetention
+}
+
+// Cancel stops the job, Wait returns once it has
+func (j *DedupJob) Cancel() {
+	j.cancel()
+}
+
+// Wait blocks until the job ends and returns its report
+func (j *DedupJob) Wait() (DedupReport, error) {
+	<-j.done
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.report, j.err
+}
+
+func (j *DedupJob) Status() DedupStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	status := DedupStatus{
+		ID:       j.ID,
+		State:    j.state,
+		Progress: j.progress,
+	}
+	if j.state != DedupJobRunning {
+		report := j.report
+		status.Report = &report
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	return status
+}
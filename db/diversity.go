@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
+)
+
+type MMROptions struct {
+	// Lambda trades relevance (1) against novelty (0)
+	Lambda float64
+
+	// Candidates is how many of the top results are re-ranked, the rest
+	// keep their order after them. 0 re-ranks every result.
+	Candidates int
+}
+
+func DefaultMMROptions() MMROptions {
+	return MMROptions{
+		Lambda:     0.5,
+		Candidates: 100,
+	}
+}
+
+// diversify re-orders the top candidates by maximal marginal relevance so
+// near-identical results don't crowd out everything else
+func (db *DB) diversify(results []storage.Result, metric string, opts MMROptions) []storage.Result {
+	n := len(results)
+	if opts.Candidates > 0 && opts.Candidates < n {
+		n = opts.Candidates
+	}
+
+	relevance := make([]float64, n)
+	vectors := make([][]float32, n)
+	for i, result := range results[:n] {
+		relevance[i] = result.Score
+		// l2 distances rank best when lowest
+		if metric == "l2" {
+			relevance[i] = -result.Score
+		}
+		if entry, exists := db.store.Get(result.Key); exists {
+			vectors[i] = entry.Vector
+		}
+	}
+
+	reranked := make([]storage.Result, 0, len(results))
+	for _, i := range search.MMR(relevance, vectors, opts.Lambda, 0) {
+		reranked = append(reranked, results[i])
+	}
+
+	return append(reranked, results[n:]...)
+}
+
+// ResultGroup holds the results sharing one value of a metadata field
+type ResultGroup struct {
+	Value   string
+	Results []storage.Result
+}
+
+// GroupByField groups results by the value of a metadata field, keeping at
+// most perGroup results in each, or all of them when perGroup is 0. Groups
+// keep the rank of their first result, and results without the field share
+// the group with an empty value.
+func GroupByField(results []storage.Result, field string, perGroup int) []ResultGroup {
+	groups := make([]ResultGroup, 0)
+	positions := make(map[string]int)
+
+	for _, result := range results {
+		value := result.Metadata[field]
+		i, seen := positions[value]
+		if !seen {
+			i = len(groups)
+			positions[value] = i
+			groups = append(groups, ResultGroup{Value: value})
+		}
+		if perGroup == 0 || len(groups[i].Results) < perGroup {
+			groups[i].Results = append(groups[i].Results, result)
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/chunk"
+	"github.com/ahhcash/ghastlydb/storage"
+)
+
+// DocumentResult is a document ranked by its best matching chunk
+type DocumentResult struct {
+	Key   string
+	Value string
+	Score float64
+
+	// Chunks are the document's matching chunks, best first
+	Chunks []storage.Result
+}
+
+// PutDocument splits value with the configured chunking strategy and embeds
+// every chunk separately, so long documents aren't truncated by the model's
+// context window. Get returns the whole document, and Delete removes it
+// together with its chunks.
+func (db *DB) PutDocument(key string, value string) error {
+	return db.PutDocumentWithChunking(key, value, db.DBConfig.Chunking, nil)
+}
+
+// PutDocumentWithChunking overrides the configured chunking for one
+// document. Every chunk carries a copy of metadata.
+func (db *DB) PutDocumentWithChunking(key string, value string, cfg chunk.Config, metadata map[string]string) error {
+	chunks, err := chunk.Split(value, cfg)
+	if err != nil {
+		return fmt.Errorf("could not chunk document %s: %v", key, err)
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("document %s is empty", key)
+	}
+
+	return db.store.PutChunks(key, value, chunks, metadata)
+}
+
+// SearchDocuments ranks documents instead of chunks. Each document scores
+// as its best chunk and keeps at most chunksPerDocument of its matching
+// chunks, or all of them when chunksPerDocument is 0. Entries stored whole
+// with Put are returned as documents with a single chunk.
+func (db *DB) SearchDocuments(query string, metric string, chunksPerDocument int) ([]DocumentResult, error) {
+	results, err := db.SearchWithMetric(query, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GroupByDocument(results, chunksPerDocument), nil
+}
+
+// GroupByDocument folds chunk results into their documents, keeping at
+// most chunksPerDocument chunks each, or all of them when it is 0. The
+// order results are ranked in is kept, so a document takes the place and
+// score of its first chunk.
+func (db *DB) GroupByDocument(results []storage.Result, chunksPerDocument int) []DocumentResult {
+	documents := make([]DocumentResult, 0)
+	positions := make(map[string]int)
+	for _, result := range results {
+		key := result.Parent
+		if key == "" {
+			key = result.Key
+		}
+
+		i, seen := positions[key]
+		if !seen {
+			value := result.Value
+			if result.Parent != "" {
+				document, exists := db.store.Get(key)
+				if !exists {
+					continue
+				}
+				value = document.Value
+			}
+
+			i = len(documents)
+			positions[key] = i
+			documents = append(documents, DocumentResult{
+				Key:   key,
+				Value: value,
+				Score: result.Score,
+			})
+		}
+
+		if chunksPerDocument == 0 || len(documents[i].Chunks) < chunksPerDocument {
+			documents[i].Chunks = append(documents[i].Chunks, result)
+		}
+	}
+
+	return documents
+}
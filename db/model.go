@@ -0,0 +1,174 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const modelFile = "model.json"
+
+// modelStamp is persisted next to the SSTables so a directory is only ever
+// reopened with the model that produced its vectors. Format and Precision
+// record how its SSTables are written, directories from before they were
+// stamped hold version 1 entries.
+type modelStamp struct {
+	Model     embed.ModelInfo `json:"model"`
+	CreatedAt int64           `json:"created_at"`
+	Format    int             `json:"format,omitempty"`
+	Precision string          `json:"precision,omitempty"`
+}
+
+func readModelStamp(dir string) (*modelStamp, error) {
+	data, err := os.ReadFile(filepath.Join(dir, modelFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", modelFile, err)
+	}
+
+	var stamp modelStamp
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", modelFile, err)
+	}
+	return &stamp, nil
+}
+
+func writeModelStamp(dir string, stamp modelStamp) error {
+	data, err := json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal %s: %v", modelFile, err)
+	}
+
+	filename := filepath.Join(dir, modelFile)
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, data, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %v", tempFilename, err)
+	}
+	if err := os.Rename(tempFilename, filename); err != nil {
+		return fmt.Errorf("could not rename temp file to %s: %v", modelFile, err)
+	}
+	return nil
+}
+
+// checkModel compares the model a directory was created with against the
+// one it's being opened with. Dimensions are only compared when both sides
+// know them.
+func checkModel(dir string, stored, current embed.ModelInfo) error {
+	dimsDiffer := stored.Dimensions != 0 && current.Dimensions != 0 && stored.Dimensions != current.Dimensions
+	if stored.Name == current.Name && stored.Version == current.Version && !dimsDiffer {
+		return nil
+	}
+
+	return fmt.Errorf("database at %s was created with embedding model %s, refusing to open it with %s",
+		dir, describeModel(stored), describeModel(current))
+}
+
+func describeModel(info embed.ModelInfo) string {
+	s := info.Name
+	if info.Version != "" {
+		s += "@" + info.Version
+	}
+	if info.Dimensions != 0 {
+		s += fmt.Sprintf(" (%d dimensions)", info.Dimensions)
+	}
+	return s
+}
+
+// dimensionGuard rejects vectors whose length doesn't match the one stamped
+// into the database, so a misconfigured model can never mix sizes. When the
+// stamp doesn't know the size yet, the first vector decides and is persisted.
+type dimensionGuard struct {
+	embedder embed.Embedder
+	dir      string
+
+	lock  sync.Mutex
+	stamp modelStamp
+}
+
+func (g *dimensionGuard) check(vector []float64) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.stamp.Model.Dimensions == 0 {
+		if len(vector) == 0 {
+			return nil
+		}
+		g.stamp.Model.Dimensions = len(vector)
+		return writeModelStamp(g.dir, g.stamp)
+	}
+
+	if len(vector) != g.stamp.Model.Dimensions {
+		return fmt.Errorf("embedding model %s returned a %d-dimensional vector, expected %d",
+			g.stamp.Model.Name, len(vector), g.stamp.Model.Dimensions)
+	}
+	return nil
+}
+
+func (g *dimensionGuard) Embed(text string) ([]float64, error) {
+	vector, err := g.embedder.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.check(vector); err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
+func (g *dimensionGuard) EmbedBatch(texts []string) ([][]float64, error) {
+	var vectors [][]float64
+	if batcher, ok := g.embedder.(embed.BatchEmbedder); ok {
+		var err error
+		vectors, err = batcher.EmbedBatch(texts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		vectors = make([][]float64, len(texts))
+		for i, text := range texts {
+			vector, err := g.embedder.Embed(text)
+			if err != nil {
+				return nil, err
+			}
+			vectors[i] = vector
+		}
+	}
+
+	for _, vector := range vectors {
+		if err := g.check(vector); err != nil {
+			return nil, err
+		}
+	}
+	return vectors, nil
+}
+
+func (g *dimensionGuard) EmbedMulti(text string) ([][]float64, error) {
+	multi, ok := g.embedder.(embed.MultiVectorEmbedder)
+	if !ok {
+		return nil, fmt.Errorf("embedding model %s does not produce per-token vectors", g.stamp.Model.Name)
+	}
+
+	vectors, err := multi.EmbedMulti(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, vector := range vectors {
+		if err := g.check(vector); err != nil {
+			return nil, err
+		}
+	}
+	return vectors, nil
+}
+
+func (g *dimensionGuard) ModelInfo() embed.ModelInfo {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.stamp.Model
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
+)
+
+type RadiusOptions struct {
+	// Radius bounds the results in the metric's own units: l2 results are
+	// at most Radius away, cosine and dot results score at least Radius
+	Radius float64
+
+	// Metric overrides the configured metric
+	Metric string
+
+	// Offset and Limit select one page of the results, closest first. A
+	// zero Limit returns everything from Offset on.
+	Offset int
+	Limit  int
+
+	// Exact scans the whole store even when its HNSW index could walk the
+	// radius instead, see storage.Store.SearchRadius
+	Exact bool
+}
+
+// RadiusPage is one page of a radius search
+type RadiusPage struct {
+	Results []storage.Result
+
+	// Total counts every result within the radius, across all pages
+	Total int
+
+	// NextOffset starts the following page, and is 0 after the last one
+	NextOffset int
+}
+
+// SearchRadius returns the entries within a radius of the query instead of
+// the top k. Stores with an HNSW index walk the graph for l2 radii, which
+// can miss entries; every other search, and any with Exact set, scans the
+// whole store and misses nothing.
+func (db *DB) SearchRadius(query string, opts RadiusOptions) (RadiusPage, error) {
+	vector, err := db.model.Embed(query)
+	if err != nil {
+		return RadiusPage{}, fmt.Errorf("could not embed query vector: %v", err)
+	}
+
+	return db.SearchRadiusVector(vector, opts)
+}
+
+// SearchRadiusVector is SearchRadius for an already computed vector, such as
+// one read back from a stored entry
+func (db *DB) SearchRadiusVector(vector []float64, opts RadiusOptions) (RadiusPage, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return RadiusPage{}, fmt.Errorf("offset and limit must not be negative")
+	}
+
+	metric := opts.Metric
+	if metric == "" {
+		metric = db.DBConfig.Metric
+	}
+	if metric == storage.MetricMaxSim {
+		return RadiusPage{}, fmt.Errorf("radius search does not support metric %s", storage.MetricMaxSim)
+	}
+
+	searchRadius := db.store.SearchRadius
+	if opts.Exact {
+		searchRadius = db.store.SearchRadiusExact
+	}
+	results, err := searchRadius(search.ToFloat32(vector), metric, opts.Radius)
+	if err != nil {
+		return RadiusPage{}, err
+	}
+
+	page := RadiusPage{Total: len(results)}
+	if opts.Offset >= len(results) {
+		page.Results = []storage.Result{}
+		return page, nil
+	}
+
+	end := len(results)
+	if opts.Limit > 0 && opts.Offset+opts.Limit < end {
+		end = opts.Offset + opts.Limit
+		page.NextOffset = end
+	}
+	page.Results = results[opts.Offset:end]
+
+	return page, nil
+}
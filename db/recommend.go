@@ -0,0 +1,162 @@
+package db
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
+	"sort"
+)
+
+const (
+	// RecommendAverage searches once with the average positive vector,
+	// pushed away from the average negative one
+	RecommendAverage = "average"
+
+	// RecommendBestScore scores every candidate by its closest positive
+	// example, and leaves out candidates closer to a negative example. It
+	// keeps the variety of the examples but runs one search per example.
+	RecommendBestScore = "best_score"
+)
+
+type RecommendOptions struct {
+	// Positive keys are examples of what to find, at least one is required
+	Positive []string
+
+	// Negative keys are examples of what to avoid
+	Negative []string
+
+	// Strategy is RecommendAverage (the default) or RecommendBestScore
+	Strategy string
+
+	// Metric overrides the configured metric
+	Metric string
+}
+
+// Recommend finds entries similar to stored ones, reusing their stored
+// vectors instead of embedding their text again. Results are scored under
+// metric like a search and ordered closest first, whatever the strategy.
+// The example entries and the chunks of example documents are never part
+// of the results.
+func (db *DB) Recommend(opts RecommendOptions) ([]storage.Result, error) {
+	if len(opts.Positive) == 0 {
+		return nil, fmt.Errorf("recommend needs at least one positive key")
+	}
+
+	metric := opts.Metric
+	if metric == "" {
+		metric = db.DBConfig.Metric
+	}
+	if metric == storage.MetricMaxSim {
+		// entries also keep a pooled vector, which cosine compares best
+		metric = "cosine"
+	}
+
+	positive, err := db.vectors(opts.Positive)
+	if err != nil {
+		return nil, err
+	}
+	negative, err := db.vectors(opts.Negative)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []storage.Result
+	switch opts.Strategy {
+	case RecommendAverage, "":
+		results, err = db.recommendAverage(positive, negative, metric)
+	case RecommendBestScore:
+		results, err = db.recommendBestScore(positive, negative, metric)
+	default:
+		return nil, fmt.Errorf("unknown recommend strategy %s", opts.Strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	examples := make(map[string]bool)
+	for _, key := range append(opts.Positive, opts.Negative...) {
+		examples[key] = true
+	}
+	filtered := make([]storage.Result, 0, len(results))
+	for _, result := range results {
+		if !examples[result.Key] && !examples[result.Parent] {
+			filtered = append(filtered, result)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return search.Closer(metric, filtered[i].Score, filtered[j].Score)
+	})
+
+	return filtered, nil
+}
+
+// vectors reads the stored vectors of keys
+func (db *DB) vectors(keys []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(keys))
+	for _, key := range keys {
+		entry, exists := db.store.Get(key)
+		if !exists {
+			return nil, fmt.Errorf("key %s does not exist", key)
+		}
+		if len(entry.Vector) == 0 {
+			return nil, fmt.Errorf("key %s has no vector of its own, use one of its chunks", key)
+		}
+		vectors = append(vectors, entry.Vector)
+	}
+	return vectors, nil
+}
+
+func (db *DB) recommendAverage(positive, negative [][]float32, metric string) ([]storage.Result, error) {
+	query := search.Mean(positive)
+	if len(negative) > 0 {
+		// move as far past the positive average as the negatives are behind it
+		avoid := search.Mean(negative)
+		for i := range query {
+			query[i] += query[i] - avoid[i]
+		}
+	}
+
+	return db.store.SearchVector(query, metric)
+}
+
+func (db *DB) recommendBestScore(positive, negative [][]float32, metric string) ([]storage.Result, error) {
+	// bestOf keeps, per key, the match closest to any of examples
+	bestOf := func(examples [][]float32) (map[string]storage.Result, error) {
+		best := make(map[string]storage.Result)
+		for _, example := range examples {
+			matches, err := db.store.SearchVector(example, metric)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				if current, seen := best[match.Key]; !seen || search.Closer(metric, match.Score, current.Score) {
+					best[match.Key] = match
+				}
+			}
+		}
+		return best, nil
+	}
+
+	bestPositive, err := bestOf(positive)
+	if err != nil {
+		return nil, err
+	}
+	bestNegative, err := bestOf(negative)
+	if err != nil {
+		return nil, err
+	}
+
+	recommended := make([]storage.Result, 0, len(bestPositive))
+	for key, result := range bestPositive {
+		if avoid, ok := bestNegative[key]; ok && search.Closer(metric, avoid.Score, result.Score) {
+			continue
+		}
+		recommended = append(recommended, result)
+	}
+	// map order would otherwise break ties at random
+	sort.Slice(recommended, func(i, j int) bool {
+		return recommended[i].Key < recommended[j].Key
+	})
+
+	return recommended, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/storage"
+	"sort"
+)
+
+// RerankOptions adjusts the collection's reranker for one query
+type RerankOptions struct {
+	// Disabled skips reranking
+	Disabled bool
+
+	// TopN overrides DBConfig.RerankTopN
+	TopN int
+}
+
+// rerank re-scores the best topN results with the reranker. Results past
+// topN are dropped, since their scores can't be compared with the
+// reranker's.
+func (db *DB) rerank(query string, results []storage.Result, metric string, opts RerankOptions) ([]storage.Result, error) {
+	topN := db.DBConfig.RerankTopN
+	if opts.TopN > 0 {
+		topN = opts.TopN
+	}
+
+	candidates := append([]storage.Result{}, results...)
+	// l2 distances rank best when lowest
+	if metric == "l2" {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score < candidates[j].Score
+		})
+	}
+	if topN > 0 && len(candidates) > topN {
+		candidates = candidates[:topN]
+	}
+
+	documents := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		documents[i] = candidate.Value
+	}
+
+	scores, err := db.reranker.Rerank(query, documents)
+	if err != nil {
+		return nil, fmt.Errorf("could not rerank results: %v", err)
+	}
+	if len(scores) != len(candidates) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d documents", len(scores), len(candidates))
+	}
+
+	for i := range candidates {
+		candidates[i].Score = scores[i]
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	return candidates, nil
+}
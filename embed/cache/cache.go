@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config controls where cached vectors live and how much space they may use.
+// An empty Path disables the cache.
+type Config struct {
+	Path string `json:"path,omitempty"`
+
+	// MaxBytes bounds the total size of cached vectors on disk, zero means unbounded
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+
+	// MaxEntries bounds the number of cached vectors, zero means unbounded
+	MaxEntries int `json:"max_entries,omitempty"`
+}
+
+// Stats is a point-in-time snapshot of cache activity.
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+}
+
+type cacheEntry struct {
+	hash string
+	size int64
+}
+
+// Cache is an on-disk, content-addressed store of embedding vectors.
+// Every vector lives in its own file named after the hash of the model
+// identity and the input text, and least recently used files are removed
+// once the configured limits are exceeded. A Cache is safe for concurrent
+// use and is meant to be shared by every collection using the same model.
+type Cache struct {
+	dir    string
+	config Config
+
+	lock    sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	bytes   int64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+var (
+	openCaches = map[string]*Cache{}
+	openLock   sync.Mutex
+)
+
+// Open returns the cache rooted at cfg.Path, loading any vectors already on
+// disk. Opening the same directory twice in one process returns the same
+// Cache so that hit counts and limits are tracked in one place, which fails
+// if the second Open asks for other limits.
+func Open(cfg Config) (*Cache, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("embedding cache path not set")
+	}
+
+	dir, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve cache path %s: %v", cfg.Path, err)
+	}
+
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	if c, exists := openCaches[dir]; exists {
+		if c.config.MaxBytes != cfg.MaxBytes || c.config.MaxEntries != cfg.MaxEntries {
+			return nil, fmt.Errorf("embedding cache at %s is already open with max_bytes %d and max_entries %d, not %d and %d",
+				dir, c.config.MaxBytes, c.config.MaxEntries, cfg.MaxBytes, cfg.MaxEntries)
+		}
+		return c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory at %s: %v", dir, err)
+	}
+
+	c := &Cache{
+		dir:     dir,
+		config:  cfg,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("could not load embedding cache: %v", err)
+	}
+	openCaches[dir] = c
+
+	return c, nil
+}
+
+// load rebuilds the LRU order from file modification times, which Get
+// refreshes on every hit
+func (c *Cache) load() error {
+	type found struct {
+		hash    string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".vec" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hash := filepath.Base(path)
+		files = append(files, found{
+			hash:    hash[:len(hash)-len(".vec")],
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// most recently used first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	for _, f := range files {
+		c.entries[f.hash] = c.lru.PushBack(&cacheEntry{hash: f.hash, size: f.size})
+		c.bytes += f.size
+	}
+	c.evict()
+
+	return nil
+}
+
+// Key derives the content address for text embedded by a given model.
+// Dimensions is part of the key because the same model can be asked for
+// shortened vectors.
+func Key(model string, dimensions int, text string) string {
+	h := sha256.New()
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(dimensions)))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.dir, hash[:2], hash+".vec")
+}
+
+// Get returns the vector stored under hash, if any.
+func (c *Cache) Get(hash string) ([]float64, bool) {
+	c.lock.Lock()
+	elem, exists := c.entries[hash]
+	if exists {
+		c.lru.MoveToFront(elem)
+	}
+	c.lock.Unlock()
+
+	if !exists {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(hash))
+	if err != nil {
+		c.remove(hash)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	vector, err := decodeVector(data)
+	if err != nil {
+		c.remove(hash)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(c.path(hash), now, now)
+	c.hits.Add(1)
+
+	return vector, true
+}
+
+// Put stores vector under hash, evicting the least recently used vectors
+// if the cache grows past its limits.
+func (c *Cache) Put(hash string, vector []float64) error {
+	data := encodeVector(vector)
+	path := c.path(hash)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create cache shard for %s: %v", hash, err)
+	}
+
+	// a temp file of its own, so concurrent puts of one hash never write
+	// into each other's file
+	file, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create temp file for cached vector %s: %v", hash, err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("could not write cached vector %s: %v", hash, err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("could not rename temp file to cached vector: %v", err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, exists := c.entries[hash]; exists {
+		entry := elem.Value.(*cacheEntry)
+		c.bytes += int64(len(data)) - entry.size
+		entry.size = int64(len(data))
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[hash] = c.lru.PushFront(&cacheEntry{hash: hash, size: int64(len(data))})
+		c.bytes += int64(len(data))
+	}
+	c.evict()
+
+	return nil
+}
+
+func (c *Cache) remove(hash string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, exists := c.entries[hash]; exists {
+		c.removeElement(elem)
+	}
+}
+
+// evict must be called with c.lock held
+func (c *Cache) evict() {
+	for c.lru.Len() > 0 && c.overLimit() {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *Cache) overLimit() bool {
+	if c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes {
+		return true
+	}
+	return c.config.MaxEntries > 0 && c.lru.Len() > c.config.MaxEntries
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.hash)
+	c.bytes -= entry.size
+	_ = os.Remove(c.path(entry.hash))
+}
+
+// Stats reports hit, miss and eviction counts since the cache was opened,
+// along with its current size.
+func (c *Cache) Stats() Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+	}
+}
+
+// vectorFloat32 follows the length of a vector cached as float32s, the
+// precision storage keeps vectors in. Files written before it hold float64s
+// right after the length, which their size tells apart.
+const vectorFloat32 byte = 1
+
+func encodeVector(vector []float64) []byte {
+	buf := make([]byte, 5+4*len(vector))
+	binary.LittleEndian.PutUint32(buf, uint32(len(vector)))
+	buf[4] = vectorFloat32
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[5+i*4:], math.Float32bits(float32(v)))
+	}
+	return buf
+}
+
+func decodeVector(data []byte) ([]float64, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("cached vector too short, got %d bytes", len(data))
+	}
+	vectorLen := int(binary.LittleEndian.Uint32(data))
+
+	vector := make([]float64, vectorLen)
+	switch {
+	case len(data) == 5+4*vectorLen && data[4] == vectorFloat32:
+		for i := range vector {
+			vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[5+i*4:])))
+		}
+	case len(data) == 4+8*vectorLen:
+		for i := range vector {
+			vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[4+i*8:]))
+		}
+	default:
+		return nil, fmt.Errorf("cached vector length mismatch: header says %d dimensions, got %d bytes", vectorLen, len(data))
+	}
+	return vector, nil
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"encoding/binary"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	testPath string
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.testPath = s.T().TempDir()
+}
+
+func (s *CacheTestSuite) TearDownTest() {
+	// forget the process-wide instance so every test starts from disk
+	openLock.Lock()
+	delete(openCaches, s.testPath)
+	openLock.Unlock()
+}
+
+func (s *CacheTestSuite) TestKeyDependsOnModelAndDimensions() {
+	base := Key("text-embedding-3-small", 0, "hello")
+
+	assert.Equal(s.T(), base, Key("text-embedding-3-small", 0, "hello"))
+	assert.NotEqual(s.T(), base, Key("text-embedding-3-large", 0, "hello"))
+	assert.NotEqual(s.T(), base, Key("text-embedding-3-small", 256, "hello"))
+	assert.NotEqual(s.T(), base, Key("text-embedding-3-small", 0, "hello!"))
+}
+
+func (s *CacheTestSuite) TestPutAndGet() {
+	c, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+
+	_, exists := c.Get(Key("m", 0, "a"))
+	assert.False(s.T(), exists)
+
+	require.NoError(s.T(), c.Put(Key("m", 0, "a"), []float64{0.1, 0.2, 0.3}))
+
+	// vectors are cached as float32s
+	vec, exists := c.Get(Key("m", 0, "a"))
+	assert.True(s.T(), exists)
+	assert.InDeltaSlice(s.T(), []float64{0.1, 0.2, 0.3}, vec, 1e-7)
+
+	stats := c.Stats()
+	assert.Equal(s.T(), int64(1), stats.Hits)
+	assert.Equal(s.T(), int64(1), stats.Misses)
+	assert.Equal(s.T(), 1, stats.Entries)
+	assert.Equal(s.T(), int64(4+1+3*4), stats.Bytes)
+}
+
+func (s *CacheTestSuite) TestReadsFloat64Vectors() {
+	// a vector cached before vectors were kept as float32s
+	hash := Key("m", 0, "old")
+	data := binary.LittleEndian.AppendUint32(nil, 2)
+	for _, v := range []float64{0.1, -2} {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	require.NoError(s.T(), os.MkdirAll(filepath.Join(s.testPath, hash[:2]), 0755))
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.testPath, hash[:2], hash+".vec"), data, 0644))
+
+	c, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+	vec, exists := c.Get(hash)
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), []float64{0.1, -2}, vec)
+
+	// and a broken one is dropped
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.testPath, hash[:2], hash+".vec"), data[:10], 0644))
+	_, exists = c.Get(hash)
+	assert.False(s.T(), exists)
+}
+
+func (s *CacheTestSuite) TestSharedPerDirectory() {
+	c1, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+	c2, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+
+	assert.Same(s.T(), c1, c2)
+
+	// the instance is shared, so it can't honour other limits
+	_, err = Open(Config{Path: s.testPath, MaxEntries: 10})
+	assert.Error(s.T(), err)
+	_, err = Open(Config{Path: s.testPath, MaxBytes: 1 << 20})
+	assert.Error(s.T(), err)
+}
+
+func (s *CacheTestSuite) TestConcurrentPutsOfOneHash() {
+	c, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+
+	hash := Key("m", 0, "a")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(s.T(), c.Put(hash, []float64{1, 2, 3}))
+		}()
+	}
+	wg.Wait()
+
+	vec, exists := c.Get(hash)
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), []float64{1, 2, 3}, vec)
+
+	// no temp files are left behind
+	files, err := os.ReadDir(filepath.Join(s.testPath, hash[:2]))
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), files, 1)
+}
+
+func (s *CacheTestSuite) TestPersistsAcrossOpens() {
+	c, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), c.Put(Key("m", 0, "a"), []float64{1, 2}))
+	s.TearDownTest()
+
+	reopened, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+	assert.NotSame(s.T(), c, reopened)
+
+	vec, exists := reopened.Get(Key("m", 0, "a"))
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), []float64{1, 2}, vec)
+}
+
+func (s *CacheTestSuite) TestEvictsLeastRecentlyUsed() {
+	c, err := Open(Config{Path: s.testPath, MaxEntries: 2})
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), c.Put("aa01", []float64{1}))
+	require.NoError(s.T(), c.Put("bb02", []float64{2}))
+
+	// touch the first entry so the second becomes the eviction candidate
+	_, exists := c.Get("aa01")
+	require.True(s.T(), exists)
+
+	require.NoError(s.T(), c.Put("cc03", []float64{3}))
+
+	_, exists = c.Get("bb02")
+	assert.False(s.T(), exists)
+	_, exists = c.Get("aa01")
+	assert.True(s.T(), exists)
+	_, exists = c.Get("cc03")
+	assert.True(s.T(), exists)
+
+	_, err = os.Stat(filepath.Join(s.testPath, "bb", "bb02.vec"))
+	assert.True(s.T(), os.IsNotExist(err))
+	assert.Equal(s.T(), int64(1), c.Stats().Evictions)
+}
+
+func (s *CacheTestSuite) TestMaxBytes() {
+	// each single-dimension vector costs 9 bytes
+	c, err := Open(Config{Path: s.testPath, MaxBytes: 20})
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), c.Put("aa01", []float64{1}))
+	require.NoError(s.T(), c.Put("bb02", []float64{2}))
+	require.NoError(s.T(), c.Put("cc03", []float64{3}))
+
+	stats := c.Stats()
+	assert.Equal(s.T(), 2, stats.Entries)
+	assert.Equal(s.T(), int64(18), stats.Bytes)
+}
+
+func (s *CacheTestSuite) TestCachedEmbedder() {
+	c, err := Open(Config{Path: s.testPath})
+	require.NoError(s.T(), err)
+
+	inner := &mocks.MockEmbedder{}
+	inner.On("Embed", "hello").Return([]float64{0.5, 0.5}, nil)
+	inner.On("Embed", "world").Return([]float64{0.1, 0.9}, nil)
+
+	e := Wrap(inner, c, embed.ModelInfo{Name: "mock"})
+
+	for i := 0; i < 3; i++ {
+		vec, err := e.Embed("hello")
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), []float64{0.5, 0.5}, vec)
+	}
+	inner.AssertNumberOfCalls(s.T(), "Embed", 1)
+
+	vecs, err := e.EmbedBatch([]string{"world", "hello"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), [][]float64{{0.1, 0.9}, {0.5, 0.5}}, vecs)
+	inner.AssertNumberOfCalls(s.T(), "Embed", 2)
+
+	// a different model never sees another model's vectors
+	other := Wrap(inner, c, embed.ModelInfo{Name: "mock", Dimensions: 2})
+	_, err = other.Embed("hello")
+	require.NoError(s.T(), err)
+	inner.AssertNumberOfCalls(s.T(), "Embed", 3)
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+)
+
+// CachedEmbedder answers from a Cache when it can and only calls the
+// wrapped embedder for texts it has not seen before.
+type CachedEmbedder struct {
+	embedder embed.Embedder
+	cache    *Cache
+	model    embed.ModelInfo
+}
+
+// Wrap puts cache in front of embedder. The model identity is part of every
+// cache key, so one cache can safely serve several models.
+func Wrap(embedder embed.Embedder, cache *Cache, model embed.ModelInfo) *CachedEmbedder {
+	return &CachedEmbedder{
+		embedder: embedder,
+		cache:    cache,
+		model:    model,
+	}
+}
+
+func (c *CachedEmbedder) key(text string) string {
+	return Key(c.model.Name+"@"+c.model.Version, c.model.Dimensions, text)
+}
+
+func (c *CachedEmbedder) Embed(text string) ([]float64, error) {
+	hash := c.key(text)
+	if vector, exists := c.cache.Get(hash); exists {
+		return vector, nil
+	}
+
+	vector, err := c.embedder.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.cache.Put(hash, vector); err != nil {
+		return nil, fmt.Errorf("could not cache embedding: %v", err)
+	}
+	return vector, nil
+}
+
+// EmbedBatch looks every text up in the cache and embeds the misses in a
+// single batch when the wrapped embedder supports it.
+func (c *CachedEmbedder) EmbedBatch(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	hashes := make([]string, len(texts))
+	var missing []int
+
+	for i, text := range texts {
+		hashes[i] = c.key(text)
+		if vector, exists := c.cache.Get(hashes[i]); exists {
+			vectors[i] = vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	var embedded [][]float64
+	if batcher, ok := c.embedder.(embed.BatchEmbedder); ok {
+		inputs := make([]string, len(missing))
+		for j, i := range missing {
+			inputs[j] = texts[i]
+		}
+		var err error
+		embedded, err = batcher.EmbedBatch(inputs)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		embedded = make([][]float64, len(missing))
+		for j, i := range missing {
+			vector, err := c.embedder.Embed(texts[i])
+			if err != nil {
+				return nil, err
+			}
+			embedded[j] = vector
+		}
+	}
+
+	for j, i := range missing {
+		vectors[i] = embedded[j]
+		if err := c.cache.Put(hashes[i], embedded[j]); err != nil {
+			return nil, fmt.Errorf("could not cache embedding: %v", err)
+		}
+	}
+
+	return vectors, nil
+}
+
+// EmbedMulti passes through to the wrapped embedder. Per-token vectors are
+// not cached.
+func (c *CachedEmbedder) EmbedMulti(text string) ([][]float64, error) {
+	multi, ok := c.embedder.(embed.MultiVectorEmbedder)
+	if !ok {
+		return nil, fmt.Errorf("embedding model does not produce per-token vectors")
+	}
+	return multi.EmbedMulti(text)
+}
+
+func (c *CachedEmbedder) ModelInfo() embed.ModelInfo {
+	return c.model
+}
+
+// Stats returns the statistics of the underlying cache, which may be shared
+// with other embedders.
+func (c *CachedEmbedder) Stats() Stats {
+	return c.cache.Stats()
+}
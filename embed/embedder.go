@@ -3,3 +3,34 @@ package embed
 type Embedder interface {
 	Embed(text string) ([]float64, error)
 }
+
+// BatchEmbedder is implemented by embedders that can embed several texts in a
+// single round trip. Vectors are returned in the same order as the input.
+type BatchEmbedder interface {
+	Embedder
+	EmbedBatch(texts []string) ([][]float64, error)
+}
+
+// MultiVectorEmbedder is implemented by late-interaction models such as
+// ColBERT, which represent a text as one vector per token rather than a
+// single pooled vector.
+type MultiVectorEmbedder interface {
+	Embedder
+	EmbedMulti(text string) ([][]float64, error)
+}
+
+// ModelInfo identifies the model behind an embedder so that vectors produced
+// by different models, or by the same model truncated to a different size,
+// are never mixed up.
+type ModelInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+
+	// Dimensions is zero when the size is only known after the first Embed call
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+// Describer is implemented by embedders that can report which model they run.
+type Describer interface {
+	ModelInfo() ModelInfo
+}
@@ -0,0 +1,127 @@
+package hash
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/search"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Config controls the feature hashing embedder.
+type Config struct {
+	// Dimensions is the size of the output vectors
+	Dimensions int `json:"dimensions"`
+
+	// NGrams lists the character n-gram sizes taken from every word
+	NGrams []int `json:"ngrams"`
+
+	// Words adds whole words as features next to their n-grams
+	Words bool `json:"words"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Dimensions: 384,
+		NGrams:     []int{3, 4},
+		Words:      true,
+	}
+}
+
+func init() {
+	embed.Register("hash", DefaultConfig, func(cfg Config) (embed.Embedder, error) {
+		return NewHashEmbedder(cfg)
+	})
+}
+
+// HashEmbedder turns text into vectors by feature hashing: lower-cased
+// words and their character n-grams are hashed into a fixed number of
+// buckets with a random sign, and the result is normalized. It needs no
+// model, network or native libraries, always produces the same vector for
+// the same text, and texts sharing words or word fragments end up close
+// together, which is enough for tests, demos and air-gapped development.
+type HashEmbedder struct {
+	config Config
+}
+
+func NewHashEmbedder(cfg Config) (*HashEmbedder, error) {
+	if cfg.Dimensions <= 0 {
+		return nil, fmt.Errorf("hash embedder needs a positive number of dimensions, got %d", cfg.Dimensions)
+	}
+	for _, n := range cfg.NGrams {
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid n-gram size %d", n)
+		}
+	}
+	if len(cfg.NGrams) == 0 && !cfg.Words {
+		return nil, fmt.Errorf("hash embedder needs n-grams, words or both as features")
+	}
+
+	return &HashEmbedder{config: cfg}, nil
+}
+
+func (h *HashEmbedder) Embed(text string) ([]float64, error) {
+	vector := make([]float64, h.config.Dimensions)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		if h.config.Words {
+			h.add(vector, "w:"+word)
+		}
+
+		// pad so prefixes and suffixes get their own n-grams
+		runes := []rune(" " + word + " ")
+		for _, n := range h.config.NGrams {
+			for i := 0; i+n <= len(runes); i++ {
+				h.add(vector, "c:"+string(runes[i:i+n]))
+			}
+		}
+	}
+
+	return search.Normalize(vector), nil
+}
+
+func (h *HashEmbedder) EmbedBatch(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = h.Embed(text)
+	}
+	return vectors, nil
+}
+
+// add hashes a feature into a bucket, using one bit of the hash as the sign
+// so that collisions cancel out on average instead of piling up
+func (h *HashEmbedder) add(vector []float64, feature string) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+
+	bucket := (sum >> 1) % uint64(len(vector))
+	if sum&1 == 0 {
+		vector[bucket]++
+	} else {
+		vector[bucket]--
+	}
+}
+
+func (h *HashEmbedder) ModelInfo() embed.ModelInfo {
+	ngrams := make([]string, len(h.config.NGrams))
+	for i, n := range h.config.NGrams {
+		ngrams[i] = strconv.Itoa(n)
+	}
+	version := "n" + strings.Join(ngrams, ",")
+	if h.config.Words {
+		version += "+w"
+	}
+
+	return embed.ModelInfo{
+		Name:       "hash",
+		Version:    version,
+		Dimensions: h.config.Dimensions,
+	}
+}
@@ -0,0 +1,79 @@
+package hash
+
+import (
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type HashEmbedderTestSuite struct {
+	suite.Suite
+	embedder *HashEmbedder
+}
+
+func (s *HashEmbedderTestSuite) SetupTest() {
+	var err error
+	s.embedder, err = NewHashEmbedder(DefaultConfig())
+	require.NoError(s.T(), err)
+}
+
+func (s *HashEmbedderTestSuite) embed(text string) []float64 {
+	vec, err := s.embedder.Embed(text)
+	require.NoError(s.T(), err)
+	return vec
+}
+
+func (s *HashEmbedderTestSuite) TestDeterministic() {
+	first := s.embed("The quick brown fox")
+	second := s.embed("The quick brown fox")
+
+	assert.Equal(s.T(), first, second)
+	assert.Len(s.T(), first, 384)
+	assert.InDelta(s.T(), 1.0, search.Dot(first, first), 0.000001)
+}
+
+func (s *HashEmbedderTestSuite) TestCaseAndPunctuationInsensitive() {
+	assert.Equal(s.T(), s.embed("hello world"), s.embed("Hello, WORLD!"))
+}
+
+func (s *HashEmbedderTestSuite) TestSimilarTextsAreCloser() {
+	query := s.embed("database connection timeout")
+	related := s.embed("the database connection timed out")
+	unrelated := s.embed("chocolate cake recipe with berries")
+
+	assert.Greater(s.T(), search.Cosine(query, related), search.Cosine(query, unrelated))
+
+	// shared word fragments still count, e.g. for typos
+	typo := s.embed("databse conection")
+	assert.Greater(s.T(), search.Cosine(query, typo), search.Cosine(query, unrelated))
+}
+
+func (s *HashEmbedderTestSuite) TestEmptyText() {
+	vec := s.embed("")
+	assert.Len(s.T(), vec, 384)
+	assert.Equal(s.T(), 0.0, search.Dot(vec, vec))
+}
+
+func (s *HashEmbedderTestSuite) TestInvalidConfig() {
+	_, err := NewHashEmbedder(Config{Dimensions: 0, NGrams: []int{3}})
+	assert.Error(s.T(), err)
+
+	_, err = NewHashEmbedder(Config{Dimensions: 16, NGrams: []int{0}})
+	assert.Error(s.T(), err)
+
+	_, err = NewHashEmbedder(Config{Dimensions: 16})
+	assert.Error(s.T(), err)
+}
+
+func (s *HashEmbedderTestSuite) TestModelInfo() {
+	info := s.embedder.ModelInfo()
+	assert.Equal(s.T(), "hash", info.Name)
+	assert.Equal(s.T(), "n3,4+w", info.Version)
+	assert.Equal(s.T(), 384, info.Dimensions)
+}
+
+func TestHashEmbedderSuite(t *testing.T) {
+	suite.Run(t, new(HashEmbedderTestSuite))
+}
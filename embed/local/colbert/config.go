@@ -1,11 +1,16 @@
 package colbert
 
+import "github.com/ahhcash/ghastlydb/embed/local/onnx"
+
 const (
 	modelHfPath = "colbert-ir/colbertv2.0"
 )
 
-type platformConfig interface {
-	OnnxPath() string
+// DefaultConfig is the generic local model configuration preset for
+// ColBERTv2. Model, cache directory, offline mode and the ONNX runtime
+// path can all be overridden.
+func DefaultConfig() onnx.Config {
+	cfg := onnx.DefaultConfig()
+	cfg.Model = modelHfPath
+	return cfg
 }
-
-var getConfig func() platformConfig
@@ -1,58 +1,38 @@
 package colbert
 
 import (
-	"github.com/knights-analytics/hugot"
-	"github.com/knights-analytics/hugot/options"
-	"github.com/knights-analytics/hugot/pipelines"
-	"os"
-	"path/filepath"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/embed/local/onnx"
 )
 
+func init() {
+	embed.Register("colbert", DefaultConfig, func(cfg onnx.Config) (embed.Embedder, error) {
+		return NewColBERTEmbedderWithConfig(cfg)
+	})
+}
+
+// ColBERTEmbedder is a local ONNX embedder preset for ColBERT. Its per-token
+// vectors (EmbedMulti) are what late-interaction search scores against,
+// Embed returns their normalized mean.
 type ColBERTEmbedder struct {
-	pipeline *pipelines.FeatureExtractionPipeline
+	*onnx.LocalEmbedder
 }
 
 func NewColBERTEmbedder() (*ColBERTEmbedder, error) {
-	osConfig := getConfig()
-	onnxPath := osConfig.OnnxPath()
-	session, err := hugot.NewORTSession(
-		options.WithOnnxLibraryPath(onnxPath))
-	if err != nil {
-		return nil, err
-	}
-
-	pwd, _ := os.Getwd()
-	dest := filepath.Join(pwd, "models")
+	return NewColBERTEmbedderWithConfig(DefaultConfig())
+}
 
-	modelPath, err := hugot.DownloadModel(modelHfPath, dest, hugot.NewDownloadOptions())
-	if err != nil {
-		return nil, err
-	}
-	pipelineConfig := hugot.FeatureExtractionConfig{
-		ModelPath: modelPath,
-		Name:      "EmbeddingPipeline",
+func NewColBERTEmbedderWithConfig(cfg onnx.Config) (*ColBERTEmbedder, error) {
+	if cfg.Model == "" {
+		cfg.Model = modelHfPath
 	}
 
-	embeddingPipeline, err := hugot.NewPipeline(session, pipelineConfig)
-	embeddingPipeline.Normalization = true
+	local, err := onnx.NewLocalEmbedder(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ColBERTEmbedder{
-		pipeline: embeddingPipeline,
+		LocalEmbedder: local,
 	}, nil
 }
-
-func (c *ColBERTEmbedder) Embed(text string) ([]float64, error) {
-	batch := []string{text}
-	result, err := c.pipeline.RunPipeline(batch)
-	if err != nil {
-		return nil, err
-	}
-	embeddings := make([]float64, len(result.Embeddings[0]))
-	for i, v := range result.Embeddings[0] {
-		embeddings[i] = float64(v)
-	}
-	return embeddings, nil
-}
@@ -0,0 +1,84 @@
+package onnx
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	PoolingMean = "mean"
+	PoolingCLS  = "cls"
+	PoolingMax  = "max"
+)
+
+type platformConfig interface {
+	OnnxPath() string
+}
+
+var getConfig func() platformConfig
+
+// Config describes a local ONNX embedding model run through hugot.
+type Config struct {
+	// Model is either a directory holding an ONNX model and its
+	// tokenizer.json, or a Hugging Face model id such as
+	// "sentence-transformers/all-MiniLM-L6-v2"
+	Model string `json:"model"`
+
+	// CacheDir is where Hugging Face models are downloaded to and looked up
+	// in. Defaults to ghastlydb/models under the user cache directory.
+	CacheDir string `json:"cache_dir,omitempty"`
+
+	// Offline never touches the network: Model must be a directory or
+	// already be present in CacheDir
+	Offline bool `json:"offline,omitempty"`
+
+	// OnnxFilename picks the model file when a repository ships several
+	OnnxFilename string `json:"onnx_filename,omitempty"`
+
+	// OutputName picks the model output holding the embeddings when there
+	// are several
+	OutputName string `json:"output_name,omitempty"`
+
+	// Pooling reduces token embeddings to one vector: "mean", "cls" or
+	// "max". Models that already output a pooled vector ignore it.
+	Pooling string `json:"pooling,omitempty"`
+
+	// Normalize scales every vector to unit length
+	Normalize bool `json:"normalize"`
+
+	// OnnxLibraryPath points at the ONNX runtime shared library. Defaults
+	// to the usual install location for the current OS.
+	OnnxLibraryPath string `json:"onnx_library_path,omitempty"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Model:           "sentence-transformers/all-MiniLM-L6-v2",
+		CacheDir:        defaultCacheDir(),
+		Pooling:         PoolingMean,
+		Normalize:       true,
+		OnnxLibraryPath: getConfig().OnnxPath(),
+	}
+}
+
+func (c Config) withDefaults() Config {
+	if c.CacheDir == "" {
+		c.CacheDir = defaultCacheDir()
+	}
+	if c.Pooling == "" {
+		c.Pooling = PoolingMean
+	}
+	if c.OnnxLibraryPath == "" {
+		c.OnnxLibraryPath = getConfig().OnnxPath()
+	}
+	return c
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		pwd, _ := os.Getwd()
+		return filepath.Join(pwd, "models")
+	}
+	return filepath.Join(dir, "ghastlydb", "models")
+}
@@ -1,6 +1,6 @@
 //go:build darwin
 
-package colbert
+package onnx
 
 func getPlatformOnnxPath() string {
 	return "/opt/homebrew/lib/libonnxruntime.1.20.1.dylib"
@@ -0,0 +1,245 @@
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/knights-analytics/hugot"
+	"github.com/knights-analytics/hugot/options"
+	"github.com/knights-analytics/hugot/pipelineBackends"
+	"github.com/knights-analytics/hugot/pipelines"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	embed.Register("local", DefaultConfig, func(cfg Config) (embed.Embedder, error) {
+		return NewLocalEmbedder(cfg)
+	})
+}
+
+// LocalEmbedder runs an ONNX embedding model in-process. It exposes both
+// pooled vectors and the raw per-token vectors, so the same model can back
+// dense search and late interaction.
+type LocalEmbedder struct {
+	pipeline *pipelines.FeatureExtractionPipeline
+	config   Config
+}
+
+func NewLocalEmbedder(cfg Config) (*LocalEmbedder, error) {
+	cfg = cfg.withDefaults()
+
+	switch cfg.Pooling {
+	case PoolingMean, PoolingCLS, PoolingMax:
+	default:
+		return nil, fmt.Errorf("unknown pooling %s, expected %s, %s or %s", cfg.Pooling, PoolingMean, PoolingCLS, PoolingMax)
+	}
+
+	modelPath, err := resolveModel(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := hugot.NewORTSession(
+		options.WithOnnxLibraryPath(cfg.OnnxLibraryPath))
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineConfig := hugot.FeatureExtractionConfig{
+		ModelPath:    modelPath,
+		Name:         "EmbeddingPipeline",
+		OnnxFilename: cfg.OnnxFilename,
+	}
+	if cfg.OutputName != "" {
+		pipelineConfig.Options = append(pipelineConfig.Options, pipelines.WithOutputName(cfg.OutputName))
+	}
+
+	embeddingPipeline, err := hugot.NewPipeline(session, pipelineConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalEmbedder{
+		pipeline: embeddingPipeline,
+		config:   cfg,
+	}, nil
+}
+
+// ModelPath finds the directory of cfg.Model the way NewLocalEmbedder
+// does, for other local models such as cross-encoders
+func ModelPath(cfg Config) (string, error) {
+	return resolveModel(cfg.withDefaults())
+}
+
+// resolveModel finds the model directory, downloading it only when it's
+// neither a local path nor already in the cache
+func resolveModel(cfg Config) (string, error) {
+	if cfg.Model == "" {
+		return "", fmt.Errorf("no local model configured")
+	}
+
+	if info, err := os.Stat(cfg.Model); err == nil && info.IsDir() {
+		return cfg.Model, nil
+	}
+
+	// hugot stores org/name under org_name
+	cached := filepath.Join(cfg.CacheDir, strings.ReplaceAll(strings.Split(cfg.Model, ":")[0], "/", "_"))
+	if info, err := os.Stat(cached); err == nil && info.IsDir() {
+		return cached, nil
+	}
+
+	if cfg.Offline {
+		return "", fmt.Errorf("model %s is not a directory and was not found in %s, and offline mode forbids downloading it", cfg.Model, cfg.CacheDir)
+	}
+
+	if err := os.MkdirAll(cfg.CacheDir, 0755); err != nil {
+		return "", fmt.Errorf("could not create model cache directory at %s: %v", cfg.CacheDir, err)
+	}
+
+	downloadOptions := hugot.NewDownloadOptions()
+	if token, exists := os.LookupEnv("HF_TOKEN"); exists {
+		downloadOptions.AuthToken = token
+	}
+
+	return hugot.DownloadModel(cfg.Model, cfg.CacheDir, downloadOptions)
+}
+
+// ModelInfo reports non-default pooling as the version, since it changes
+// the vectors the same model produces
+func (l *LocalEmbedder) ModelInfo() embed.ModelInfo {
+	version := ""
+	if l.config.Pooling != PoolingMean || !l.config.Normalize {
+		version = l.config.Pooling
+		if l.config.Normalize {
+			version += "+norm"
+		}
+	}
+
+	return embed.ModelInfo{
+		Name:    l.config.Model,
+		Version: version,
+	}
+}
+
+// tokenVectors runs the model and returns, per input, the vectors of every
+// non-padding token. Models whose output is already pooled yield a single
+// vector per input.
+func (l *LocalEmbedder) tokenVectors(texts []string) ([][][]float64, error) {
+	batch := pipelineBackends.NewBatch()
+	defer func(batch *pipelineBackends.PipelineBatch) {
+		_ = batch.Destroy()
+	}(batch)
+
+	if err := l.pipeline.Preprocess(batch, texts); err != nil {
+		return nil, err
+	}
+	if err := l.pipeline.Forward(batch); err != nil {
+		return nil, err
+	}
+
+	outputDimensions := l.pipeline.Output.Dimensions
+	dims := int(outputDimensions[len(outputDimensions)-1])
+	output := batch.OutputValues[0]
+
+	// a 2d output is one pooled vector per input
+	tokensPerInput := 1
+	if len(outputDimensions) > 2 {
+		tokensPerInput = batch.MaxSequenceLength
+	}
+
+	if len(output) != len(texts)*tokensPerInput*dims {
+		return nil, fmt.Errorf("unexpected model output size %d for %d inputs of %d tokens and %d dimensions",
+			len(output), len(texts), tokensPerInput, dims)
+	}
+
+	results := make([][][]float64, len(texts))
+	for i := range texts {
+		mask := batch.Input[i].AttentionMask
+		for token := 0; token < tokensPerInput; token++ {
+			if tokensPerInput > 1 && (token >= len(mask) || mask[token] == 0) {
+				continue
+			}
+
+			start := (i*tokensPerInput + token) * dims
+			vector := make([]float64, dims)
+			for j, v := range output[start : start+dims] {
+				vector[j] = float64(v)
+			}
+			results[i] = append(results[i], vector)
+		}
+	}
+
+	return results, nil
+}
+
+func (l *LocalEmbedder) EmbedBatch(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+
+	tokens, err := l.tokenVectors(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, t := range tokens {
+		if len(t) == 0 {
+			return nil, errors.New("model produced no token embeddings")
+		}
+		vectors[i] = pool(t, l.config.Pooling)
+		if l.config.Normalize {
+			search.Normalize(vectors[i])
+		}
+	}
+
+	return vectors, nil
+}
+
+func (l *LocalEmbedder) Embed(text string) ([]float64, error) {
+	vectors, err := l.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedMulti returns one vector per non-padding token for late interaction.
+// Token vectors are always normalized, as MaxSim expects.
+func (l *LocalEmbedder) EmbedMulti(text string) ([][]float64, error) {
+	if len(l.pipeline.Output.Dimensions) <= 2 {
+		return nil, fmt.Errorf("model output %v is already pooled, no token vectors available", l.pipeline.Output.Dimensions)
+	}
+
+	tokens, err := l.tokenVectors([]string{text})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vector := range tokens[0] {
+		search.Normalize(vector)
+	}
+	return tokens[0], nil
+}
+
+func pool(tokens [][]float64, pooling string) []float64 {
+	switch pooling {
+	case PoolingCLS:
+		return append([]float64(nil), tokens[0]...)
+	case PoolingMax:
+		pooled := append([]float64(nil), tokens[0]...)
+		for _, token := range tokens[1:] {
+			for i, v := range token {
+				if v > pooled[i] {
+					pooled[i] = v
+				}
+			}
+		}
+		return pooled
+	default:
+		return search.Mean(tokens)
+	}
+}
@@ -0,0 +1,65 @@
+package onnx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type LocalEmbedderTestSuite struct {
+	suite.Suite
+	cacheDir string
+}
+
+func (s *LocalEmbedderTestSuite) SetupTest() {
+	s.cacheDir = s.T().TempDir()
+}
+
+func (s *LocalEmbedderTestSuite) TestResolveLocalDirectory() {
+	modelDir := filepath.Join(s.T().TempDir(), "my-model")
+	require.NoError(s.T(), os.MkdirAll(modelDir, 0755))
+
+	path, err := resolveModel(Config{Model: modelDir, CacheDir: s.cacheDir, Offline: true})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), modelDir, path)
+}
+
+func (s *LocalEmbedderTestSuite) TestResolveFromCache() {
+	cached := filepath.Join(s.cacheDir, "sentence-transformers_all-MiniLM-L6-v2")
+	require.NoError(s.T(), os.MkdirAll(cached, 0755))
+
+	path, err := resolveModel(Config{Model: "sentence-transformers/all-MiniLM-L6-v2", CacheDir: s.cacheDir, Offline: true})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), cached, path)
+}
+
+func (s *LocalEmbedderTestSuite) TestOfflineMissingModel() {
+	_, err := resolveModel(Config{Model: "org/not-downloaded", CacheDir: s.cacheDir, Offline: true})
+	require.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "offline")
+}
+
+func (s *LocalEmbedderTestSuite) TestUnknownPooling() {
+	_, err := NewLocalEmbedder(Config{Model: s.cacheDir, Pooling: "median"})
+	assert.Error(s.T(), err)
+}
+
+func (s *LocalEmbedderTestSuite) TestPool() {
+	tokens := [][]float64{{1, 4}, {3, 2}}
+
+	assert.Equal(s.T(), []float64{2, 3}, pool(tokens, PoolingMean))
+	assert.Equal(s.T(), []float64{1, 4}, pool(tokens, PoolingCLS))
+	assert.Equal(s.T(), []float64{3, 4}, pool(tokens, PoolingMax))
+
+	// pooling never aliases the token vectors
+	pooled := pool(tokens, PoolingCLS)
+	pooled[0] = 100
+	assert.Equal(s.T(), 1.0, tokens[0][0])
+}
+
+func TestLocalEmbedderSuite(t *testing.T) {
+	suite.Run(t, new(LocalEmbedderTestSuite))
+}
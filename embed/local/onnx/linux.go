@@ -1,6 +1,6 @@
 //go:build linux
 
-package colbert
+package onnx
 
 func getPlatformOnnxPath() string {
 	return "/usr/local/lib/onnxruntime.so"
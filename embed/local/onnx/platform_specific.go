@@ -1,4 +1,4 @@
-package colbert
+package onnx
 
 type osConfig struct {
 	onnxPath string
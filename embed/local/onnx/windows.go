@@ -1,6 +1,6 @@
 //go:build windows
 
-package colbert
+package onnx
 
 func getPlatformOnnxPath() string {
 	return "C:\\Program Files\\onnxruntime\\bin\\onnxruntime.dll"
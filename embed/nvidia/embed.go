@@ -3,6 +3,7 @@ package nvidia
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
 	"github.com/valyala/fasthttp"
 	"os"
 )
@@ -12,6 +13,12 @@ const (
 	apiBaseUrl = "https://integrate.api.nvidia.com"
 )
 
+func init() {
+	embed.Register("nvidia", func() struct{} { return struct{}{} }, func(struct{}) (embed.Embedder, error) {
+		return LoadNvidiaEmbedder()
+	})
+}
+
 type NvidiaEmbedder struct {
 	apiBaseUrl string
 	apiKey     string
@@ -79,6 +86,10 @@ func marshalRequest(input string) ([]byte, error) {
 	return jsonBody, nil
 }
 
+func (nv *NvidiaEmbedder) ModelInfo() embed.ModelInfo {
+	return embed.ModelInfo{Name: model}
+}
+
 func (nv *NvidiaEmbedder) Embed(text string) ([]float64, error) {
 	nvResp, err := nv.GetEmbeddings(text)
 	if err != nil {
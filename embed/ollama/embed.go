@@ -0,0 +1,166 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/valyala/fasthttp"
+	"os"
+	"strings"
+)
+
+const (
+	defaultModel   = "nomic-embed-text"
+	defaultBaseUrl = "http://localhost:11434"
+)
+
+// Config describes how to reach an Ollama server. The zero value uses
+// nomic-embed-text on $OLLAMA_HOST, falling back to localhost:11434.
+type Config struct {
+	// BaseURL is the server root, e.g. http://localhost:11434
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Model is the name of a pulled embedding model
+	Model string `json:"model,omitempty"`
+
+	// KeepAlive controls how long the model stays loaded after a request,
+	// as an Ollama duration ("5m", "1h", "-1" to keep it forever)
+	KeepAlive string `json:"keep_alive,omitempty"`
+
+	// Truncate cuts inputs that exceed the model's context window instead
+	// of failing the request. Nil leaves the server default (true).
+	Truncate *bool `json:"truncate,omitempty"`
+}
+
+// DefaultConfig leaves BaseURL empty, so $OLLAMA_HOST is looked up when
+// the embedder is built
+func DefaultConfig() Config {
+	return Config{
+		Model: defaultModel,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	if c.BaseURL == "" {
+		c.BaseURL = hostFromEnv()
+	}
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+	return c
+}
+
+// hostFromEnv mirrors the ollama CLI, which reads OLLAMA_HOST and accepts
+// it with or without a scheme
+func hostFromEnv() string {
+	host, exists := os.LookupEnv("OLLAMA_HOST")
+	if !exists || host == "" {
+		return defaultBaseUrl
+	}
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "http://" + host
+	}
+	return host
+}
+
+func init() {
+	embed.Register("ollama", DefaultConfig, func(cfg Config) (embed.Embedder, error) {
+		return NewOllamaEmbedder(cfg)
+	})
+}
+
+type OllamaEmbedder struct {
+	apiBaseUrl string
+	config     Config
+}
+
+func NewOllamaEmbedder(cfg Config) (*OllamaEmbedder, error) {
+	cfg = cfg.withDefaults()
+
+	return &OllamaEmbedder{
+		apiBaseUrl: strings.TrimSuffix(cfg.BaseURL, "/") + "/api/embed",
+		config:     cfg,
+	}, nil
+}
+
+func (o *OllamaEmbedder) GetEmbeddings(inputs []string) (*OllamaEmbedResponse, error) {
+	jsonBody, err := o.marshalRequest(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(o.apiBaseUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonBody)
+
+	if err := fasthttp.Do(req, resp); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		var errResp OllamaErrorResponse
+		if json.Unmarshal(resp.Body(), &errResp) == nil && errResp.Error != "" {
+			return nil, fmt.Errorf("ollama request failed with status code %d: %s", resp.StatusCode(), errResp.Error)
+		}
+		return nil, fmt.Errorf("ollama request failed with status code %d: %s", resp.StatusCode(), resp.Body())
+	}
+
+	var embedResponse OllamaEmbedResponse
+	err = json.Unmarshal(resp.Body(), &embedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &embedResponse, nil
+}
+
+func (o *OllamaEmbedder) marshalRequest(inputs []string) ([]byte, error) {
+	requestBody := OllamaEmbedRequest{
+		Model:     o.config.Model,
+		Input:     inputs,
+		Truncate:  o.config.Truncate,
+		KeepAlive: o.config.KeepAlive,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return jsonBody, nil
+}
+
+func (o *OllamaEmbedder) EmbedBatch(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+
+	response, err := o.GetEmbeddings(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings in response, got %d", len(texts), len(response.Embeddings))
+	}
+
+	return response.Embeddings, nil
+}
+
+func (o *OllamaEmbedder) ModelInfo() embed.ModelInfo {
+	return embed.ModelInfo{Name: o.config.Model}
+}
+
+func (o *OllamaEmbedder) Embed(text string) ([]float64, error) {
+	embeddings, err := o.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+
+	return embeddings[0], nil
+}
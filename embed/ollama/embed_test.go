@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"encoding/json"
+	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type OllamaEmbedderTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	path     string
+	lastBody OllamaEmbedRequest
+	rawBody  map[string]interface{}
+}
+
+func (s *OllamaEmbedderTestSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.path = r.URL.Path
+		s.rawBody = map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&s.rawBody)
+		raw, _ := json.Marshal(s.rawBody)
+		_ = json.Unmarshal(raw, &s.lastBody)
+
+		if s.lastBody.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}`))
+			return
+		}
+
+		// one fake vector per input, derived from the input length so order can be checked
+		resp := OllamaEmbedResponse{Model: s.lastBody.Model}
+		for _, in := range s.lastBody.Input {
+			resp.Embeddings = append(resp.Embeddings, []float64{float64(len(in)), 1})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func (s *OllamaEmbedderTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *OllamaEmbedderTestSuite) TestEmbed() {
+	e, err := NewOllamaEmbedder(Config{BaseURL: s.server.URL})
+	require.NoError(s.T(), err)
+
+	vec, err := e.Embed("hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{5, 1}, vec)
+	assert.Equal(s.T(), "/api/embed", s.path)
+	assert.Equal(s.T(), defaultModel, s.lastBody.Model)
+
+	// unset options are left to the server
+	assert.NotContains(s.T(), s.rawBody, "truncate")
+	assert.NotContains(s.T(), s.rawBody, "keep_alive")
+}
+
+func (s *OllamaEmbedderTestSuite) TestEmbedBatch() {
+	truncate := false
+	e, err := NewOllamaEmbedder(Config{
+		BaseURL:   s.server.URL + "/",
+		Model:     "mxbai-embed-large",
+		KeepAlive: "10m",
+		Truncate:  &truncate,
+	})
+	require.NoError(s.T(), err)
+
+	vecs, err := e.EmbedBatch([]string{"a", "abc", "ab"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), [][]float64{{1, 1}, {3, 1}, {2, 1}}, vecs)
+
+	assert.Equal(s.T(), []string{"a", "abc", "ab"}, s.lastBody.Input)
+	assert.Equal(s.T(), "mxbai-embed-large", s.lastBody.Model)
+	assert.Equal(s.T(), "10m", s.lastBody.KeepAlive)
+	require.NotNil(s.T(), s.lastBody.Truncate)
+	assert.False(s.T(), *s.lastBody.Truncate)
+}
+
+func (s *OllamaEmbedderTestSuite) TestServerError() {
+	e, err := NewOllamaEmbedder(Config{BaseURL: s.server.URL, Model: "missing"})
+	require.NoError(s.T(), err)
+
+	_, err = e.Embed("hello")
+	require.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "try pulling it first")
+}
+
+func (s *OllamaEmbedderTestSuite) TestHostFromEnv() {
+	_ = os.Setenv("OLLAMA_HOST", "10.0.0.5:11434")
+	defer func() { _ = os.Unsetenv("OLLAMA_HOST") }()
+
+	e, err := NewOllamaEmbedder(Config{})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "http://10.0.0.5:11434/api/embed", e.apiBaseUrl)
+}
+
+func (s *OllamaEmbedderTestSuite) TestRegistryHonorsHostFromEnv() {
+	s.T().Setenv("OLLAMA_HOST", strings.TrimPrefix(s.server.URL, "http://"))
+
+	e, err := embed.New("ollama", nil)
+	require.NoError(s.T(), err)
+	vec, err := e.Embed("hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{5, 1}, vec)
+	assert.Equal(s.T(), "/api/embed", s.path)
+	assert.Equal(s.T(), defaultModel, s.lastBody.Model)
+}
+
+func TestOllamaEmbedderSuite(t *testing.T) {
+	suite.Run(t, new(OllamaEmbedderTestSuite))
+}
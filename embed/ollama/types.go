@@ -0,0 +1,20 @@
+package ollama
+
+type OllamaEmbedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	Truncate  *bool    `json:"truncate,omitempty"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+type OllamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	TotalDuration   int64       `json:"total_duration"`
+	LoadDuration    int64       `json:"load_duration"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+type OllamaErrorResponse struct {
+	Error string `json:"error"`
+}
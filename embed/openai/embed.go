@@ -3,34 +3,118 @@ package openai
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
 	"github.com/valyala/fasthttp"
+	"net/url"
 	"os"
+	"strings"
 )
 
 const (
-	model      = "text-embedding-3-small"
-	apiBaseUrl = "https://api.openai.com/v1/embeddings"
+	defaultModel     = "text-embedding-3-small"
+	defaultBaseUrl   = "https://api.openai.com/v1"
+	defaultAPIKeyEnv = "OPENAI_API_KEY"
 )
 
+// Config describes an OpenAI-compatible embeddings endpoint. The zero value
+// talks to api.openai.com with text-embedding-3-small, so the same embedder
+// can be pointed at Azure OpenAI, vLLM, LocalAI or an internal gateway by
+// only overriding the fields that differ.
+type Config struct {
+	// BaseURL is the API root, "/embeddings" is appended to it.
+	// For Azure this is https://<resource>.openai.azure.com/openai/deployments/<deployment>
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Model is sent as the "model" field of every request
+	Model string `json:"model,omitempty"`
+
+	// Dimensions asks the server to shorten the returned vectors.
+	// Zero leaves the model's native size.
+	Dimensions int `json:"dimensions,omitempty"`
+
+	// Organization is sent as the OpenAI-Organization header when set
+	Organization string `json:"organization,omitempty"`
+
+	// APIVersion is added as the api-version query parameter (required by Azure)
+	APIVersion string `json:"api_version,omitempty"`
+
+	// APIKey takes precedence over APIKeyEnv. It is never serialized.
+	APIKey string `json:"-"`
+
+	// APIKeyEnv names the environment variable holding the key, OPENAI_API_KEY by default
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+
+	// APIKeyHeader sends the raw key in this header instead of "Authorization: Bearer <key>".
+	// Azure expects "api-key".
+	APIKeyHeader string `json:"api_key_header,omitempty"`
+
+	// SkipAuth sends no credentials at all, for local servers that don't check them
+	SkipAuth bool `json:"skip_auth,omitempty"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:   defaultBaseUrl,
+		Model:     defaultModel,
+		APIKeyEnv: defaultAPIKeyEnv,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	if c.BaseURL == "" {
+		c.BaseURL = defaultBaseUrl
+	}
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+	if c.APIKeyEnv == "" {
+		c.APIKeyEnv = defaultAPIKeyEnv
+	}
+	return c
+}
+
+func init() {
+	embed.Register("openai", DefaultConfig, func(cfg Config) (embed.Embedder, error) {
+		return NewOpenAIEmbedderWithConfig(cfg)
+	})
+}
+
 type OpenAIEmbedder struct {
 	apiKey     string
 	apiBaseUrl string
+	config     Config
 }
 
 func NewOpenAIEmbedder() (*OpenAIEmbedder, error) {
-	apiKey, exists := os.LookupEnv("OPENAI_API_KEY")
-	if !exists {
-		return nil, fmt.Errorf("OPENAI_API_KEY not set")
+	return NewOpenAIEmbedderWithConfig(DefaultConfig())
+}
+
+func NewOpenAIEmbedderWithConfig(cfg Config) (*OpenAIEmbedder, error) {
+	cfg = cfg.withDefaults()
+
+	apiKey := cfg.APIKey
+	if apiKey == "" && !cfg.SkipAuth {
+		var exists bool
+		apiKey, exists = os.LookupEnv(cfg.APIKeyEnv)
+		if !exists {
+			return nil, fmt.Errorf("%s not set", cfg.APIKeyEnv)
+		}
+	}
+
+	endpoint := strings.TrimSuffix(cfg.BaseURL, "/") + "/embeddings"
+	if cfg.APIVersion != "" {
+		endpoint += "?api-version=" + url.QueryEscape(cfg.APIVersion)
 	}
 
 	return &OpenAIEmbedder{
 		apiKey:     apiKey,
-		apiBaseUrl: apiBaseUrl,
+		apiBaseUrl: endpoint,
+		config:     cfg,
 	}, nil
 }
 
 func (e *OpenAIEmbedder) GetEmbeddings(input string) (*OpenAIEmbeddingResponse, error) {
-	jsonBody, err := marshalRequest(input)
+	jsonBody, err := e.marshalRequest(input)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +127,7 @@ func (e *OpenAIEmbedder) GetEmbeddings(input string) (*OpenAIEmbeddingResponse,
 	req.SetRequestURI(e.apiBaseUrl)
 	req.Header.SetMethod(fasthttp.MethodPost)
 	req.Header.SetContentType("application/json")
-	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	e.setAuthHeaders(req)
 	req.SetBody(jsonBody)
 
 	if err := fasthttp.Do(req, resp); err != nil {
@@ -63,10 +147,24 @@ func (e *OpenAIEmbedder) GetEmbeddings(input string) (*OpenAIEmbeddingResponse,
 	return &embedResponse, nil
 }
 
-func marshalRequest(input string) ([]byte, error) {
+func (e *OpenAIEmbedder) setAuthHeaders(req *fasthttp.Request) {
+	if e.apiKey != "" {
+		if e.config.APIKeyHeader != "" {
+			req.Header.Set(e.config.APIKeyHeader, e.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+e.apiKey)
+		}
+	}
+	if e.config.Organization != "" {
+		req.Header.Set("OpenAI-Organization", e.config.Organization)
+	}
+}
+
+func (e *OpenAIEmbedder) marshalRequest(input string) ([]byte, error) {
 	requestBody := OpenAIEmbeddingRequest{
-		Input: []string{input},
-		Model: model,
+		Input:      []string{input},
+		Model:      e.config.Model,
+		Dimensions: e.config.Dimensions,
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
@@ -76,6 +174,13 @@ func marshalRequest(input string) ([]byte, error) {
 	return jsonBody, nil
 }
 
+func (e *OpenAIEmbedder) ModelInfo() embed.ModelInfo {
+	return embed.ModelInfo{
+		Name:       e.config.Model,
+		Dimensions: e.config.Dimensions,
+	}
+}
+
 func (e *OpenAIEmbedder) Embed(text string) ([]float64, error) {
 	response, err := e.GetEmbeddings(text)
 	if err != nil {
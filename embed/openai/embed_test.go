@@ -0,0 +1,97 @@
+package openai
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type OpenAIEmbedderTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	lastReq  *http.Request
+	lastBody OpenAIEmbeddingRequest
+}
+
+func (s *OpenAIEmbedderTestSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lastReq = r
+		_ = json.NewDecoder(r.Body).Decode(&s.lastBody)
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5,-0.25,1]}],"model":"m"}`))
+	}))
+}
+
+func (s *OpenAIEmbedderTestSuite) TearDownTest() {
+	s.server.Close()
+	_ = os.Unsetenv("TEST_GATEWAY_KEY")
+}
+
+func (s *OpenAIEmbedderTestSuite) TestDefaultsRequireKey() {
+	_ = os.Unsetenv("OPENAI_API_KEY")
+	_, err := NewOpenAIEmbedder()
+	assert.Error(s.T(), err)
+}
+
+func (s *OpenAIEmbedderTestSuite) TestCustomEndpoint() {
+	_ = os.Setenv("TEST_GATEWAY_KEY", "secret")
+	e, err := NewOpenAIEmbedderWithConfig(Config{
+		BaseURL:      s.server.URL + "/v1/",
+		Model:        "custom-model",
+		Dimensions:   3,
+		Organization: "org-123",
+		APIKeyEnv:    "TEST_GATEWAY_KEY",
+	})
+	require.NoError(s.T(), err)
+
+	vec, err := e.Embed("hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0.5, -0.25, 1}, vec)
+
+	assert.Equal(s.T(), "/v1/embeddings", s.lastReq.URL.Path)
+	assert.Equal(s.T(), "Bearer secret", s.lastReq.Header.Get("Authorization"))
+	assert.Equal(s.T(), "org-123", s.lastReq.Header.Get("OpenAI-Organization"))
+	assert.Equal(s.T(), "custom-model", s.lastBody.Model)
+	assert.Equal(s.T(), 3, s.lastBody.Dimensions)
+	assert.Equal(s.T(), []string{"hello"}, s.lastBody.Input)
+}
+
+func (s *OpenAIEmbedderTestSuite) TestAzureStyleAuth() {
+	e, err := NewOpenAIEmbedderWithConfig(Config{
+		BaseURL:      s.server.URL + "/openai/deployments/emb",
+		APIKey:       "azure-key",
+		APIKeyHeader: "api-key",
+		APIVersion:   "2024-02-01",
+	})
+	require.NoError(s.T(), err)
+
+	_, err = e.Embed("hello")
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "/openai/deployments/emb/embeddings", s.lastReq.URL.Path)
+	assert.Equal(s.T(), "2024-02-01", s.lastReq.URL.Query().Get("api-version"))
+	assert.Equal(s.T(), "azure-key", s.lastReq.Header.Get("api-key"))
+	assert.Empty(s.T(), s.lastReq.Header.Get("Authorization"))
+}
+
+func (s *OpenAIEmbedderTestSuite) TestSkipAuth() {
+	e, err := NewOpenAIEmbedderWithConfig(Config{
+		BaseURL:   s.server.URL,
+		APIKeyEnv: "TEST_GATEWAY_KEY",
+		SkipAuth:  true,
+	})
+	require.NoError(s.T(), err)
+
+	_, err = e.Embed("hello")
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), s.lastReq.Header.Get("Authorization"))
+	assert.Equal(s.T(), defaultModel, s.lastBody.Model)
+}
+
+func TestOpenAIEmbedderSuite(t *testing.T) {
+	suite.Run(t, new(OpenAIEmbedderTestSuite))
+}
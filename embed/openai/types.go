@@ -1,8 +1,9 @@
 package openai
 
 type OpenAIEmbeddingRequest struct {
-	Input []string `json:"input"`
-	Model string   `json:"model"`
+	Input      []string `json:"input"`
+	Model      string   `json:"model"`
+	Dimensions int      `json:"dimensions,omitempty"`
 }
 
 type OpenAIEmbeddingResponse struct {
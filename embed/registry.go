@@ -0,0 +1,95 @@
+package embed
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// provider builds an embedder from untyped options
+type provider func(options any) (Embedder, error)
+
+var (
+	providers    = map[string]provider{}
+	providerLock sync.RWMutex
+)
+
+// Register makes an embedding provider available by name to New and to
+// DBConfig.EmbeddingModel. defaults returns the options used when none are
+// given, and is also the starting point that JSON options (from a config
+// file) are decoded on top of. Register panics if name is already taken,
+// mirroring database/sql.Register, so it is meant to be called from init.
+func Register[T any](name string, defaults func() T, build func(options T) (Embedder, error)) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("embed: provider %s registered twice", name))
+	}
+
+	providers[name] = func(options any) (Embedder, error) {
+		typed := defaults()
+		if err := DecodeOptions(options, &typed); err != nil {
+			return nil, fmt.Errorf("invalid options for embedding model %s: %v", name, err)
+		}
+		return build(typed)
+	}
+}
+
+// DecodeOptions accepts the provider's own option struct (or a pointer to
+// it), nil for the defaults, or anything JSON-shaped such as raw bytes or a
+// map decoded from a config file
+func DecodeOptions[T any](options any, dest *T) error {
+	switch o := options.(type) {
+	case nil:
+		return nil
+	case T:
+		*dest = o
+		return nil
+	case *T:
+		if o != nil {
+			*dest = *o
+		}
+		return nil
+	case json.RawMessage:
+		return json.Unmarshal(o, dest)
+	case []byte:
+		return json.Unmarshal(o, dest)
+	case map[string]any:
+		raw, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(raw, dest)
+	default:
+		return fmt.Errorf("expected %T, got %T", *dest, options)
+	}
+}
+
+// New builds the embedder registered under name.
+func New(name string, options any) (Embedder, error) {
+	providerLock.RLock()
+	p, exists := providers[name]
+	providerLock.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("embedding model %s not supported", name)
+	}
+
+	return p(options)
+}
+
+// Registered lists the names of all registered providers in sorted order.
+func Registered() []string {
+	providerLock.RLock()
+	defer providerLock.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
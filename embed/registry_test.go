@@ -0,0 +1,80 @@
+package embed
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type fakeOptions struct {
+	Model string `json:"model"`
+	Dims  int    `json:"dims"`
+}
+
+type fakeEmbedder struct {
+	options fakeOptions
+}
+
+func (f *fakeEmbedder) Embed(string) ([]float64, error) {
+	return make([]float64, f.options.Dims), nil
+}
+
+type RegistryTestSuite struct {
+	suite.Suite
+}
+
+func (s *RegistryTestSuite) SetupSuite() {
+	Register("registry-test", func() fakeOptions {
+		return fakeOptions{Model: "default", Dims: 2}
+	}, func(options fakeOptions) (Embedder, error) {
+		return &fakeEmbedder{options: options}, nil
+	})
+}
+
+func (s *RegistryTestSuite) build(options any) fakeOptions {
+	e, err := New("registry-test", options)
+	require.NoError(s.T(), err)
+	return e.(*fakeEmbedder).options
+}
+
+func (s *RegistryTestSuite) TestDefaults() {
+	assert.Equal(s.T(), fakeOptions{Model: "default", Dims: 2}, s.build(nil))
+}
+
+func (s *RegistryTestSuite) TestTypedOptions() {
+	assert.Equal(s.T(), fakeOptions{Model: "typed", Dims: 8}, s.build(fakeOptions{Model: "typed", Dims: 8}))
+	assert.Equal(s.T(), fakeOptions{Model: "ptr", Dims: 4}, s.build(&fakeOptions{Model: "ptr", Dims: 4}))
+}
+
+func (s *RegistryTestSuite) TestJSONOptionsOverlayDefaults() {
+	assert.Equal(s.T(), fakeOptions{Model: "default", Dims: 16}, s.build(json.RawMessage(`{"dims":16}`)))
+
+	var decoded any
+	require.NoError(s.T(), json.Unmarshal([]byte(`{"model":"from-file"}`), &decoded))
+	assert.Equal(s.T(), fakeOptions{Model: "from-file", Dims: 2}, s.build(decoded))
+}
+
+func (s *RegistryTestSuite) TestWrongOptionType() {
+	_, err := New("registry-test", struct{ Other string }{})
+	assert.Error(s.T(), err)
+}
+
+func (s *RegistryTestSuite) TestUnknownProvider() {
+	_, err := New("does-not-exist", nil)
+	assert.Error(s.T(), err)
+}
+
+func (s *RegistryTestSuite) TestDuplicateRegistrationPanics() {
+	assert.Panics(s.T(), func() {
+		Register("registry-test", func() fakeOptions { return fakeOptions{} }, func(fakeOptions) (Embedder, error) {
+			return nil, nil
+		})
+	})
+	assert.Contains(s.T(), Registered(), "registry-test")
+}
+
+func TestRegistrySuite(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
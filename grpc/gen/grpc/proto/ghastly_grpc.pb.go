@@ -19,15 +19,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GhastlyDB_Put_FullMethodName         = "/ghastlydb.GhastlyDB/Put"
-	GhastlyDB_Get_FullMethodName         = "/ghastlydb.GhastlyDB/Get"
-	GhastlyDB_Delete_FullMethodName      = "/ghastlydb.GhastlyDB/Delete"
-	GhastlyDB_Exists_FullMethodName      = "/ghastlydb.GhastlyDB/Exists"
-	GhastlyDB_Search_FullMethodName      = "/ghastlydb.GhastlyDB/Search"
-	GhastlyDB_BulkPut_FullMethodName     = "/ghastlydb.GhastlyDB/BulkPut"
-	GhastlyDB_BulkSearch_FullMethodName  = "/ghastlydb.GhastlyDB/BulkSearch"
-	GhastlyDB_HealthCheck_FullMethodName = "/ghastlydb.GhastlyDB/HealthCheck"
-	GhastlyDB_GetConfig_FullMethodName   = "/ghastlydb.GhastlyDB/GetConfig"
+	GhastlyDB_Put_FullMethodName            = "/ghastlydb.GhastlyDB/Put"
+	GhastlyDB_Get_FullMethodName            = "/ghastlydb.GhastlyDB/Get"
+	GhastlyDB_Delete_FullMethodName         = "/ghastlydb.GhastlyDB/Delete"
+	GhastlyDB_Exists_FullMethodName         = "/ghastlydb.GhastlyDB/Exists"
+	GhastlyDB_Search_FullMethodName         = "/ghastlydb.GhastlyDB/Search"
+	GhastlyDB_Recommend_FullMethodName      = "/ghastlydb.GhastlyDB/Recommend"
+	GhastlyDB_SearchRadius_FullMethodName   = "/ghastlydb.GhastlyDB/SearchRadius"
+	GhastlyDB_TrainIndex_FullMethodName     = "/ghastlydb.GhastlyDB/TrainIndex"
+	GhastlyDB_StartDedup_FullMethodName     = "/ghastlydb.GhastlyDB/StartDedup"
+	GhastlyDB_GetDedupJob_FullMethodName    = "/ghastlydb.GhastlyDB/GetDedupJob"
+	GhastlyDB_CancelDedupJob_FullMethodName = "/ghastlydb.GhastlyDB/CancelDedupJob"
+	GhastlyDB_BulkPut_FullMethodName        = "/ghastlydb.GhastlyDB/BulkPut"
+	GhastlyDB_BulkSearch_FullMethodName     = "/ghastlydb.GhastlyDB/BulkSearch"
+	GhastlyDB_HealthCheck_FullMethodName    = "/ghastlydb.GhastlyDB/HealthCheck"
+	GhastlyDB_GetConfig_FullMethodName      = "/ghastlydb.GhastlyDB/GetConfig"
 )
 
 // GhastlyDBClient is the client API for GhastlyDB service.
@@ -39,6 +45,12 @@ type GhastlyDBClient interface {
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error)
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	SearchRadius(ctx context.Context, in *SearchRadiusRequest, opts ...grpc.CallOption) (*SearchRadiusResponse, error)
+	TrainIndex(ctx context.Context, in *TrainIndexRequest, opts ...grpc.CallOption) (*TrainIndexResponse, error)
+	StartDedup(ctx context.Context, in *DedupRequest, opts ...grpc.CallOption) (*DedupJobStatus, error)
+	GetDedupJob(ctx context.Context, in *DedupJobRequest, opts ...grpc.CallOption) (*DedupJobStatus, error)
+	CancelDedupJob(ctx context.Context, in *DedupJobRequest, opts ...grpc.CallOption) (*DedupJobStatus, error)
 	BulkPut(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PutRequest, BulkPutResponse], error)
 	BulkSearch(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SearchResponse], error)
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
@@ -103,6 +115,66 @@ func (c *ghastlyDBClient) Search(ctx context.Context, in *SearchRequest, opts ..
 	return out, nil
 }
 
+func (c *ghastlyDBClient) Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, GhastlyDB_Recommend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ghastlyDBClient) SearchRadius(ctx context.Context, in *SearchRadiusRequest, opts ...grpc.CallOption) (*SearchRadiusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchRadiusResponse)
+	err := c.cc.Invoke(ctx, GhastlyDB_SearchRadius_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ghastlyDBClient) TrainIndex(ctx context.Context, in *TrainIndexRequest, opts ...grpc.CallOption) (*TrainIndexResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrainIndexResponse)
+	err := c.cc.Invoke(ctx, GhastlyDB_TrainIndex_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ghastlyDBClient) StartDedup(ctx context.Context, in *DedupRequest, opts ...grpc.CallOption) (*DedupJobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DedupJobStatus)
+	err := c.cc.Invoke(ctx, GhastlyDB_StartDedup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ghastlyDBClient) GetDedupJob(ctx context.Context, in *DedupJobRequest, opts ...grpc.CallOption) (*DedupJobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DedupJobStatus)
+	err := c.cc.Invoke(ctx, GhastlyDB_GetDedupJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ghastlyDBClient) CancelDedupJob(ctx context.Context, in *DedupJobRequest, opts ...grpc.CallOption) (*DedupJobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DedupJobStatus)
+	err := c.cc.Invoke(ctx, GhastlyDB_CancelDedupJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *ghastlyDBClient) BulkPut(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PutRequest, BulkPutResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &GhastlyDB_ServiceDesc.Streams[0], GhastlyDB_BulkPut_FullMethodName, cOpts...)
@@ -164,6 +236,12 @@ type GhastlyDBServer interface {
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
 	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	Recommend(context.Context, *RecommendRequest) (*SearchResponse, error)
+	SearchRadius(context.Context, *SearchRadiusRequest) (*SearchRadiusResponse, error)
+	TrainIndex(context.Context, *TrainIndexRequest) (*TrainIndexResponse, error)
+	StartDedup(context.Context, *DedupRequest) (*DedupJobStatus, error)
+	GetDedupJob(context.Context, *DedupJobRequest) (*DedupJobStatus, error)
+	CancelDedupJob(context.Context, *DedupJobRequest) (*DedupJobStatus, error)
 	BulkPut(grpc.ClientStreamingServer[PutRequest, BulkPutResponse]) error
 	BulkSearch(*SearchRequest, grpc.ServerStreamingServer[SearchResponse]) error
 	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
@@ -193,6 +271,24 @@ func (UnimplementedGhastlyDBServer) Exists(context.Context, *ExistsRequest) (*Ex
 func (UnimplementedGhastlyDBServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
 }
+func (UnimplementedGhastlyDBServer) Recommend(context.Context, *RecommendRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recommend not implemented")
+}
+func (UnimplementedGhastlyDBServer) SearchRadius(context.Context, *SearchRadiusRequest) (*SearchRadiusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchRadius not implemented")
+}
+func (UnimplementedGhastlyDBServer) TrainIndex(context.Context, *TrainIndexRequest) (*TrainIndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TrainIndex not implemented")
+}
+func (UnimplementedGhastlyDBServer) StartDedup(context.Context, *DedupRequest) (*DedupJobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartDedup not implemented")
+}
+func (UnimplementedGhastlyDBServer) GetDedupJob(context.Context, *DedupJobRequest) (*DedupJobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDedupJob not implemented")
+}
+func (UnimplementedGhastlyDBServer) CancelDedupJob(context.Context, *DedupJobRequest) (*DedupJobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelDedupJob not implemented")
+}
 func (UnimplementedGhastlyDBServer) BulkPut(grpc.ClientStreamingServer[PutRequest, BulkPutResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method BulkPut not implemented")
 }
@@ -316,6 +412,114 @@ func _GhastlyDB_Search_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GhastlyDB_Recommend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecommendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).Recommend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_Recommend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).Recommend(ctx, req.(*RecommendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GhastlyDB_SearchRadius_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRadiusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).SearchRadius(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_SearchRadius_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).SearchRadius(ctx, req.(*SearchRadiusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GhastlyDB_TrainIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrainIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).TrainIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_TrainIndex_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).TrainIndex(ctx, req.(*TrainIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GhastlyDB_StartDedup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DedupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).StartDedup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_StartDedup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).StartDedup(ctx, req.(*DedupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GhastlyDB_GetDedupJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DedupJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).GetDedupJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_GetDedupJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).GetDedupJob(ctx, req.(*DedupJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GhastlyDB_CancelDedupJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DedupJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GhastlyDBServer).CancelDedupJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GhastlyDB_CancelDedupJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GhastlyDBServer).CancelDedupJob(ctx, req.(*DedupJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _GhastlyDB_BulkPut_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(GhastlyDBServer).BulkPut(&grpc.GenericServerStream[PutRequest, BulkPutResponse]{ServerStream: stream})
 }
@@ -397,6 +601,30 @@ var GhastlyDB_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Search",
 			Handler:    _GhastlyDB_Search_Handler,
 		},
+		{
+			MethodName: "Recommend",
+			Handler:    _GhastlyDB_Recommend_Handler,
+		},
+		{
+			MethodName: "SearchRadius",
+			Handler:    _GhastlyDB_SearchRadius_Handler,
+		},
+		{
+			MethodName: "TrainIndex",
+			Handler:    _GhastlyDB_TrainIndex_Handler,
+		},
+		{
+			MethodName: "StartDedup",
+			Handler:    _GhastlyDB_StartDedup_Handler,
+		},
+		{
+			MethodName: "GetDedupJob",
+			Handler:    _GhastlyDB_GetDedupJob_Handler,
+		},
+		{
+			MethodName: "CancelDedupJob",
+			Handler:    _GhastlyDB_CancelDedupJob_Handler,
+		},
 		{
 			MethodName: "HealthCheck",
 			Handler:    _GhastlyDB_HealthCheck_Handler,
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	db2 "github.com/ahhcash/ghastlydb/db"
 	pb "github.com/ahhcash/ghastlydb/grpc/gen/grpc/proto"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"io"
@@ -22,7 +24,7 @@ func NewGhastlyServer(db *db2.DB) *GhastlyServer {
 }
 
 func (s *GhastlyServer) Put(_ context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
-	if err := s.db.Put(req.Key, req.Value); err != nil {
+	if err := s.put(req); err != nil {
 		return &pb.PutResponse{
 			Success: false,
 			Error:   err.Error(),
@@ -32,6 +34,30 @@ func (s *GhastlyServer) Put(_ context.Context, req *pb.PutRequest) (*pb.PutRespo
 	return &pb.PutResponse{Success: true}, nil
 }
 
+func (s *GhastlyServer) put(req *pb.PutRequest) error {
+	if req.Chunk {
+		return s.db.PutDocumentWithChunking(req.Key, req.Value, s.db.DBConfig.Chunking, req.Metadata)
+	}
+
+	opts := storage.PutOptions{Metadata: req.Metadata}
+	if req.Sparse != nil {
+		sparse, err := fromSparseVector(req.Sparse)
+		if err != nil {
+			return err
+		}
+		opts.Sparse = sparse
+	}
+	return s.db.PutWithOptions(req.Key, req.Value, opts)
+}
+
+func fromSparseVector(vec *pb.SparseVector) (search.SparseVector, error) {
+	values := make([]float64, len(vec.Values))
+	for i, v := range vec.Values {
+		values[i] = float64(v)
+	}
+	return search.NewSparseVector(vec.Indices, values)
+}
+
 func (s *GhastlyServer) Get(_ context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
 	value, err := s.db.Get(req.Key)
 	if err != nil {
@@ -68,22 +94,213 @@ func (s *GhastlyServer) Exists(_ context.Context, req *pb.ExistsRequest) (*pb.Ex
 }
 
 func (s *GhastlyServer) Search(_ context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
-	results, err := s.db.Search(req.Query)
+	opts := db2.DefaultSearchOptions()
+	opts.Metric = req.Metric
+	if req.Mode != "" {
+		opts.Mode = req.Mode
+	}
+	if req.Fusion != "" {
+		opts.Hybrid.Fusion = req.Fusion
+	}
+	if req.Alpha != nil {
+		opts.Hybrid.Alpha = float64(*req.Alpha)
+	}
+	if req.Sparse != nil {
+		sparse, err := fromSparseVector(req.Sparse)
+		if err != nil {
+			return &pb.SearchResponse{
+				Error: err.Error(),
+			}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts.Sparse = sparse
+	}
+	if req.Rerank != nil && !*req.Rerank {
+		opts.Rerank.Disabled = true
+	}
+	opts.Rerank.TopN = int(req.RerankTopN)
+	opts.NProbe = int(req.Nprobe)
+	if req.Mmr || req.Lambda != nil {
+		mmr := db2.DefaultMMROptions()
+		if req.Lambda != nil {
+			mmr.Lambda = float64(*req.Lambda)
+		}
+		opts.MMR = &mmr
+	}
+
+	results, err := s.db.SearchWithOptions(req.Query, opts)
 	if err != nil {
 		return &pb.SearchResponse{
 			Error: err.Error(),
 		}, status.Error(codes.Internal, err.Error())
 	}
 
+	if req.GroupByDocument {
+		return searchDocumentsResponse(req, s.db.GroupByDocument(results, int(req.ChunksPerDocument))), nil
+	}
+
+	if req.GroupBy != "" {
+		return searchGroupsResponse(req, db2.GroupByField(results, req.GroupBy, int(req.GroupSize))), nil
+	}
+
 	pbResults := make([]*pb.SearchResult, 0, len(results))
 	for _, r := range results {
 		if req.ScoreThreshold > 0 && r.Score < float64(req.ScoreThreshold) {
 			continue
 		}
+		pbResults = append(pbResults, toSearchResult(r))
+	}
+
+	if req.Limit > 0 && int32(len(pbResults)) > req.Limit {
+		pbResults = pbResults[:req.Limit]
+	}
+
+	return &pb.SearchResponse{Results: pbResults}, nil
+}
+
+func (s *GhastlyServer) Recommend(_ context.Context, req *pb.RecommendRequest) (*pb.SearchResponse, error) {
+	results, err := s.db.Recommend(db2.RecommendOptions{
+		Positive: req.Positive,
+		Negative: req.Negative,
+		Strategy: req.Strategy,
+		Metric:   req.Metric,
+	})
+	if err != nil {
+		return &pb.SearchResponse{
+			Error: err.Error(),
+		}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	pbResults := make([]*pb.SearchResult, 0, len(results))
+	for _, r := range results {
+		pbResults = append(pbResults, toSearchResult(r))
+	}
+
+	if req.Limit > 0 && int32(len(pbResults)) > req.Limit {
+		pbResults = pbResults[:req.Limit]
+	}
+
+	return &pb.SearchResponse{Results: pbResults}, nil
+}
+
+func (s *GhastlyServer) SearchRadius(_ context.Context, req *pb.SearchRadiusRequest) (*pb.SearchRadiusResponse, error) {
+	page, err := s.db.SearchRadius(req.Query, db2.RadiusOptions{
+		Radius: req.Radius,
+		Metric: req.Metric,
+		Offset: int(req.Offset),
+		Limit:  int(req.Limit),
+	})
+	if err != nil {
+		return &pb.SearchRadiusResponse{
+			Error: err.Error(),
+		}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	pbResults := make([]*pb.SearchResult, 0, len(page.Results))
+	for _, r := range page.Results {
+		pbResults = append(pbResults, toSearchResult(r))
+	}
+
+	return &pb.SearchRadiusResponse{
+		Results:    pbResults,
+		Total:      int32(page.Total),
+		NextOffset: int32(page.NextOffset),
+	}, nil
+}
+
+func (s *GhastlyServer) TrainIndex(_ context.Context, _ *pb.TrainIndexRequest) (*pb.TrainIndexResponse, error) {
+	if err := s.db.TrainIndex(); err != nil {
+		return &pb.TrainIndexResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &pb.TrainIndexResponse{Success: true}, nil
+}
+
+func (s *GhastlyServer) StartDedup(_ context.Context, req *pb.DedupRequest) (*pb.DedupJobStatus, error) {
+	opts := db2.DefaultDedupOptions()
+	if req.Threshold != 0 {
+		opts.Threshold = req.Threshold
+	}
+	if req.Action != "" {
+		opts.Action = req.Action
+	}
+	if req.Ef != 0 {
+		opts.Ef = int(req.Ef)
+	}
+
+	return toDedupJobStatus(s.db.StartDedup(opts).Status()), nil
+}
+
+func (s *GhastlyServer) GetDedupJob(_ context.Context, req *pb.DedupJobRequest) (*pb.DedupJobStatus, error) {
+	job, exists := s.db.DedupJob(req.Id)
+	if !exists {
+		return nil, status.Error(codes.NotFound, "dedup job not found")
+	}
+
+	return toDedupJobStatus(job.Status()), nil
+}
+
+func (s *GhastlyServer) CancelDedupJob(_ context.Context, req *pb.DedupJobRequest) (*pb.DedupJobStatus, error) {
+	job, exists := s.db.DedupJob(req.Id)
+	if !exists {
+		return nil, status.Error(codes.NotFound, "dedup job not found")
+	}
+
+	job.Cancel()
+	_, _ = job.Wait()
+	return toDedupJobStatus(job.Status()), nil
+}
+
+func toDedupJobStatus(jobStatus db2.DedupStatus) *pb.DedupJobStatus {
+	result := &pb.DedupJobStatus{
+		Id:    jobStatus.ID,
+		State: jobStatus.State,
+		Phase: jobStatus.Progress.Phase,
+		Done:  int32(jobStatus.Progress.Done),
+		Total: int32(jobStatus.Progress.Total),
+		Error: jobStatus.Error,
+	}
+	if jobStatus.Report == nil {
+		return result
+	}
+
+	result.Scanned = int32(jobStatus.Report.Scanned)
+	result.Removed = int32(jobStatus.Report.Removed)
+	for _, cluster := range jobStatus.Report.Clusters {
+		pbCluster := &pb.DuplicateCluster{
+			Keep:       cluster.Keep,
+			Duplicates: cluster.Duplicates,
+		}
+		for _, pair := range cluster.Pairs {
+			pbCluster.Pairs = append(pbCluster.Pairs, &pb.DuplicatePair{
+				A:          pair.A,
+				B:          pair.B,
+				Similarity: pair.Similarity,
+			})
+		}
+		result.Clusters = append(result.Clusters, pbCluster)
+	}
+
+	return result
+}
+
+func searchDocumentsResponse(req *pb.SearchRequest, documents []db2.DocumentResult) *pb.SearchResponse {
+	pbResults := make([]*pb.SearchResult, 0, len(documents))
+	for _, d := range documents {
+		if req.ScoreThreshold > 0 && d.Score < float64(req.ScoreThreshold) {
+			continue
+		}
+		chunks := make([]*pb.SearchResult, 0, len(d.Chunks))
+		for _, r := range d.Chunks {
+			chunks = append(chunks, toSearchResult(r))
+		}
 		pbResults = append(pbResults, &pb.SearchResult{
-			Key:   r.Key,
-			Value: r.Value,
-			Score: float32(r.Score),
+			Key:    d.Key,
+			Value:  d.Value,
+			Score:  float32(d.Score),
+			Chunks: chunks,
 		})
 	}
 
@@ -91,7 +308,39 @@ func (s *GhastlyServer) Search(_ context.Context, req *pb.SearchRequest) (*pb.Se
 		pbResults = pbResults[:req.Limit]
 	}
 
-	return &pb.SearchResponse{Results: pbResults}, nil
+	return &pb.SearchResponse{Results: pbResults}
+}
+
+func searchGroupsResponse(req *pb.SearchRequest, groups []db2.ResultGroup) *pb.SearchResponse {
+	pbGroups := make([]*pb.SearchGroup, 0, len(groups))
+	for _, g := range groups {
+		results := make([]*pb.SearchResult, 0, len(g.Results))
+		for _, r := range g.Results {
+			if req.ScoreThreshold > 0 && r.Score < float64(req.ScoreThreshold) {
+				continue
+			}
+			results = append(results, toSearchResult(r))
+		}
+		if len(results) > 0 {
+			pbGroups = append(pbGroups, &pb.SearchGroup{Value: g.Value, Results: results})
+		}
+	}
+
+	if req.Limit > 0 && int32(len(pbGroups)) > req.Limit {
+		pbGroups = pbGroups[:req.Limit]
+	}
+
+	return &pb.SearchResponse{Groups: pbGroups}
+}
+
+func toSearchResult(r storage.Result) *pb.SearchResult {
+	return &pb.SearchResult{
+		Key:      r.Key,
+		Value:    r.Value,
+		Score:    float32(r.Score),
+		Parent:   r.Parent,
+		Metadata: r.Metadata,
+	}
 }
 
 func (s *GhastlyServer) GetConfig(_ context.Context, req *pb.GetConfigRequest) (*pb.GetConfigResponse, error) {
@@ -122,7 +371,7 @@ func (s *GhastlyServer) BulkPut(stream pb.GhastlyDB_BulkPutServer) error {
 			return err
 		}
 
-		if err := s.db.Put(req.Key, req.Value); err != nil {
+		if err := s.put(req); err != nil {
 			failed = append(failed, req.Key)
 		} else {
 			processed++
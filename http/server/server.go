@@ -1,7 +1,10 @@
 package server
 
 import (
+	"github.com/ahhcash/ghastlydb/chunk"
 	"github.com/ahhcash/ghastlydb/db"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/ahhcash/ghastlydb/storage"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"net/http"
@@ -42,7 +45,14 @@ func (s *Server) registerRoutes() {
 	s.router.GET("/v1/documents/:key", s.handleGet)
 	s.router.DELETE("/v1/documents/:key", s.handleDelete)
 	s.router.POST("/v1/search", s.handleSearch)
+	s.router.POST("/v1/search/radius", s.handleSearchRadius)
+	s.router.POST("/v1/recommend", s.handleRecommend)
+	s.router.POST("/v1/index/train", s.handleTrainIndex)
+	s.router.POST("/v1/dedup", s.handleStartDedup)
+	s.router.GET("/v1/dedup/:id", s.handleGetDedup)
+	s.router.DELETE("/v1/dedup/:id", s.handleCancelDedup)
 	s.router.GET("/v1/config", s.handleGetConfig)
+	s.router.GET("/v1/stats/embedding-cache", s.handleEmbeddingCacheStats)
 }
 
 // Start begins listening for HTTP requests
@@ -61,6 +71,16 @@ func (s *Server) handleHealthCheck(c echo.Context) error {
 type PutRequest struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+
+	// Chunk splits long values before embedding, using Chunking when set
+	// and the database's chunking config otherwise
+	Chunk    bool          `json:"chunk,omitempty"`
+	Chunking *chunk.Config `json:"chunking,omitempty"`
+
+	// Sparse is stored next to the dense embedding of Value
+	Sparse *search.SparseVector `json:"sparse,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // handlePut handles document storage requests
@@ -72,7 +92,21 @@ func (s *Server) handlePut(c echo.Context) error {
 		})
 	}
 
-	if err := s.db.Put(req.Key, req.Value); err != nil {
+	var err error
+	if req.Chunk || req.Chunking != nil {
+		chunking := s.db.DBConfig.Chunking
+		if req.Chunking != nil {
+			chunking = *req.Chunking
+		}
+		err = s.db.PutDocumentWithChunking(req.Key, req.Value, chunking, req.Metadata)
+	} else {
+		opts := storage.PutOptions{Metadata: req.Metadata}
+		if req.Sparse != nil {
+			opts.Sparse = *req.Sparse
+		}
+		err = s.db.PutWithOptions(req.Key, req.Value, opts)
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
@@ -115,8 +149,72 @@ func (s *Server) handleDelete(c echo.Context) error {
 
 // SearchRequest represents the search query parameters
 type SearchRequest struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit,omitempty"`
+	Query  string `json:"query"`
+	Limit  int    `json:"limit,omitempty"`
+	Metric string `json:"metric,omitempty"`
+
+	// Mode is "vector", "keyword" or "hybrid". Hybrid search fuses the
+	// rankings with Fusion ("rrf" or "weighted"), weighting the vector
+	// score by Alpha when blending.
+	Mode   string   `json:"mode,omitempty"`
+	Fusion string   `json:"fusion,omitempty"`
+	Alpha  *float64 `json:"alpha,omitempty"`
+
+	// Sparse queries sparse vectors, alone in "sparse" mode or fused with
+	// the dense query in "vector" mode
+	Sparse *search.SparseVector `json:"sparse,omitempty"`
+
+	// Rerank set to false skips the configured reranker, and RerankTopN
+	// overrides how many results it re-scores
+	Rerank     *bool `json:"rerank,omitempty"`
+	RerankTopN int   `json:"rerank_top_n,omitempty"`
+
+	// MMR re-ranks results for diversity, trading relevance (Lambda 1)
+	// against novelty (Lambda 0)
+	MMR    bool     `json:"mmr,omitempty"`
+	Lambda *float64 `json:"lambda,omitempty"`
+
+	// GroupByDocument ranks chunked documents by their best chunk
+	GroupByDocument   bool `json:"group_by_document,omitempty"`
+	ChunksPerDocument int  `json:"chunks_per_document,omitempty"`
+
+	// GroupBy returns the best GroupSize results for every value of a
+	// metadata field
+	GroupBy   string `json:"group_by,omitempty"`
+	GroupSize int    `json:"group_size,omitempty"`
+
+	// NProbe overrides how many posting lists an ivf index scans
+	NProbe int `json:"nprobe,omitempty"`
+}
+
+func (r SearchRequest) options() db.SearchOptions {
+	opts := db.DefaultSearchOptions()
+	opts.Metric = r.Metric
+	if r.Mode != "" {
+		opts.Mode = r.Mode
+	}
+	if r.Fusion != "" {
+		opts.Hybrid.Fusion = r.Fusion
+	}
+	if r.Alpha != nil {
+		opts.Hybrid.Alpha = *r.Alpha
+	}
+	if r.Sparse != nil {
+		opts.Sparse = *r.Sparse
+	}
+	if r.Rerank != nil && !*r.Rerank {
+		opts.Rerank.Disabled = true
+	}
+	opts.Rerank.TopN = r.RerankTopN
+	opts.NProbe = r.NProbe
+	if r.MMR || r.Lambda != nil {
+		mmr := db.DefaultMMROptions()
+		if r.Lambda != nil {
+			mmr.Lambda = *r.Lambda
+		}
+		opts.MMR = &mmr
+	}
+	return opts
 }
 
 // handleSearch performs semantic search over documents
@@ -128,18 +226,194 @@ func (s *Server) handleSearch(c echo.Context) error {
 		})
 	}
 
-	results, err := s.db.Search(req.Query)
+	results, err := s.db.SearchWithOptions(req.Query, req.options())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
+	if req.GroupByDocument {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"results": s.db.GroupByDocument(results, req.ChunksPerDocument),
+		})
+	}
+
+	if req.GroupBy != "" {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"groups": db.GroupByField(results, req.GroupBy, req.GroupSize),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
+
+// SearchRadiusRequest pages through every entry within Radius of the query
+type SearchRadiusRequest struct {
+	Query  string  `json:"query"`
+	Radius float64 `json:"radius"`
+	Metric string  `json:"metric,omitempty"`
+	Offset int     `json:"offset,omitempty"`
+	Limit  int     `json:"limit,omitempty"`
+}
+
+// handleSearchRadius returns one page of the entries within a radius
+func (s *Server) handleSearchRadius(c echo.Context) error {
+	var req SearchRadiusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	page, err := s.db.SearchRadius(req.Query, db.RadiusOptions{
+		Radius: req.Radius,
+		Metric: req.Metric,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results":     page.Results,
+		"total":       page.Total,
+		"next_offset": page.NextOffset,
+	})
+}
+
+// RecommendRequest asks for entries like the positive examples and unlike
+// the negative ones
+type RecommendRequest struct {
+	Positive []string `json:"positive"`
+	Negative []string `json:"negative,omitempty"`
+	Strategy string   `json:"strategy,omitempty"`
+	Metric   string   `json:"metric,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+}
+
+// handleRecommend searches with the stored vectors of existing entries
+func (s *Server) handleRecommend(c echo.Context) error {
+	var req RecommendRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	results, err := s.db.Recommend(db.RecommendOptions{
+		Positive: req.Positive,
+		Negative: req.Negative,
+		Strategy: req.Strategy,
+		Metric:   req.Metric,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Limit > 0 && len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"results": results,
 	})
 }
 
+// handleTrainIndex trains the ivf index on the vectors stored so far
+func (s *Server) handleTrainIndex(c echo.Context) error {
+	if err := s.db.TrainIndex(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "success",
+	})
+}
+
+// DedupRequest starts a near-duplicate detection job
+type DedupRequest struct {
+	// Threshold is the cosine similarity at which entries count as
+	// duplicates
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Action is "report" (the default), "delete" or "merge"
+	Action string `json:"action,omitempty"`
+	Ef     int    `json:"ef,omitempty"`
+}
+
+// handleStartDedup starts a dedup job in the background and returns its id
+func (s *Server) handleStartDedup(c echo.Context) error {
+	var req DedupRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	opts := db.DefaultDedupOptions()
+	if req.Threshold != 0 {
+		opts.Threshold = req.Threshold
+	}
+	if req.Action != "" {
+		opts.Action = req.Action
+	}
+	if req.Ef != 0 {
+		opts.Ef = req.Ef
+	}
+
+	job := s.db.StartDedup(opts)
+	return c.JSON(http.StatusAccepted, job.Status())
+}
+
+// handleGetDedup reports the progress of a dedup job, and its report once
+// it is done
+func (s *Server) handleGetDedup(c echo.Context) error {
+	job, exists := s.db.DedupJob(c.Param("id"))
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Dedup job not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, job.Status())
+}
+
+// handleCancelDedup cancels a dedup job
+func (s *Server) handleCancelDedup(c echo.Context) error {
+	job, exists := s.db.DedupJob(c.Param("id"))
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Dedup job not found",
+		})
+	}
+
+	job.Cancel()
+	_, _ = job.Wait()
+	return c.JSON(http.StatusOK, job.Status())
+}
+
+// handleEmbeddingCacheStats reports embedding cache hits, misses and size
+func (s *Server) handleEmbeddingCacheStats(c echo.Context) error {
+	stats, enabled := s.db.EmbeddingCacheStats()
+	if !enabled {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Embedding cache is disabled",
+		})
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
 // handleGetConfig returns the current database configuration
 func (s *Server) handleGetConfig(c echo.Context) error {
 	return c.JSON(http.StatusOK, s.db.DBConfig)
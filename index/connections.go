@@ -2,12 +2,13 @@ package index
 
 import (
 	"container/heap"
+	"github.com/ahhcash/ghastlydb/search"
 	"math"
 )
 
 // distanceFunc represents a function that calculates distance between two vectors
 // nolint
-type distanceFunc func([]float64, []float64) float64
+type distanceFunc func([]float32, []float32) float64
 
 // neighborSet represents a priority queue of potential neighbors
 // nolint
@@ -51,6 +52,33 @@ func (pq *distQueue) Pop() interface{} {
 	return item
 }
 
+// farQueue is a max-heap that keeps the farthest of the current results on top
+type farQueue []*queueItem
+
+func (pq *farQueue) Len() int {
+	return len(*pq)
+}
+
+func (pq *farQueue) Less(i, j int) bool {
+	return (*pq)[i].distance > (*pq)[j].distance
+}
+
+func (pq *farQueue) Swap(i, j int) {
+	(*pq)[i], (*pq)[j] = (*pq)[j], (*pq)[i]
+}
+
+func (pq *farQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(*queueItem))
+}
+
+func (pq *farQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	*pq = old[0 : n-1]
+	return item
+}
+
 // selectNeighbors implements the Neighborhood Selection algorithm
 // It selects the best M neighbors from the candidate set
 func (h *HNSW) selectNeighbors(candidates []*queueItem, M int, keepPrunedConnections bool) []*queueItem {
@@ -68,6 +96,9 @@ func (h *HNSW) selectNeighbors(candidates []*queueItem, M int, keepPrunedConnect
 	// Create a map to track selected nodes for efficient lookup
 	selected := make(map[string]bool)
 
+	// vectors of the selected nodes, decoded once on a quantized graph
+	selectedVectors := make([][]float32, 0, M)
+
 	for len(result) < M && workingSet.Len() > 0 {
 		// Get the closest candidate
 		candidate := heap.Pop(&workingSet).(*queueItem)
@@ -85,8 +116,9 @@ func (h *HNSW) selectNeighbors(candidates []*queueItem, M int, keepPrunedConnect
 			// Heuristic: If this node would create too many closely connected components,
 			// skip it and try the next closest one
 			tooClose := false
-			for _, existing := range result[:len(result)-1] {
-				if h.isDistanceTooClose(candidate.node.vector, existing.node.vector) {
+			candidateVector := h.nodeVector(candidate.node)
+			for _, existing := range selectedVectors {
+				if h.isDistanceTooClose(candidateVector, existing) {
 					tooClose = true
 					break
 				}
@@ -94,6 +126,8 @@ func (h *HNSW) selectNeighbors(candidates []*queueItem, M int, keepPrunedConnect
 			if tooClose {
 				result = result[:len(result)-1]
 				selected[candidate.id] = false
+			} else {
+				selectedVectors = append(selectedVectors, candidateVector)
 			}
 		}
 	}
@@ -103,19 +137,8 @@ func (h *HNSW) selectNeighbors(candidates []*queueItem, M int, keepPrunedConnect
 
 // isDistanceTooClose checks if two vectors are too close to each other
 // This helps maintain diversity in connections
-func (h *HNSW) isDistanceTooClose(vec1, vec2 []float64) bool {
-	// Calculate cosine similarity between vectors
-	dotProduct := 0.0
-	norm1 := 0.0
-	norm2 := 0.0
-
-	for i := range vec1 {
-		dotProduct += vec1[i] * vec2[i]
-		norm1 += vec1[i] * vec1[i]
-		norm2 += vec2[i] * vec2[i]
-	}
-
-	similarity := dotProduct / (math.Sqrt(norm1) * math.Sqrt(norm2))
+func (h *HNSW) isDistanceTooClose(vec1, vec2 []float32) bool {
+	similarity := search.Cosine(vec1, vec2)
 
 	// Consider vectors too close if similarity is above threshold
 	// This threshold can be tuned based on your needs
@@ -124,6 +147,10 @@ func (h *HNSW) isDistanceTooClose(vec1, vec2 []float64) bool {
 
 // connectNodes establishes bidirectional connections between nodes
 func (h *HNSW) connectNodes(node1 *node, node2 *node, level int) {
+	if node1 == node2 {
+		return
+	}
+
 	// Acquire locks for both nodes to prevent deadlocks
 	if node1.id < node2.id {
 		node1.lock.Lock()
@@ -136,64 +163,50 @@ func (h *HNSW) connectNodes(node1 *node, node2 *node, level int) {
 	defer node1.lock.Unlock()
 	defer node2.lock.Unlock()
 
-	// Add bidirectional connections
-	node1.neighbors[level] = append(node1.neighbors[level], node2.id)
-	node2.neighbors[level] = append(node2.neighbors[level], node1.id)
+	// Add bidirectional connections, once
+	if !containsString(node1.neighbors[level], node2.id) {
+		node1.neighbors[level] = append(node1.neighbors[level], node2.id)
+	}
+	if !containsString(node2.neighbors[level], node1.id) {
+		node2.neighbors[level] = append(node2.neighbors[level], node1.id)
+	}
 
 	// Ensure we don't exceed maximum connections at this level
 	if len(node1.neighbors[level]) > h.config.M {
-		// Select best M neighbors
-		candidates := make([]*queueItem, 0, len(node1.neighbors[level]))
-		for _, neighborID := range node1.neighbors[level] {
-			neighbor := h.nodes[neighborID]
-			dist := h.distanceToNode(node1.vector, neighbor.vector)
-			candidates = append(candidates, &queueItem{
-				node:     neighbor,
-				distance: dist,
-				id:       neighborID,
-			})
-		}
-
-		selected := h.selectNeighbors(candidates, h.config.M, false)
-
-		// Update connections
-		newNeighbors := make([]string, len(selected))
-		for i, item := range selected {
-			newNeighbors[i] = item.id
-		}
-		node1.neighbors[level] = newNeighbors
+		node1.neighbors[level] = h.bestNeighbors(node1, node1.neighbors[level], level)
 	}
-
-	// Do the same for node2
 	if len(node2.neighbors[level]) > h.config.M {
-		candidates := make([]*queueItem, 0, len(node2.neighbors[level]))
-		for _, neighborID := range node2.neighbors[level] {
-			neighbor := h.nodes[neighborID]
-			dist := h.distanceToNode(node2.vector, neighbor.vector)
-			candidates = append(candidates, &queueItem{
-				node:     neighbor,
-				distance: dist,
-				id:       neighborID,
-			})
-		}
-
-		selected := h.selectNeighbors(candidates, h.config.M, false)
+		node2.neighbors[level] = h.bestNeighbors(node2, node2.neighbors[level], level)
+	}
+}
 
-		newNeighbors := make([]string, len(selected))
-		for i, item := range selected {
-			newNeighbors[i] = item.id
+// bestNeighbors selects the best M of the ids for n's neighbors at level,
+// skipping n itself and ids missing from the graph
+func (h *HNSW) bestNeighbors(n *node, ids []string, level int) []string {
+	from := h.nodePoint(n)
+	candidates := make([]*queueItem, 0, len(ids))
+	for _, neighborID := range ids {
+		neighbor, exists := h.neighbor(neighborID, level)
+		if !exists || neighbor == n {
+			continue
 		}
-		node2.neighbors[level] = newNeighbors
+		candidates = append(candidates, &queueItem{
+			node:     neighbor,
+			distance: h.distance(from, neighbor),
+			id:       neighborID,
+		})
+	}
+	selected := h.selectNeighbors(candidates, h.config.M, h.config.KeepCloseNeighbors)
+
+	newNeighbors := make([]string, len(selected), h.config.M)
+	for i, item := range selected {
+		newNeighbors[i] = item.id
 	}
+	return newNeighbors
 }
 
 // distanceToNode calculates the distance between two vectors
 // Currently using Euclidean distance, but this could be made configurable
-func (h *HNSW) distanceToNode(vec1, vec2 []float64) float64 {
-	sum := 0.0
-	for i := range vec1 {
-		diff := vec1[i] - vec2[i]
-		sum += diff * diff
-	}
-	return math.Sqrt(sum)
+func (h *HNSW) distanceToNode(vec1, vec2 []float32) float64 {
+	return math.Sqrt(float64(search.SquaredL2Float32(vec1, vec2)))
 }
@@ -0,0 +1,396 @@
+package index
+
+import (
+	"fmt"
+)
+
+// Delete marks the node with the given ID deleted. Searches stop returning
+// it right away but keep routing through it, so deleting can't cut the
+// graph apart; Repair unlinks deleted nodes, and runs by itself once
+// RepairAfter of them have piled up.
+func (h *HNSW) Delete(id string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	node, exists := h.nodes[id]
+	if !exists || node.deleted {
+		return fmt.Errorf("node with id %s does not exist", id)
+	}
+	node.deleted = true
+	h.deleted++
+
+	if h.config.RepairAfter > 0 && h.deleted >= h.config.RepairAfter {
+		h.repair()
+	}
+
+	return nil
+}
+
+// Len is how many nodes searches can return
+func (h *HNSW) Len() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	h.nodesLock.RLock()
+	defer h.nodesLock.RUnlock()
+
+	return len(h.nodes) - h.deleted
+}
+
+// Deleted is how many deleted nodes still wait for Repair
+func (h *HNSW) Deleted() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.deleted
+}
+
+// Repair unlinks every deleted node. Each node that linked to one picks
+// its neighbors again from its remaining ones and those of the deleted
+// nodes it linked to, so the neighborhoods around deletions stay
+// connected.
+func (h *HNSW) Repair() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.repair()
+}
+
+// repair is Repair for callers holding the lock
+func (h *HNSW) repair() {
+	for _, n := range h.nodes {
+		if n.deleted {
+			continue
+		}
+		for level := range n.neighbors {
+			if h.linksDeleted(n.neighbors[level], level) {
+				h.reconnect(n, level)
+			}
+		}
+	}
+
+	for id, n := range h.nodes {
+		if n.deleted {
+			delete(h.nodes, id)
+		}
+	}
+	h.deleted = 0
+	h.resetEntryPoint()
+	h.reattach()
+}
+
+// maxReattachRounds bounds how often reattach looks for stranded nodes
+// again after linking some back
+const maxReattachRounds = 8
+
+// reattach links live nodes that searches can't reach, usually because
+// pruning dropped every link to them, back into the graph. Each one is
+// searched for like a new node, and the closest node found links to it,
+// in place of a neighbor that other reached nodes also link to when its
+// neighbors are full.
+func (h *HNSW) reattach() {
+	for round := 0; round < maxReattachRounds && len(h.nodes) > 0; round++ {
+		reached := h.reachable()
+		inLinks := make(map[string]int)
+		for id, n := range h.nodes {
+			if reached[id] {
+				for _, neighborID := range n.neighbors[0] {
+					inLinks[neighborID]++
+				}
+			}
+		}
+
+		stranded := 0
+		for id, n := range h.nodes {
+			if reached[id] || n.deleted {
+				continue
+			}
+			stranded++
+			for _, candidate := range h.nearest(h.nodePoint(n), 0, h.config.EfConstruction) {
+				if candidate.node != n && h.linkTo(candidate.node, n, inLinks) {
+					break
+				}
+			}
+		}
+		if stranded == 0 {
+			return
+		}
+	}
+}
+
+// linkTo adds a layer 0 link from n to target, replacing the neighbor of n
+// with the most links to it when n has M already. It reports false when
+// every neighbor of n depends on its link.
+func (h *HNSW) linkTo(n *node, target *node, inLinks map[string]int) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	neighbors := n.neighbors[0]
+	if containsString(neighbors, target.id) {
+		return false
+	}
+	if len(neighbors) < h.config.M {
+		n.neighbors[0] = append(neighbors, target.id)
+		inLinks[target.id]++
+		return true
+	}
+
+	replace := -1
+	for i, neighborID := range neighbors {
+		if inLinks[neighborID] >= 2 && (replace < 0 || inLinks[neighborID] > inLinks[neighbors[replace]]) {
+			replace = i
+		}
+	}
+	if replace < 0 {
+		return false
+	}
+	inLinks[neighbors[replace]]--
+	inLinks[target.id]++
+	neighbors[replace] = target.id
+	return true
+}
+
+// nearest walks down from the entry point and returns the ef nodes closest
+// to query it finds on level
+func (h *HNSW) nearest(query point, level int, ef int) []*queueItem {
+	entryNode := h.nodes[h.entryPoint]
+	currNode := entryNode
+	currDist := h.distance(query, entryNode)
+	for lc := entryNode.maxLevel; lc > level; lc-- {
+		currNode, currDist = h.searchAtLayer(query, currNode, currDist, lc)
+	}
+	return h.searchLayer(query, currNode, level, ef, false)
+}
+
+// reachable returns the nodes a search can get to: it walks every level
+// from the nodes reached on the level above, starting at the entry point
+func (h *HNSW) reachable() map[string]bool {
+	entryNode, exists := h.nodes[h.entryPoint]
+	if !exists {
+		return map[string]bool{}
+	}
+
+	reached := map[string]bool{h.entryPoint: true}
+	frontier := []string{h.entryPoint}
+	for level := entryNode.maxLevel; level >= 0; level-- {
+		// everything reached above is on this level too
+		queue := append([]string(nil), frontier...)
+		for len(queue) > 0 {
+			current := h.nodes[queue[0]]
+			queue = queue[1:]
+			for _, neighborID := range current.neighbors[level] {
+				if _, exists := h.neighbor(neighborID, level); exists && !reached[neighborID] {
+					reached[neighborID] = true
+					frontier = append(frontier, neighborID)
+					queue = append(queue, neighborID)
+				}
+			}
+		}
+	}
+	return reached
+}
+
+// remove unlinks n at once, reconnecting the neighbors it links to. Nodes
+// linking to n without n linking back keep their link, which searches
+// skip, until a node takes n's id or the next repair drops it.
+func (h *HNSW) remove(n *node) {
+	if !n.deleted {
+		n.deleted = true
+		h.deleted++
+	}
+	for level := range n.neighbors {
+		for _, neighborID := range n.neighbors[level] {
+			neighbor, exists := h.neighbor(neighborID, level)
+			if exists && !neighbor.deleted && containsString(neighbor.neighbors[level], n.id) {
+				h.reconnect(neighbor, level)
+			}
+		}
+	}
+
+	delete(h.nodes, n.id)
+	h.deleted--
+	h.resetEntryPoint()
+}
+
+// linksDeleted reports whether any of the ids linked on level is deleted
+// or missing
+func (h *HNSW) linksDeleted(ids []string, level int) bool {
+	for _, id := range ids {
+		if neighbor, exists := h.neighbor(id, level); !exists || neighbor.deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// reconnect replaces the deleted and missing neighbors of n at level: the
+// candidates are its live neighbors and the live neighbors of the deleted
+// ones
+func (h *HNSW) reconnect(n *node, level int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	candidates := make([]string, 0, 2*h.config.M)
+	seen := map[string]bool{n.id: true}
+	add := func(id string) {
+		if neighbor, exists := h.neighbor(id, level); exists && !neighbor.deleted && !seen[id] {
+			seen[id] = true
+			candidates = append(candidates, id)
+		}
+	}
+	for _, id := range n.neighbors[level] {
+		neighbor, exists := h.neighbor(id, level)
+		if !exists {
+			continue
+		}
+		if !neighbor.deleted {
+			add(id)
+			continue
+		}
+		for _, second := range neighbor.neighbors[level] {
+			add(second)
+		}
+	}
+
+	n.neighbors[level] = h.bestNeighbors(n, candidates, level)
+}
+
+// resetEntryPoint picks a new entry point when the current one has left
+// the graph: a node on the highest level, live if there is one there
+func (h *HNSW) resetEntryPoint() {
+	if _, exists := h.nodes[h.entryPoint]; exists {
+		return
+	}
+
+	h.entryPoint = ""
+	var best *node
+	for id, n := range h.nodes {
+		if best == nil || n.maxLevel > best.maxLevel || (n.maxLevel == best.maxLevel && best.deleted && !n.deleted) {
+			best = n
+			h.entryPoint = id
+		}
+	}
+}
+
+// Consolidate repairs the graph, then rebuilds every neighborhood: each
+// node searches the graph for its nearest nodes as if it were inserted
+// again and keeps the best of those and its current neighbors, linking
+// back from the ones it picks. It costs about as much as building the
+// graph anew, which after many deletions it comes close to in quality.
+func (h *HNSW) Consolidate() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.repair()
+	if len(h.nodes) == 0 {
+		return
+	}
+
+	for _, n := range h.nodes {
+		query := h.nodePoint(n)
+		entryNode := h.nodes[h.entryPoint]
+		currNode := entryNode
+		currDist := h.distance(query, entryNode)
+		for lc := entryNode.maxLevel; lc > n.maxLevel; lc-- {
+			currNode, currDist = h.searchAtLayer(query, currNode, currDist, lc)
+		}
+
+		top := n.maxLevel
+		if entryNode.maxLevel < top {
+			top = entryNode.maxLevel
+		}
+		for lc := top; lc >= 0; lc-- {
+			found := h.searchLayer(query, currNode, lc, h.config.EfConstruction, false)
+			ids := make([]string, 0, len(found)+len(n.neighbors[lc]))
+			ids = append(ids, n.neighbors[lc]...)
+			for _, item := range found {
+				if !containsString(ids, item.id) {
+					ids = append(ids, item.id)
+				}
+			}
+
+			n.lock.Lock()
+			n.neighbors[lc] = h.bestNeighbors(n, ids, lc)
+			neighbors := n.neighbors[lc]
+			n.lock.Unlock()
+
+			for _, neighborID := range neighbors {
+				neighbor := h.nodes[neighborID]
+				neighbor.lock.Lock()
+				if !containsString(neighbor.neighbors[lc], n.id) {
+					neighbor.neighbors[lc] = append(neighbor.neighbors[lc], n.id)
+					if len(neighbor.neighbors[lc]) > h.config.M {
+						neighbor.neighbors[lc] = h.bestNeighbors(neighbor, neighbor.neighbors[lc], lc)
+					}
+				}
+				neighbor.lock.Unlock()
+			}
+
+			currNode = found[0].node
+		}
+	}
+
+	// pruning the links back can strand nodes again
+	h.reattach()
+}
+
+// CheckConnectivity reports the first fault it finds in the graph: a link
+// to a node that isn't in it, a node linking to itself, twice to the same
+// node or to more than M nodes on a level, or a live node that searches
+// can't reach from the entry point. Deleted nodes count as part of the
+// graph until Repair unlinks them. Inserts wait for the check.
+func (h *HNSW) CheckConnectivity() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.nodes) == 0 {
+		return nil
+	}
+	if _, exists := h.nodes[h.entryPoint]; !exists {
+		return fmt.Errorf("entry point %s is not in the graph", h.entryPoint)
+	}
+
+	for id, n := range h.nodes {
+		for level, neighbors := range n.neighbors {
+			if len(neighbors) > h.config.M {
+				return fmt.Errorf("node %s has %d neighbors on level %d, more than %d", id, len(neighbors), level, h.config.M)
+			}
+			seen := make(map[string]bool, len(neighbors))
+			for _, neighborID := range neighbors {
+				neighbor, exists := h.nodes[neighborID]
+				switch {
+				case !exists:
+					return fmt.Errorf("node %s links to missing node %q on level %d", id, neighborID, level)
+				case neighborID == id:
+					return fmt.Errorf("node %s links to itself on level %d", id, level)
+				case seen[neighborID]:
+					return fmt.Errorf("node %s links to %s twice on level %d", id, neighborID, level)
+				case neighbor.maxLevel < level:
+					return fmt.Errorf("node %s links to %s on level %d above its top level %d", id, neighborID, level, neighbor.maxLevel)
+				}
+				seen[neighborID] = true
+			}
+		}
+	}
+
+	reached := h.reachable()
+	unreachable := 0
+	for id, n := range h.nodes {
+		if !n.deleted && !reached[id] {
+			unreachable++
+		}
+	}
+	if unreachable > 0 {
+		return fmt.Errorf("%d of %d nodes can't be reached from entry point %s", unreachable, len(h.nodes)-h.deleted, h.entryPoint)
+	}
+
+	return nil
+}
+
+func containsString(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
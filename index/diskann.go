@@ -0,0 +1,504 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+)
+
+type DiskANNConfig struct {
+	// Degree caps the out-edges of every node (R)
+	Degree int
+
+	// BuildList is how many candidates the searches that build the graph
+	// keep (L)
+	BuildList int
+
+	// Alpha above 1 keeps longer edges when pruning, which shortens
+	// searches at the cost of denser neighborhoods
+	Alpha float64
+
+	// SearchList is how many candidates a search keeps by default
+	SearchList int
+
+	// BeamWidth is how many nodes a search reads from disk at every step
+	BeamWidth int
+
+	// PQ configures the codes kept in memory to steer searches. Subspaces
+	// is capped at the vectors' dimensions.
+	PQ PQConfig
+
+	// ShardSize caps how many vectors a build holds in memory at once.
+	// Larger builds are split into overlapping shards that are built one
+	// at a time and merged, which needs about ShardSize × (4 × dimensions
+	// + 4 × Degree) bytes for the shard, 8 × Degree bytes per vector for
+	// the merged edges and the PQ codes. Zero builds over every vector in
+	// memory at once.
+	ShardSize int
+
+	Seed int64
+}
+
+func DefaultDiskANNConfig() DiskANNConfig {
+	return DiskANNConfig{
+		Degree:     64,
+		BuildList:  100,
+		Alpha:      1.2,
+		SearchList: 100,
+		BeamWidth:  4,
+		PQ:         DefaultPQConfig(),
+		ShardSize:  1 << 20,
+		Seed:       42,
+	}
+}
+
+// diskBlockSize is the page size node blocks are aligned to
+const diskBlockSize = 4096
+
+// diskANNMagic starts every disk index, followed by a format version. The
+// rest of the header block holds the layout of the node blocks after it.
+var diskANNMagic = [4]byte{'G', 'D', 'A', 'N'}
+
+const diskANNVersion uint32 = 1
+
+// DiskANN is a Vamana graph kept on disk. Every node's full vector and
+// out-edges are stored together, packed into page aligned blocks so reading
+// a node takes one read. Only the ids and product quantization codes of the
+// vectors stay in memory: searches steer by the codes, read the nodes they
+// expand from disk, and rank the results by the full vectors read on the
+// way.
+type DiskANN struct {
+	file   *os.File
+	config DiskANNConfig
+
+	dim    int
+	degree int
+	medoid uint32
+
+	// nodeSize is the bytes one node takes, perBlock how many nodes share
+	// a block of blockSize bytes
+	nodeSize  int
+	perBlock  int
+	blockSize int
+
+	ids   []string
+	pq    *PQ
+	codes []byte
+}
+
+// diskNode is a node read back from disk
+type diskNode struct {
+	id        uint32
+	vector    []float32
+	neighbors []uint32
+}
+
+// diskLayout works out how nodes of dim dimensions and degree out-edges
+// are packed into blocks: several to a block when they fit, otherwise one
+// node spread over as many whole blocks as it needs
+func diskLayout(dim, degree int) (nodeSize, perBlock, blockSize int) {
+	nodeSize = 4*dim + 4 + 4*degree
+	if nodeSize <= diskBlockSize {
+		return nodeSize, diskBlockSize / nodeSize, diskBlockSize
+	}
+	return nodeSize, 1, (nodeSize + diskBlockSize - 1) / diskBlockSize * diskBlockSize
+}
+
+// BuildDiskANN builds the graph over vectors, trains the codes on them and
+// writes the index to path
+func BuildDiskANN(path string, ids []string, vectors [][]float32, config DiskANNConfig) (*DiskANN, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("cannot build a disk index without vectors")
+	}
+	dim := len(vectors[0])
+	for _, vector := range vectors {
+		if len(vector) != dim {
+			return nil, fmt.Errorf("vectors have %d and %d dimensions", dim, len(vector))
+		}
+	}
+	return buildDiskANN(path, ids, memoryVectors(vectors), config)
+}
+
+// BuildDiskANNFromFile is BuildDiskANN over the vectors of a vector file,
+// of which it holds at most config.ShardSize in memory at once
+func BuildDiskANNFromFile(path string, ids []string, vectors *VectorFile, config DiskANNConfig) (*DiskANN, error) {
+	if vectors.Len() == 0 {
+		return nil, fmt.Errorf("cannot build a disk index without vectors")
+	}
+	return buildDiskANN(path, ids, vectors, config)
+}
+
+func buildDiskANN(path string, ids []string, source vectorSource, config DiskANNConfig) (*DiskANN, error) {
+	if len(ids) != source.Len() {
+		return nil, fmt.Errorf("got %d ids for %d vectors", len(ids), source.Len())
+	}
+	if config.Degree <= 0 || config.BuildList <= 0 {
+		return nil, fmt.Errorf("disk index needs a positive degree and build list")
+	}
+	sharded := config.ShardSize > 0 && source.Len() > config.ShardSize
+	if sharded && config.ShardSize <= config.Degree {
+		return nil, fmt.Errorf("disk index shards of %d vectors are too small for %d out-edges per node", config.ShardSize, config.Degree)
+	}
+
+	// a sharded build trains on a sample no larger than a shard, which
+	// also splits the vectors into shards
+	var training [][]float32
+	var err error
+	if sharded {
+		training, err = sampleVectors(source, config.ShardSize, rand.New(rand.NewSource(config.Seed)))
+	} else {
+		training, err = loadVectors(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pqConfig := config.PQ
+	if pqConfig.Subspaces > source.Dims() {
+		pqConfig.Subspaces = source.Dims()
+	}
+	pq, err := TrainPQ(training, pqConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not train disk index codes: %v", err)
+	}
+	codes := make([]byte, 0, source.Len()*pq.Subspaces())
+	for i := 0; i < source.Len(); i++ {
+		vector, err := source.Vector(i)
+		if err != nil {
+			return nil, err
+		}
+		code, err := pq.Encode(vector)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code...)
+	}
+
+	var neighbors [][]uint32
+	var start uint32
+	if sharded {
+		neighbors, start, err = buildShardedVamana(source, training, config)
+		if err != nil {
+			return nil, fmt.Errorf("could not build disk index shards: %v", err)
+		}
+	} else {
+		graph := buildVamana(training, config)
+		neighbors, start = graph.neighbors, graph.medoid
+	}
+	if err := writeDiskANN(path, ids, source, neighbors, start, config.Degree, pq, codes); err != nil {
+		return nil, err
+	}
+
+	return OpenDiskANN(path, config)
+}
+
+// writeDiskANN lays the header block, the node blocks and then the ids and
+// codes out in a temp file that replaces path once complete
+func writeDiskANN(path string, ids []string, source vectorSource, neighbors [][]uint32, start uint32, degree int, pq *PQ, codes []byte) error {
+	dim := source.Dims()
+	nodeSize, perBlock, blockSize := diskLayout(dim, degree)
+	blocks := (source.Len() + perBlock - 1) / perBlock
+
+	tempPath := path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %v", tempPath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	w := bufio.NewWriter(file)
+	write := func(data any) {
+		if err == nil {
+			err = binary.Write(w, binary.LittleEndian, data)
+		}
+	}
+
+	header := make([]byte, 0, diskBlockSize)
+	header = append(header, diskANNMagic[:]...)
+	for _, field := range []uint32{diskANNVersion, uint32(dim), uint32(degree), uint32(len(ids)), start} {
+		header = binary.LittleEndian.AppendUint32(header, field)
+	}
+	write(header[:diskBlockSize])
+
+	block := make([]byte, blockSize)
+	for b := 0; b < blocks && err == nil; b++ {
+		clear(block)
+		for slot := 0; slot < perBlock; slot++ {
+			i := b*perBlock + slot
+			if i >= source.Len() {
+				break
+			}
+			var vector []float32
+			if vector, err = source.Vector(i); err != nil {
+				break
+			}
+			buf := block[slot*nodeSize : slot*nodeSize]
+			for _, value := range vector {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(value))
+			}
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(len(neighbors[i])))
+			for _, neighbor := range neighbors[i] {
+				buf = binary.LittleEndian.AppendUint32(buf, neighbor)
+			}
+		}
+		write(block)
+	}
+
+	for _, id := range ids {
+		write(uint32(len(id)))
+		write([]byte(id))
+	}
+	if err == nil {
+		err = pq.writeTo(w)
+	}
+	write(codes)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("could not write disk index: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("could not rename temp file to disk index: %v", err)
+	}
+
+	return nil
+}
+
+// OpenDiskANN loads the ids and codes of the index at path, keeping the
+// file open to read nodes from. config supplies the search settings.
+func OpenDiskANN(path string, config DiskANNConfig) (*DiskANN, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open disk index %s: %v", path, err)
+	}
+	d, err := readDiskANN(file, config)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("could not read disk index %s: %v", path, err)
+	}
+	return d, nil
+}
+
+func readDiskANN(file *os.File, config DiskANNConfig) (*DiskANN, error) {
+	header := make([]byte, diskBlockSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if [4]byte(header[:4]) != diskANNMagic {
+		return nil, fmt.Errorf("not a disk index")
+	}
+	field := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(header[4+4*i:])
+	}
+	if version := field(0); version == 0 || version > diskANNVersion {
+		return nil, fmt.Errorf("unsupported disk index version %d", version)
+	}
+
+	d := &DiskANN{
+		file:   file,
+		config: config,
+		dim:    int(field(1)),
+		degree: int(field(2)),
+		ids:    make([]string, field(3)),
+		medoid: field(4),
+	}
+	d.nodeSize, d.perBlock, d.blockSize = diskLayout(d.dim, d.degree)
+	blocks := (len(d.ids) + d.perBlock - 1) / d.perBlock
+	if _, err := file.Seek(int64(diskBlockSize+blocks*d.blockSize), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var err error
+	r := bufio.NewReader(file)
+	read := func(data any) {
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, data)
+		}
+	}
+	for i := range d.ids {
+		var idLen uint32
+		read(&idLen)
+		id := make([]byte, idLen)
+		read(id)
+		d.ids[i] = string(id)
+	}
+	if err == nil {
+		d.pq, err = readPQ(r, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.codes = make([]byte, len(d.ids)*d.pq.Subspaces())
+	read(d.codes)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Len is the number of vectors in the index
+func (d *DiskANN) Len() int {
+	return len(d.ids)
+}
+
+// Dims is the length of the indexed vectors
+func (d *DiskANN) Dims() int {
+	return d.dim
+}
+
+// Close releases the index file
+func (d *DiskANN) Close() error {
+	return d.file.Close()
+}
+
+// Search finds the k nearest neighbors (Euclidean distance) of query with
+// the configured search list and beam width
+func (d *DiskANN) Search(query []float32, k int) (SearchResults, error) {
+	return d.SearchWithBeam(query, k, d.config.SearchList, d.config.BeamWidth)
+}
+
+// SearchWithBeam is Search keeping list candidates, ordered by their codes,
+// and reading the beam closest unexpanded ones from disk at every step. A
+// longer list finds more of the true neighbors, a wider beam takes fewer,
+// larger rounds of reads.
+func (d *DiskANN) SearchWithBeam(query []float32, k, list, beam int) (SearchResults, error) {
+	if len(query) != d.dim {
+		return nil, fmt.Errorf("cannot search a %d dimensional index with a %d dimensional query", d.dim, len(query))
+	}
+	if list < k {
+		list = k
+	}
+	if beam < 1 {
+		beam = 1
+	}
+
+	table, err := d.pq.Table(query, "l2")
+	if err != nil {
+		return nil, err
+	}
+	subspaces := d.pq.Subspaces()
+	approximate := func(id uint32) float64 {
+		return table.Score(d.codes[int(id)*subspaces : int(id+1)*subspaces])
+	}
+
+	seen := map[uint32]bool{d.medoid: true}
+	candidates := []candidate{{id: d.medoid, distance: approximate(d.medoid)}}
+	results := make(SearchResults, 0, list)
+	for {
+		frontier := make([]uint32, 0, beam)
+		for i := range candidates {
+			if len(frontier) == beam {
+				break
+			}
+			if !candidates[i].expanded {
+				candidates[i].expanded = true
+				frontier = append(frontier, candidates[i].id)
+			}
+		}
+		if len(frontier) == 0 {
+			break
+		}
+
+		nodes, err := d.readNodes(frontier)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			results = append(results, SearchResult{
+				ID:       d.ids[n.id],
+				Distance: math.Sqrt(squaredL2(query, n.vector)),
+				Vector:   n.vector,
+			})
+			for _, neighbor := range n.neighbors {
+				if seen[neighbor] {
+					continue
+				}
+				seen[neighbor] = true
+				candidates = insertCandidate(candidates, candidate{id: neighbor, distance: approximate(neighbor)}, list)
+			}
+		}
+	}
+
+	sort.Sort(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// readNodes reads the blocks holding ids, all at once, and decodes the
+// nodes from them
+func (d *DiskANN) readNodes(ids []uint32) ([]diskNode, error) {
+	byBlock := make(map[int][]uint32)
+	order := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if int(id) >= len(d.ids) {
+			return nil, fmt.Errorf("disk index has no node %d", id)
+		}
+		b := int(id) / d.perBlock
+		if _, exists := byBlock[b]; !exists {
+			order = append(order, b)
+		}
+		byBlock[b] = append(byBlock[b], id)
+	}
+
+	blocks := make([][]byte, len(order))
+	errs := make([]error, len(order))
+	var wg sync.WaitGroup
+	for i, b := range order {
+		wg.Add(1)
+		go func(i, b int) {
+			defer wg.Done()
+			blocks[i] = make([]byte, d.blockSize)
+			_, errs[i] = d.file.ReadAt(blocks[i], int64(diskBlockSize+b*d.blockSize))
+		}(i, b)
+	}
+	wg.Wait()
+
+	nodes := make([]diskNode, 0, len(ids))
+	for i, b := range order {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("could not read disk index block %d: %v", b, errs[i])
+		}
+		for _, id := range byBlock[b] {
+			n, err := d.decodeNode(id, blocks[i][(int(id)%d.perBlock)*d.nodeSize:])
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+func (d *DiskANN) decodeNode(id uint32, buf []byte) (diskNode, error) {
+	n := diskNode{id: id, vector: make([]float32, d.dim)}
+	for i := range n.vector {
+		n.vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	buf = buf[4*d.dim:]
+	count := int(binary.LittleEndian.Uint32(buf))
+	if count > d.degree {
+		return diskNode{}, fmt.Errorf("disk index node %d has %d neighbors, more than %d", id, count, d.degree)
+	}
+	n.neighbors = make([]uint32, count)
+	for i := range n.neighbors {
+		n.neighbors[i] = binary.LittleEndian.Uint32(buf[4+4*i:])
+		if int(n.neighbors[i]) >= len(d.ids) {
+			return diskNode{}, fmt.Errorf("disk index node %d links to missing node %d", id, n.neighbors[i])
+		}
+	}
+	return n, nil
+}
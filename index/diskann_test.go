@@ -0,0 +1,230 @@
+package index
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type DiskANNTestSuite struct {
+	suite.Suite
+	ids     []string
+	vectors [][]float32
+	config  DiskANNConfig
+	path    string
+}
+
+func (s *DiskANNTestSuite) SetupTest() {
+	rng := rand.New(rand.NewSource(11))
+	s.ids = make([]string, 1000)
+	s.vectors = make([][]float32, len(s.ids))
+	for i := range s.vectors {
+		s.ids[i] = fmt.Sprintf("v%d", i)
+		s.vectors[i] = make([]float32, 32)
+		for d := range s.vectors[i] {
+			s.vectors[i][d] = float32(rng.NormFloat64())
+		}
+	}
+
+	s.config = DefaultDiskANNConfig()
+	s.config.Degree = 24
+	s.config.BuildList = 50
+	s.config.SearchList = 50
+	s.config.PQ.Subspaces = 8
+	s.path = filepath.Join(s.T().TempDir(), "vectors.diskann")
+}
+
+func (s *DiskANNTestSuite) exact(query []float32, k int) []string {
+	order := make([]int, len(s.vectors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return squaredL2(s.vectors[order[i]], query) < squaredL2(s.vectors[order[j]], query)
+	})
+	ids := make([]string, k)
+	for i := range ids {
+		ids[i] = s.ids[order[i]]
+	}
+	return ids
+}
+
+func (s *DiskANNTestSuite) TestBuildAndSearch() {
+	d, err := BuildDiskANN(s.path, s.ids, s.vectors, s.config)
+	require.NoError(s.T(), err)
+	defer func() { _ = d.Close() }()
+	assert.Equal(s.T(), 1000, d.Len())
+	assert.Equal(s.T(), 32, d.Dims())
+
+	// node blocks are page aligned after a header block
+	info, err := os.Stat(s.path)
+	require.NoError(s.T(), err)
+	nodeSize, perBlock, blockSize := diskLayout(32, 24)
+	assert.Equal(s.T(), 4*32+4+4*24, nodeSize)
+	assert.Equal(s.T(), diskBlockSize, blockSize)
+	assert.Greater(s.T(), info.Size(), int64(diskBlockSize+(1000/perBlock)*blockSize))
+
+	found, total := 0, 0
+	for q := 0; q < 50; q++ {
+		query := s.vectors[q*17]
+		results, err := d.Search(query, 10)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), results, 10)
+		// distances come from the full vectors read off disk
+		assert.InDelta(s.T(), math.Sqrt(squaredL2(query, results[0].Vector)), results[0].Distance, 1e-9)
+		assert.True(s.T(), sort.IsSorted(results))
+
+		for _, id := range s.exact(query, 10) {
+			total++
+			for _, result := range results {
+				if result.ID == id {
+					found++
+					break
+				}
+			}
+		}
+	}
+	assert.GreaterOrEqual(s.T(), float64(found)/float64(total), 0.9)
+
+	_, err = d.Search([]float32{1, 2}, 10)
+	assert.Error(s.T(), err)
+}
+
+func (s *DiskANNTestSuite) TestOpen() {
+	built, err := BuildDiskANN(s.path, s.ids, s.vectors, s.config)
+	require.NoError(s.T(), err)
+	defer func() { _ = built.Close() }()
+
+	opened, err := OpenDiskANN(s.path, s.config)
+	require.NoError(s.T(), err)
+	defer func() { _ = opened.Close() }()
+	assert.Equal(s.T(), built.ids, opened.ids)
+	assert.Equal(s.T(), built.codes, opened.codes)
+	assert.Equal(s.T(), built.medoid, opened.medoid)
+
+	query := s.vectors[3]
+	want, err := built.SearchWithBeam(query, 5, 40, 2)
+	require.NoError(s.T(), err)
+	got, err := opened.SearchWithBeam(query, 5, 40, 2)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), want, got)
+
+	require.NoError(s.T(), os.WriteFile(s.path, []byte("not an index"), 0644))
+	_, err = OpenDiskANN(s.path, s.config)
+	assert.Error(s.T(), err)
+}
+
+func (s *DiskANNTestSuite) TestLargeNodes() {
+	// nodes bigger than a block take whole blocks of their own
+	rng := rand.New(rand.NewSource(5))
+	vectors := make([][]float32, 50)
+	for i := range vectors {
+		vectors[i] = make([]float32, 1100)
+		for d := range vectors[i] {
+			vectors[i][d] = float32(rng.NormFloat64())
+		}
+	}
+	_, perBlock, blockSize := diskLayout(1100, s.config.Degree)
+	assert.Equal(s.T(), 1, perBlock)
+	assert.Equal(s.T(), 2*diskBlockSize, blockSize)
+
+	d, err := BuildDiskANN(s.path, s.ids[:50], vectors, s.config)
+	require.NoError(s.T(), err)
+	defer func() { _ = d.Close() }()
+	results, err := d.Search(vectors[7], 1)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "v7", results[0].ID)
+}
+
+func (s *DiskANNTestSuite) TestShardedBuild() {
+	// vectors streamed from a file, with room for a third of them in a
+	// shard, still make one graph the medoid reaches nearly every node
+	// from, as a build in memory does
+	file, err := CreateVectorFile(filepath.Join(s.T().TempDir(), "vectors"), 32)
+	require.NoError(s.T(), err)
+	defer func() { _ = file.Remove() }()
+	for _, vector := range s.vectors {
+		require.NoError(s.T(), file.Append(vector))
+	}
+	assert.Error(s.T(), file.Append([]float32{1, 2}))
+	read, err := file.Vector(17)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), s.vectors[17], read)
+
+	s.config.ShardSize = 300
+	d, err := BuildDiskANNFromFile(s.path, s.ids, file, s.config)
+	require.NoError(s.T(), err)
+	defer func() { _ = d.Close() }()
+	assert.Equal(s.T(), 1000, d.Len())
+
+	reached := map[uint32]bool{d.medoid: true}
+	frontier := []uint32{d.medoid}
+	for len(frontier) > 0 {
+		nodes, err := d.readNodes(frontier)
+		require.NoError(s.T(), err)
+		frontier = frontier[:0]
+		for _, n := range nodes {
+			assert.LessOrEqual(s.T(), len(n.neighbors), s.config.Degree)
+			for _, neighbor := range n.neighbors {
+				if !reached[neighbor] {
+					reached[neighbor] = true
+					frontier = append(frontier, neighbor)
+				}
+			}
+		}
+	}
+	assert.GreaterOrEqual(s.T(), len(reached), 990)
+
+	found, total := 0, 0
+	for q := 0; q < 50; q++ {
+		query := s.vectors[q*17]
+		results, err := d.Search(query, 10)
+		require.NoError(s.T(), err)
+		for _, id := range s.exact(query, 10) {
+			total++
+			for _, result := range results {
+				if result.ID == id {
+					found++
+					break
+				}
+			}
+		}
+	}
+	assert.GreaterOrEqual(s.T(), float64(found)/float64(total), 0.9)
+
+	// shards must hold more vectors than a node has out-edges
+	s.config.ShardSize = s.config.Degree
+	_, err = BuildDiskANNFromFile(s.path, s.ids, file, s.config)
+	assert.Error(s.T(), err)
+}
+
+func (s *DiskANNTestSuite) TestInvalidBuild() {
+	_, err := BuildDiskANN(s.path, nil, nil, s.config)
+	assert.Error(s.T(), err)
+	_, err = BuildDiskANN(s.path, s.ids[:1], s.vectors[:2], s.config)
+	assert.Error(s.T(), err)
+	_, err = BuildDiskANN(s.path, s.ids[:2], [][]float32{{1}, {1, 2}}, s.config)
+	assert.Error(s.T(), err)
+}
+
+func (s *DiskANNTestSuite) TestRobustPruneKeepsDiverseEdges() {
+	// the two points right of the origin are close together, so alpha
+	// pruning keeps the nearer one and the point on the left
+	g := &vamana{
+		vectors:   [][]float32{{0, 0}, {1, 0}, {1.1, 0}, {-1.2, 0}},
+		neighbors: make([][]uint32, 4),
+	}
+	g.robustPrune(0, []uint32{1, 2, 3}, 1.2, 3)
+	assert.Equal(s.T(), []uint32{1, 3}, g.neighbors[0])
+}
+
+func TestDiskANNSuite(t *testing.T) {
+	suite.Run(t, new(DiskANNTestSuite))
+}
@@ -3,8 +3,11 @@ package index
 import (
 	"container/heap"
 	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"golang.org/x/sync/errgroup"
 	"math"
 	"math/rand"
+	"runtime"
 	"sync"
 )
 
@@ -20,6 +23,24 @@ type HNSWConfig struct {
 
 	// claude wtf???
 	EfConstruction int
+
+	// Quantization is QuantizationNone, QuantizationInt8 or
+	// QuantizationBinary. Quantized graphs keep codes instead of vectors
+	// and compare them approximately, see SearchRescored.
+	Quantization string
+
+	// Scalar holds the per-dimension ranges for QuantizationInt8, see
+	// search.TrainScalar
+	Scalar *search.ScalarQuantizer
+
+	// RepairAfter is how many deleted nodes pile up before Delete runs
+	// Repair, zero leaves repairs to the caller
+	RepairAfter int
+
+	// KeepCloseNeighbors turns off the heuristic that skips a candidate
+	// nearly parallel to a neighbor already selected. Graphs over
+	// near-duplicates need it, or the copies are pruned away from each other.
+	KeepCloseNeighbors bool
 }
 
 func DefaultHNSWConfig() HNSWConfig {
@@ -28,12 +49,17 @@ func DefaultHNSWConfig() HNSWConfig {
 		MaxLevel:       16,
 		LevelMult:      1 / math.Log(2),
 		EfConstruction: 128,
+		RepairAfter:    1000,
 	}
 }
 
 type node struct {
-	// vector data
-	vector []float64
+	// vector data, unless the graph is quantized
+	vector []float32
+
+	// scalar or bits hold the vector's code on a quantized graph
+	scalar []int8
+	bits   []uint64
 
 	// unique identifier for the node
 	id string
@@ -44,17 +70,20 @@ type node struct {
 	// neighbors[level] is a slice of neighbor IDs at that level
 	neighbors [][]string
 
+	// deleted nodes are no longer returned by searches, but still route
+	// them until Repair unlinks them
+	deleted bool
+
 	lock sync.RWMutex
 }
 
-func newNode(data []float64, id string, maxLevel int, maxConnections int) *node {
+func newNode(id string, maxLevel int, maxConnections int) *node {
 	neighbors := make([][]string, maxLevel+1)
 	for i := range neighbors {
-		neighbors[i] = make([]string, maxConnections)
+		neighbors[i] = make([]string, 0, maxConnections)
 	}
 
 	return &node{
-		vector:    data,
 		id:        id,
 		maxLevel:  maxLevel,
 		neighbors: neighbors,
@@ -71,8 +100,20 @@ type HNSW struct {
 	// the entryPoint (node at the highest level) to start our searches
 	entryPoint string
 
-	// global lock
+	// dims is the vector length, fixed by the first insert
+	dims int
+
+	// deleted counts the nodes marked deleted
+	deleted int
+
+	// lock is shared by inserts and searches, which lock the nodes whose
+	// neighbors they read or change. Deletes, repairs and replacing a node
+	// change many nodes at once and hold it exclusively.
 	lock sync.RWMutex
+
+	// nodesLock guards nodes, entryPoint and dims while inserts run in
+	// parallel
+	nodesLock sync.RWMutex
 }
 
 func NewHNSW(config HNSWConfig) *HNSW {
@@ -82,6 +123,35 @@ func NewHNSW(config HNSWConfig) *HNSW {
 	}
 }
 
+// neighbor looks up the node a link on level leads to. A link left to a
+// replaced node can lead to a node that doesn't reach level, which counts
+// as missing.
+func (h *HNSW) neighbor(id string, level int) (*node, bool) {
+	n, exists := h.lookup(id)
+	if !exists || n.maxLevel < level {
+		return nil, false
+	}
+	return n, true
+}
+
+// lookup returns the node for id
+func (h *HNSW) lookup(id string) (*node, bool) {
+	h.nodesLock.RLock()
+	defer h.nodesLock.RUnlock()
+
+	n, exists := h.nodes[id]
+	return n, exists
+}
+
+// entry returns the entry point, or false when the graph is empty
+func (h *HNSW) entry() (*node, bool) {
+	h.nodesLock.RLock()
+	defer h.nodesLock.RUnlock()
+
+	n, exists := h.nodes[h.entryPoint]
+	return n, exists
+}
+
 func (h *HNSW) randomLevel() int {
 	level := 0
 	for rand.Float64() < 1/h.config.LevelMult && level < h.config.MaxLevel {
@@ -91,81 +161,149 @@ func (h *HNSW) randomLevel() int {
 	return level
 }
 
-func (h *HNSW) Insert(id string, vector []float64) error {
+// Insert adds vector to the graph under id, replacing the node id had.
+// Inserts of new ids run in parallel with each other and with searches.
+func (h *HNSW) Insert(id string, vector []float32) error {
+	h.lock.RLock()
+	if _, exists := h.lookup(id); !exists {
+		inserted, err := h.insert(id, vector)
+		h.lock.RUnlock()
+		if inserted || err != nil {
+			return err
+		}
+	} else {
+		h.lock.RUnlock()
+	}
+
+	// replacing a node unlinks it from its neighbors first
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
+	if existing, exists := h.nodes[id]; exists {
+		h.remove(existing)
+	}
+	_, err := h.insert(id, vector)
+	return err
+}
+
+// InsertBatch inserts vectors[i] under ids[i] from workers goroutines, one
+// per CPU when workers is zero, and returns the first error
+func (h *HNSW) InsertBatch(ids []string, vectors [][]float32, workers int) error {
+	if len(ids) != len(vectors) {
+		return fmt.Errorf("got %d ids for %d vectors", len(ids), len(vectors))
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var g errgroup.Group
+	g.SetLimit(workers)
+	for i := range ids {
+		g.Go(func() error {
+			if err := h.Insert(ids[i], vectors[i]); err != nil {
+				return fmt.Errorf("could not index %s: %v", ids[i], err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// insert links a new node for id into the graph. It reports false, leaving
+// the graph as it was, when a node for id turns up before the new one is
+// registered. The caller must hold the lock, shared or exclusively.
+func (h *HNSW) insert(id string, vector []float32) (bool, error) {
 	level := h.randomLevel()
-	newnode := newNode(vector, id, level, h.config.M)
+	newnode := newNode(id, level, h.config.M)
+	if err := h.encode(newnode, vector); err != nil {
+		return false, err
+	}
+	query := h.point(vector)
 
+	h.nodesLock.Lock()
+	if _, exists := h.nodes[id]; exists {
+		h.nodesLock.Unlock()
+		return false, nil
+	}
 	if len(h.nodes) == 0 {
 		h.nodes[id] = newnode
 		h.entryPoint = id
-		return nil
+		h.nodesLock.Unlock()
+		return true, nil
 	}
 	entryNode := h.nodes[h.entryPoint]
+	// register the node first, pruning in connectNodes looks its id up
+	h.nodes[id] = newnode
+	h.nodesLock.Unlock()
 
 	currNode := entryNode
-	currDist := h.distanceToNode(vector, entryNode.vector)
-
+	currDist := h.distance(query, entryNode)
 	for lc := entryNode.maxLevel; lc > level; lc-- {
-		changed := true
-		for changed {
-			changed = false
-
-			currNode.lock.RLock()
-			neighbors := currNode.neighbors[lc]
-			currNode.lock.RUnlock()
-
-			for _, neighborID := range neighbors {
-				neighbor := h.nodes[neighborID]
-				distance := h.distanceToNode(vector, neighbor.vector)
-
-				if distance < currDist {
-					currDist = distance
-					currNode = neighbor
-					changed = true
-					break
-				}
-			}
-		}
+		currNode, currDist = h.searchAtLayer(query, currNode, currDist, lc)
 	}
 
-	for lc := 0; lc <= level; lc++ {
-		candidates := h.searchLayer(vector, currNode, lc, h.config.EfConstruction)
+	// the new node can only link into levels the graph already has
+	top := level
+	if entryNode.maxLevel < top {
+		top = entryNode.maxLevel
+	}
+	for lc := top; lc >= 0; lc-- {
+		candidates := h.searchLayer(query, currNode, lc, h.config.EfConstruction, false)
+		// links left to a replaced node with this id can lead back here
+		for i, candidate := range candidates {
+			if candidate.node == newnode {
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
 
-		selectedNeighbors := h.selectNeighbors(candidates, h.config.M, false)
+		selectedNeighbors := h.selectNeighbors(candidates, h.config.M, h.config.KeepCloseNeighbors)
 
 		for _, neighbor := range selectedNeighbors {
 			h.connectNodes(newnode, neighbor.node, lc)
 		}
-	}
 
-	h.nodes[id] = newnode
+		// the closest candidate enters the next level down
+		currNode = candidates[0].node
+	}
 
+	h.nodesLock.Lock()
 	if level > h.nodes[h.entryPoint].maxLevel {
 		h.entryPoint = id
 	}
+	h.nodesLock.Unlock()
 
-	return nil
+	return true, nil
 }
 
-func (h *HNSW) searchLayer(queryVector []float64, entryNode *node, level int, ef int) []*queueItem {
+// searchLayer returns the ef nodes closest to query it finds on level,
+// closest first. With skipDeleted, deleted nodes are walked through but
+// left out of the results.
+func (h *HNSW) searchLayer(query point, entryNode *node, level int, ef int, skipDeleted bool) []*queueItem {
 	visited := make(map[string]bool)
 	visited[entryNode.id] = true
 
 	candidates := make(distQueue, 0)
 	heap.Init(&candidates)
 
-	results := make(distQueue, 0)
+	// results is a max-heap so the farthest result is always on top
+	results := make(farQueue, 0)
 	heap.Init(&results)
 
-	startDist := h.distanceToNode(queryVector, entryNode.vector)
+	startDist := h.distance(query, entryNode)
 	item := &queueItem{node: entryNode, distance: startDist, id: entryNode.id}
 	heap.Push(&candidates, item)
-	heap.Push(&results, item)
+	if !skipDeleted || !entryNode.deleted {
+		heap.Push(&results, item)
+	}
 
-	for candidates.Len() > 0 && candidates[0].distance <= results[results.Len()-1].distance {
+	for candidates.Len() > 0 {
+		if results.Len() >= ef && candidates[0].distance > results[0].distance {
+			break
+		}
 		current := heap.Pop(&candidates).(*queueItem)
 
 		current.node.lock.RLock()
@@ -178,12 +316,18 @@ func (h *HNSW) searchLayer(queryVector []float64, entryNode *node, level int, ef
 			}
 
 			visited[neighborID] = true
-			neighbor := h.nodes[neighborID]
-			distance := h.distanceToNode(queryVector, neighbor.vector)
+			neighbor, exists := h.neighbor(neighborID, level)
+			if !exists {
+				continue
+			}
+			distance := h.distance(query, neighbor)
 
-			if results.Len() < ef || distance < results[results.Len()-1].distance {
+			if results.Len() < ef || distance < results[0].distance {
 				item := &queueItem{node: neighbor, distance: distance, id: neighborID}
 				heap.Push(&candidates, item)
+				if skipDeleted && neighbor.deleted {
+					continue
+				}
 				heap.Push(&results, item)
 
 				if results.Len() > ef {
@@ -193,6 +337,8 @@ func (h *HNSW) searchLayer(queryVector []float64, entryNode *node, level int, ef
 		}
 	}
 
+	// popping the max-heap yields the farthest first, fill from the back
+	// so the slice is ordered closest first
 	resultSlice := make([]*queueItem, results.Len())
 	for i := len(resultSlice) - 1; i >= 0; i-- {
 		resultSlice[i] = heap.Pop(&results).(*queueItem)
@@ -200,80 +346,3 @@ func (h *HNSW) searchLayer(queryVector []float64, entryNode *node, level int, ef
 
 	return resultSlice
 }
-
-// Delete removes a node with the given ID from the index
-func (h *HNSW) Delete(id string) error {
-	h.lock.Lock()
-	defer h.lock.Unlock()
-
-	// Check if the node exists
-	node, exists := h.nodes[id]
-	if !exists {
-		return fmt.Errorf("node with id %s does not exist", id)
-	}
-
-	// If this is the entry point, we need to find a new one
-	if h.entryPoint == id {
-		h.updateEntryPointForDeletion(id)
-	}
-
-	// Remove references to this node from all its neighbors
-	for level := 0; level <= node.maxLevel; level++ {
-		node.lock.RLock()
-		neighbors := node.neighbors[level]
-		node.lock.RUnlock()
-
-		for _, neighborID := range neighbors {
-			if neighborID == "" {
-				continue
-			}
-			neighbor := h.nodes[neighborID]
-			h.removeNeighborConnection(neighbor, id, level)
-		}
-	}
-
-	// Delete the node from our nodes map
-	delete(h.nodes, id)
-
-	return nil
-}
-
-// updateEntryPointForDeletion finds a new entry point when the current one is being deleted
-func (h *HNSW) updateEntryPointForDeletion(deletingID string) {
-	if len(h.nodes) == 1 {
-		// If this is the last node, clear the entry point
-		h.entryPoint = ""
-		return
-	}
-
-	// Find the node with the highest level that isn't the one being deleted
-	maxLevel := -1
-	var newEntryID string
-
-	for nodeID, node := range h.nodes {
-		if nodeID != deletingID && node.maxLevel > maxLevel {
-			maxLevel = node.maxLevel
-			newEntryID = nodeID
-		}
-	}
-
-	h.entryPoint = newEntryID
-}
-
-// removeNeighborConnection removes a connection to the specified node at the given level
-func (h *HNSW) removeNeighborConnection(node *node, targetID string, level int) {
-	node.lock.Lock()
-	defer node.lock.Unlock()
-
-	for i, neighborID := range node.neighbors[level] {
-		if neighborID == targetID {
-			if i == len(node.neighbors[level])-1 {
-				node.neighbors[level][i] = ""
-			} else {
-				copy(node.neighbors[level][i:], node.neighbors[level][i+1:])
-				node.neighbors[level][len(node.neighbors[level])-1] = ""
-			}
-			break
-		}
-	}
-}
@@ -0,0 +1,427 @@
+package index
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type HNSWTestSuite struct {
+	suite.Suite
+	vectors map[string][]float32
+	hnsw    *HNSW
+}
+
+func (s *HNSWTestSuite) SetupTest() {
+	rng := rand.New(rand.NewSource(42))
+	s.vectors = make(map[string][]float32)
+	s.hnsw = NewHNSW(DefaultHNSWConfig())
+	for i := 0; i < 500; i++ {
+		vector := []float32{rng.Float32(), rng.Float32(), rng.Float32(), rng.Float32()}
+		id := fmt.Sprintf("v%d", i)
+		s.vectors[id] = vector
+		require.NoError(s.T(), s.hnsw.Insert(id, vector))
+	}
+}
+
+func (s *HNSWTestSuite) TestSearch() {
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+
+	results, err := s.hnsw.Search(query, 10)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 10)
+	assert.True(s.T(), sort.IsSorted(results))
+}
+
+func (s *HNSWTestSuite) TestSearchRadius() {
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+	radius := 0.3
+
+	exact := make(map[string]bool)
+	for id, vector := range s.vectors {
+		if search.L2(vector, query) <= radius {
+			exact[id] = true
+		}
+	}
+	require.NotEmpty(s.T(), exact)
+
+	results, err := s.hnsw.SearchRadius(query, radius, 64)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), sort.IsSorted(results))
+
+	found := 0
+	for _, result := range results {
+		assert.LessOrEqual(s.T(), result.Distance, radius)
+		assert.True(s.T(), exact[result.ID], "%s is outside the radius", result.ID)
+		found++
+	}
+	// the walk is approximate, but on a small dense graph it finds nearly all
+	assert.GreaterOrEqual(s.T(), float64(found), 0.9*float64(len(exact)))
+
+	results, err = s.hnsw.SearchRadius([]float32{10, 10, 10, 10}, radius, 64)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), results)
+}
+
+func (s *HNSWTestSuite) TestQuantizedSearch() {
+	rng := rand.New(rand.NewSource(7))
+	vectors := make(map[string][]float32)
+	sample := make([][]float32, 0)
+	for i := 0; i < 500; i++ {
+		vector := make([]float32, 64)
+		for d := range vector {
+			vector[d] = float32(rng.NormFloat64())
+		}
+		id := fmt.Sprintf("v%d", i)
+		vectors[id] = vector
+		sample = append(sample, vector)
+	}
+	scalar, err := search.TrainScalar(sample)
+	require.NoError(s.T(), err)
+	vectorOf := func(id string) ([]float32, bool) {
+		vector, ok := vectors[id]
+		return vector, ok
+	}
+
+	for _, quantization := range []string{QuantizationInt8, QuantizationBinary} {
+		config := DefaultHNSWConfig()
+		config.Quantization = quantization
+		config.Scalar = scalar
+		graph := NewHNSW(config)
+		for id, vector := range vectors {
+			require.NoError(s.T(), graph.Insert(id, vector))
+		}
+		assert.Nil(s.T(), graph.nodes["v0"].vector)
+
+		found, total := 0, 0
+		for q := 0; q < 20; q++ {
+			query := sample[rng.Intn(len(sample))]
+			exact := make(SearchResults, 0, len(vectors))
+			for id, vector := range vectors {
+				exact = append(exact, SearchResult{ID: id, Distance: search.L2(query, vector)})
+			}
+			sort.Sort(exact)
+			want := make(map[string]bool)
+			for _, result := range exact[:10] {
+				want[result.ID] = true
+			}
+
+			results, err := graph.SearchRescored(query, 10, 10, vectorOf)
+			require.NoError(s.T(), err)
+			require.Len(s.T(), results, 10)
+			assert.True(s.T(), sort.IsSorted(results))
+			// rescored results carry exact distances
+			assert.InDelta(s.T(), search.L2(query, vectors[results[0].ID]), results[0].Distance, 1e-9)
+			for _, result := range results {
+				if want[result.ID] {
+					found++
+				}
+			}
+			total += 10
+		}
+		// rescoring the oversampled candidates recovers most of the recall
+		// the codes lose, sign bits lose a lot more than int8
+		recall := map[string]float64{QuantizationInt8: 0.9, QuantizationBinary: 0.7}
+		assert.GreaterOrEqual(s.T(), float64(found)/float64(total), recall[quantization], quantization)
+	}
+
+	// int8 graphs need trained ranges
+	config := DefaultHNSWConfig()
+	config.Quantization = QuantizationInt8
+	assert.Error(s.T(), NewHNSW(config).Insert("v0", sample[0]))
+}
+
+// recall is the share of the exact k nearest live vectors that graph finds
+// for a sample of the queries
+func (s *HNSWTestSuite) recall(graph *HNSW, vectors map[string][]float32, k int) float64 {
+	found, total := 0, 0
+	for id, query := range vectors {
+		if total >= 50*k {
+			break
+		}
+		_ = id
+		exact := make(SearchResults, 0, len(vectors))
+		for id, vector := range vectors {
+			exact = append(exact, SearchResult{ID: id, Distance: search.L2(query, vector)})
+		}
+		sort.Sort(exact)
+		want := make(map[string]bool)
+		for _, result := range exact[:k] {
+			want[result.ID] = true
+		}
+
+		results, err := graph.SearchWithAccuracy(query, k, 64)
+		require.NoError(s.T(), err)
+		for _, result := range results {
+			_, live := vectors[result.ID]
+			require.True(s.T(), live, "search returned deleted %s", result.ID)
+			if want[result.ID] {
+				found++
+			}
+		}
+		total += k
+	}
+	return float64(found) / float64(total)
+}
+
+func (s *HNSWTestSuite) TestDeleteAndRepair() {
+	rng := rand.New(rand.NewSource(9))
+	config := DefaultHNSWConfig()
+	config.RepairAfter = 0
+	graph := NewHNSW(config)
+	vectors := make(map[string][]float32)
+	for i := 0; i < 400; i++ {
+		vector := make([]float32, 16)
+		for d := range vector {
+			vector[d] = float32(rng.NormFloat64())
+		}
+		id := fmt.Sprintf("v%d", i)
+		vectors[id] = vector
+		require.NoError(s.T(), graph.Insert(id, vector))
+	}
+	// pruning while inserting can drop every link to a node, repairing
+	// links such nodes back
+	graph.Repair()
+	require.NoError(s.T(), graph.CheckConnectivity())
+
+	// deleted nodes stay in the graph as waypoints, but aren't returned
+	for i := 0; i < 400; i += 5 {
+		for _, id := range []string{fmt.Sprintf("v%d", i), fmt.Sprintf("v%d", i+1)} {
+			require.NoError(s.T(), graph.Delete(id))
+			delete(vectors, id)
+		}
+	}
+	assert.Error(s.T(), graph.Delete("v0"))
+	assert.Error(s.T(), graph.Delete("missing"))
+	assert.Equal(s.T(), 240, graph.Len())
+	assert.Equal(s.T(), 160, graph.Deleted())
+	require.NoError(s.T(), graph.CheckConnectivity())
+	assert.GreaterOrEqual(s.T(), s.recall(graph, vectors, 10), 0.9)
+
+	radius, err := graph.SearchRadius(vectors["v2"], 3, 64)
+	require.NoError(s.T(), err)
+	for _, result := range radius {
+		assert.Contains(s.T(), vectors, result.ID)
+	}
+
+	// repairing unlinks the deleted nodes without disconnecting the rest
+	graph.Repair()
+	assert.Equal(s.T(), 0, graph.Deleted())
+	assert.Len(s.T(), graph.nodes, 240)
+	require.NoError(s.T(), graph.CheckConnectivity())
+	assert.GreaterOrEqual(s.T(), s.recall(graph, vectors, 10), 0.9)
+
+	graph.Consolidate()
+	require.NoError(s.T(), graph.CheckConnectivity())
+	assert.GreaterOrEqual(s.T(), s.recall(graph, vectors, 10), 0.95)
+
+	// deleting everything leaves an empty graph that still takes inserts
+	for id := range vectors {
+		require.NoError(s.T(), graph.Delete(id))
+	}
+	results, err := graph.Search(vectors["v2"], 10)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), results)
+	graph.Repair()
+	assert.Empty(s.T(), graph.nodes)
+	require.NoError(s.T(), graph.Insert("v2", vectors["v2"]))
+	require.NoError(s.T(), graph.CheckConnectivity())
+}
+
+func (s *HNSWTestSuite) TestRepairAfter() {
+	config := DefaultHNSWConfig()
+	config.RepairAfter = 10
+	graph := NewHNSW(config)
+	for id, vector := range s.vectors {
+		require.NoError(s.T(), graph.Insert(id, vector))
+	}
+
+	for i := 0; i < 9; i++ {
+		require.NoError(s.T(), graph.Delete(fmt.Sprintf("v%d", i)))
+	}
+	assert.Equal(s.T(), 9, graph.Deleted())
+	require.NoError(s.T(), graph.Delete("v9"))
+	assert.Equal(s.T(), 0, graph.Deleted())
+	assert.Equal(s.T(), 490, graph.Len())
+	assert.Len(s.T(), graph.nodes, 490)
+	require.NoError(s.T(), graph.CheckConnectivity())
+}
+
+func (s *HNSWTestSuite) TestInsertReplaces() {
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("v%d", i)
+		s.vectors[id] = []float32{1 - s.vectors[id][0], s.vectors[id][1], 1 - s.vectors[id][2], s.vectors[id][3]}
+		require.NoError(s.T(), s.hnsw.Insert(id, s.vectors[id]))
+	}
+	assert.Equal(s.T(), 500, s.hnsw.Len())
+	s.hnsw.Repair()
+	require.NoError(s.T(), s.hnsw.CheckConnectivity())
+
+	// the replaced node is found by its new vector
+	results, err := s.hnsw.SearchWithAccuracy(s.vectors["v7"], 1, 64)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "v7", results[0].ID)
+	assert.Equal(s.T(), s.vectors["v7"], results[0].Vector)
+}
+
+func (s *HNSWTestSuite) TestInsertBatch() {
+	rng := rand.New(rand.NewSource(13))
+	ids := make([]string, 1000)
+	vectors := make(map[string][]float32, len(ids))
+	batch := make([][]float32, len(ids))
+	for i := range ids {
+		ids[i] = fmt.Sprintf("v%d", i)
+		batch[i] = make([]float32, 16)
+		for d := range batch[i] {
+			batch[i][d] = float32(rng.NormFloat64())
+		}
+		vectors[ids[i]] = batch[i]
+	}
+
+	graph := NewHNSW(DefaultHNSWConfig())
+	require.NoError(s.T(), graph.InsertBatch(ids, batch, 8))
+	assert.Equal(s.T(), 1000, graph.Len())
+	graph.Repair()
+	require.NoError(s.T(), graph.CheckConnectivity())
+	assert.GreaterOrEqual(s.T(), s.recall(graph, vectors, 10), 0.9)
+
+	assert.Error(s.T(), graph.InsertBatch(ids[:2], batch[:1], 0))
+	assert.Error(s.T(), graph.InsertBatch([]string{"short"}, [][]float32{{1, 2}}, 0))
+}
+
+func (s *HNSWTestSuite) TestConcurrentStress() {
+	// run with -race: inserts, replacements, deletes and searches all at once
+	rng := rand.New(rand.NewSource(17))
+	vectors := make([][]float32, 1500)
+	for i := range vectors {
+		vectors[i] = make([]float32, 16)
+		for d := range vectors[i] {
+			vectors[i][d] = float32(rng.NormFloat64())
+		}
+	}
+
+	config := DefaultHNSWConfig()
+	config.RepairAfter = 25
+	graph := NewHNSW(config)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 1000; i += 8 {
+				if err := graph.Insert(fmt.Sprintf("v%d", i), vectors[i]); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 400; i += 4 {
+				if _, err := graph.SearchWithAccuracy(vectors[1000+i], 10, 32); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := graph.SearchRadius(vectors[1000+i], 2, 16); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// ids 0-99 are inserted again with new vectors, or deleted, racing
+		// with their first insert
+		for i := 0; i < 100; i++ {
+			id := fmt.Sprintf("v%d", i)
+			if i%4 == 0 {
+				_ = graph.Delete(id)
+				continue
+			}
+			if err := graph.Insert(id, vectors[1400+i]); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(s.T(), err)
+	}
+
+	// settle the ids whose delete raced with their insert
+	live := make(map[string][]float32)
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("v%d", i)
+		switch {
+		case i < 100 && i%4 == 0:
+			_ = graph.Delete(id)
+		case i < 100:
+			require.NoError(s.T(), graph.Insert(id, vectors[1400+i]))
+			live[id] = vectors[1400+i]
+		default:
+			live[id] = vectors[i]
+		}
+	}
+	assert.Equal(s.T(), len(live), graph.Len())
+	graph.Repair()
+	require.NoError(s.T(), graph.CheckConnectivity())
+	assert.GreaterOrEqual(s.T(), s.recall(graph, live, 10), 0.9)
+}
+
+func (s *HNSWTestSuite) TestSearchRadiusDuplicateCluster() {
+	// 40 near-copies of one vector among random ones, which the diversity
+	// heuristic would prune away from each other
+	rng := rand.New(rand.NewSource(21))
+	random := func() []float32 {
+		vector := make([]float32, 16)
+		for i := range vector {
+			vector[i] = float32(rng.NormFloat64())
+		}
+		return vector
+	}
+	base := random()
+
+	config := DefaultHNSWConfig()
+	config.KeepCloseNeighbors = true
+	graph := NewHNSW(config)
+	for i := 0; i < 1000; i++ {
+		require.NoError(s.T(), graph.Insert(fmt.Sprintf("r%d", i), random()))
+	}
+	for i := 0; i < 40; i++ {
+		vector := make([]float32, len(base))
+		for j := range vector {
+			vector[j] = base[j] + 0.01*float32(rng.NormFloat64())
+		}
+		require.NoError(s.T(), graph.Insert(fmt.Sprintf("c%d", i), vector))
+	}
+
+	results, err := graph.SearchRadius(base, 0.2, 400)
+	require.NoError(s.T(), err)
+	copies := 0
+	for _, result := range results {
+		if strings.HasPrefix(result.ID, "c") {
+			copies++
+		}
+	}
+	assert.Equal(s.T(), 40, copies)
+}
+
+func TestHNSWSuite(t *testing.T) {
+	suite.Run(t, new(HNSWTestSuite))
+}
@@ -0,0 +1,747 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+)
+
+type IVFConfig struct {
+	// NLists is how many coarse centroids, and so posting lists, training
+	// produces. It is capped at the number of training vectors.
+	NLists int
+
+	// NProbe is how many of the nearest lists a search scans by default
+	NProbe int
+
+	// TrainAfter is how many vectors the index collects, searching them
+	// exhaustively, before it trains itself
+	TrainAfter int
+
+	// SampleSize caps how many vectors k-means trains on
+	SampleSize int
+
+	// Iterations caps the k-means iterations
+	Iterations int
+
+	Seed int64
+
+	// PQ turns the index into IVF-PQ when PQ.Subspaces is set: once
+	// trained, posting lists keep product quantization codes instead of
+	// the vectors, and probes score the codes approximately. Subspaces is
+	// capped at the vectors' dimensions.
+	PQ PQConfig
+}
+
+func DefaultIVFConfig() IVFConfig {
+	return IVFConfig{
+		NLists:     256,
+		NProbe:     8,
+		TrainAfter: 10000,
+		SampleSize: 65536,
+		Iterations: 20,
+		Seed:       42,
+		PQ: PQConfig{
+			SampleSize: 65536,
+			Iterations: 20,
+			Seed:       42,
+		},
+	}
+}
+
+// ivfPosting is one vector in a posting list, kept whole or, in a trained
+// IVF-PQ index, as its code
+type ivfPosting struct {
+	id     string
+	vector []float32
+	norm   float32
+	code   []byte
+}
+
+// IVF is an inverted-file index (IVF-Flat). Training clusters a sample of
+// the vectors into coarse centroids, every vector is filed in the posting
+// list of its nearest centroid, and a search only scans the lists of the
+// NProbe centroids nearest the query. Unlike HNSW it keeps no graph, just
+// the vectors and their list, or just their codes for IVF-PQ.
+type IVF struct {
+	config IVFConfig
+
+	// dim is the vector length, fixed by the first vector added
+	dim int
+
+	// centroids is empty until the index is trained
+	centroids [][]float32
+
+	// pq encodes the vectors of a trained IVF-PQ index
+	pq *PQ
+
+	// lists[i] holds the vectors nearest centroids[i]. Before training
+	// everything sits in unassigned.
+	lists      [][]ivfPosting
+	unassigned []ivfPosting
+
+	// where maps an id to its list, -1 for unassigned
+	where map[string]int
+
+	// changes are the adds (with a vector) and deletes (without) since the
+	// index was last saved, which Persist appends to the log
+	changes []ivfChange
+
+	// saved is set while the file written by Save holds the current
+	// centroids, so the log can carry the changes since. generation tells
+	// that file's log apart from one left behind by an older save.
+	saved      bool
+	generation uint64
+
+	lock sync.RWMutex
+}
+
+// ivfChange is a vector added under id, or id deleted when vector is nil
+type ivfChange struct {
+	id     string
+	vector []float32
+}
+
+func NewIVF(config IVFConfig) *IVF {
+	return &IVF{
+		config: config,
+		where:  make(map[string]int),
+	}
+}
+
+// Trained reports whether the index has centroids yet
+func (ivf *IVF) Trained() bool {
+	ivf.lock.RLock()
+	defer ivf.lock.RUnlock()
+
+	return len(ivf.centroids) > 0
+}
+
+// Quantized reports whether the posting lists hold codes instead of
+// vectors
+func (ivf *IVF) Quantized() bool {
+	ivf.lock.RLock()
+	defer ivf.lock.RUnlock()
+
+	return ivf.pq != nil
+}
+
+// Len counts the indexed vectors
+func (ivf *IVF) Len() int {
+	ivf.lock.RLock()
+	defer ivf.lock.RUnlock()
+
+	return len(ivf.where)
+}
+
+// Add files vector under id, replacing any vector already stored for it.
+// Once the index is trained the vector goes straight to the list of its
+// nearest centroid; before that, the Add that reaches TrainAfter vectors
+// trains the index.
+func (ivf *IVF) Add(id string, vector []float32) error {
+	ivf.lock.Lock()
+	defer ivf.lock.Unlock()
+
+	if err := ivf.add(id, vector); err != nil {
+		return err
+	}
+	ivf.changes = append(ivf.changes, ivfChange{id: id, vector: vector})
+	return nil
+}
+
+func (ivf *IVF) add(id string, vector []float32) error {
+	if ivf.dim == 0 {
+		ivf.dim = len(vector)
+	}
+	if len(vector) != ivf.dim {
+		return fmt.Errorf("vector for %s has %d dimensions, the index holds %d", id, len(vector), ivf.dim)
+	}
+
+	ivf.remove(id)
+	posting := ivfPosting{id: id, vector: vector, norm: float32(search.Norm(vector))}
+	if len(ivf.centroids) == 0 {
+		ivf.unassigned = append(ivf.unassigned, posting)
+		ivf.where[id] = -1
+		if ivf.config.TrainAfter > 0 && len(ivf.unassigned) >= ivf.config.TrainAfter {
+			return ivf.train()
+		}
+		return nil
+	}
+
+	if ivf.pq != nil {
+		code, err := ivf.pq.Encode(vector)
+		if err != nil {
+			return err
+		}
+		posting = ivfPosting{id: id, code: code}
+	}
+	list := nearestCentroid(ivf.centroids, vector)
+	ivf.lists[list] = append(ivf.lists[list], posting)
+	ivf.where[id] = list
+	return nil
+}
+
+// Delete removes id from the index
+func (ivf *IVF) Delete(id string) {
+	ivf.lock.Lock()
+	defer ivf.lock.Unlock()
+
+	if ivf.remove(id) {
+		ivf.changes = append(ivf.changes, ivfChange{id: id})
+	}
+}
+
+// remove reports whether id was indexed
+func (ivf *IVF) remove(id string) bool {
+	list, exists := ivf.where[id]
+	if !exists {
+		return false
+	}
+	delete(ivf.where, id)
+
+	postings := ivf.unassigned
+	if list >= 0 {
+		postings = ivf.lists[list]
+	}
+	for i, posting := range postings {
+		if posting.id == id {
+			postings[i] = postings[len(postings)-1]
+			postings = postings[:len(postings)-1]
+			break
+		}
+	}
+	if list >= 0 {
+		ivf.lists[list] = postings
+	} else {
+		ivf.unassigned = postings
+	}
+	return true
+}
+
+// Train clusters a sample of the indexed vectors into new centroids and
+// refiles every vector. Calling it again retrains, for instance after the
+// data has drifted from the original sample. A trained IVF-PQ index no
+// longer has the vectors to retrain on, build a new one instead.
+func (ivf *IVF) Train() error {
+	ivf.lock.Lock()
+	defer ivf.lock.Unlock()
+
+	if len(ivf.where) == 0 {
+		return fmt.Errorf("cannot train an empty index")
+	}
+	if ivf.pq != nil {
+		return fmt.Errorf("cannot retrain an ivf-pq index from its codes")
+	}
+	return ivf.train()
+}
+
+func (ivf *IVF) train() error {
+	all := ivf.unassigned
+	for _, list := range ivf.lists {
+		all = append(all, list...)
+	}
+
+	rng := rand.New(rand.NewSource(ivf.config.Seed))
+	sample := make([][]float32, 0, len(all))
+	for _, posting := range all {
+		sample = append(sample, posting.vector)
+	}
+	if ivf.config.SampleSize > 0 && len(sample) > ivf.config.SampleSize {
+		rng.Shuffle(len(sample), func(i, j int) {
+			sample[i], sample[j] = sample[j], sample[i]
+		})
+		sample = sample[:ivf.config.SampleSize]
+	}
+
+	var pq *PQ
+	if ivf.config.PQ.Subspaces > 0 {
+		pqConfig := ivf.config.PQ
+		if pqConfig.Subspaces > ivf.dim {
+			pqConfig.Subspaces = ivf.dim
+		}
+		var err error
+		pq, err = TrainPQ(sample, pqConfig)
+		if err != nil {
+			return fmt.Errorf("could not train product quantization: %v", err)
+		}
+	}
+
+	ivf.centroids = kmeans(sample, ivf.config.NLists, ivf.config.Iterations, rng)
+	ivf.pq = pq
+	// every vector moves, so the log can't carry this
+	ivf.saved = false
+	ivf.lists = make([][]ivfPosting, len(ivf.centroids))
+	ivf.unassigned = nil
+	for _, posting := range all {
+		list := nearestCentroid(ivf.centroids, posting.vector)
+		if pq != nil {
+			// the full vector is what IVF-PQ saves memory on
+			code, err := pq.Encode(posting.vector)
+			if err != nil {
+				return err
+			}
+			posting = ivfPosting{id: posting.id, code: code}
+		}
+		ivf.lists[list] = append(ivf.lists[list], posting)
+		ivf.where[posting.id] = list
+	}
+
+	return nil
+}
+
+// Probe returns every vector in the nprobe lists nearest the query, with
+// its Euclidean distance, for the caller to score with its own metric. A
+// zero nprobe uses the configured NProbe. Before training it returns every
+// vector. An IVF-PQ index returns approximate distances and no vectors.
+func (ivf *IVF) Probe(queryVector []float32, nprobe int) SearchResults {
+	scored, err := ivf.ProbeScores(queryVector, nprobe, "l2")
+	if err != nil {
+		return SearchResults{}
+	}
+
+	results := make(SearchResults, len(scored))
+	for i, candidate := range scored {
+		results[i] = SearchResult{
+			ID:       candidate.ID,
+			Distance: candidate.Score,
+			Vector:   candidate.Vector,
+		}
+	}
+
+	return results
+}
+
+// Scored is a probed vector and its score under a metric
+type Scored struct {
+	ID    string
+	Score float64
+
+	// Vector is nil when the index only keeps a code, in which case Score
+	// is an approximation
+	Vector []float32
+}
+
+// ProbeScores is Probe scoring every vector with metric ("l2", "dot" or
+// "cosine") in the units of the search package's metric functions
+func (ivf *IVF) ProbeScores(queryVector []float32, nprobe int, metric string) ([]Scored, error) {
+	scorer, err := search.NewScorer(metric, queryVector)
+	if err != nil {
+		return nil, err
+	}
+
+	ivf.lock.RLock()
+	defer ivf.lock.RUnlock()
+
+	if nprobe <= 0 {
+		nprobe = ivf.config.NProbe
+	}
+
+	results := make([]Scored, 0)
+	if len(queryVector) != ivf.dim {
+		return results, nil
+	}
+	var table *PQTable
+	if ivf.pq != nil {
+		table, err = ivf.pq.Table(queryVector, metric)
+		if err != nil {
+			return nil, err
+		}
+	}
+	collect := func(postings []ivfPosting) {
+		for _, posting := range postings {
+			scored := Scored{ID: posting.id, Vector: posting.vector}
+			if posting.vector != nil {
+				scored.Score = scorer.Score(posting.vector, posting.norm)
+			} else {
+				scored.Score = table.Score(posting.code)
+			}
+			results = append(results, scored)
+		}
+	}
+
+	collect(ivf.unassigned)
+	for _, list := range ivf.nearestLists(queryVector, nprobe) {
+		collect(ivf.lists[list])
+	}
+
+	return results, nil
+}
+
+// Search finds the k nearest neighbors among the nprobe nearest lists
+func (ivf *IVF) Search(queryVector []float32, k int, nprobe int) (SearchResults, error) {
+	results := ivf.Probe(queryVector, nprobe)
+	sort.Sort(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results, nil
+}
+
+// nearestLists orders the lists by how close their centroid is to the
+// query and returns the first n
+func (ivf *IVF) nearestLists(queryVector []float32, n int) []int {
+	if len(ivf.centroids) == 0 {
+		return []int{}
+	}
+
+	lists := make([]int, len(ivf.centroids))
+	distances := make([]float64, len(ivf.centroids))
+	for i, centroid := range ivf.centroids {
+		lists[i] = i
+		distances[i] = squaredL2(centroid, queryVector)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return distances[lists[i]] < distances[lists[j]]
+	})
+	if n < len(lists) {
+		lists = lists[:n]
+	}
+
+	return lists
+}
+
+// ivfMagic starts every saved IVF index, followed by a format version.
+// Version 2 added the product quantization codebooks after the centroids,
+// version 3 stores vectors, centroids and codebooks as float32 instead of
+// float64, version 4 adds the generation of the log after the version.
+var ivfMagic = [4]byte{'G', 'I', 'V', 'F'}
+
+const ivfVersion uint32 = 4
+
+// ivfLogSuffix names the log of the index saved at a path. It starts with
+// the generation of the index it applies to, followed by records of an op
+// byte, the id's length and bytes and, for adds, the vector's length and
+// float32s.
+const ivfLogSuffix = ".log"
+
+const (
+	ivfLogDelete byte = 0
+	ivfLogAdd    byte = 1
+)
+
+// Save writes the centroids and posting lists to path, through a temporary
+// file so a crash never leaves a half written index behind, and drops the
+// log of the previous save
+func (ivf *IVF) Save(path string) error {
+	ivf.lock.Lock()
+	defer ivf.lock.Unlock()
+
+	return ivf.save(path)
+}
+
+// Persist brings the index saved at path up to date. The adds and deletes
+// since the last save are appended to its log, so it costs what changed
+// rather than the size of the index. Once the log outgrows the index, or
+// after training moved every vector, the index is saved whole instead.
+func (ivf *IVF) Persist(path string) error {
+	ivf.lock.Lock()
+	defer ivf.lock.Unlock()
+
+	if !ivf.saved {
+		return ivf.save(path)
+	}
+	if len(ivf.changes) == 0 {
+		return nil
+	}
+
+	logPath := path + ivfLogSuffix
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", logPath, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %v", logPath, err)
+	}
+
+	var buf []byte
+	if info.Size() == 0 {
+		buf = binary.LittleEndian.AppendUint64(buf, ivf.generation)
+	}
+	for _, change := range ivf.changes {
+		buf = appendChange(buf, change)
+	}
+	if _, err := file.Write(buf); err != nil {
+		return fmt.Errorf("could not append to %s: %v", logPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %v", logPath, err)
+	}
+	ivf.changes = nil
+
+	saved, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not stat ivf index: %v", err)
+	}
+	if info.Size()+int64(len(buf)) > saved.Size() {
+		return ivf.save(path)
+	}
+	return nil
+}
+
+func appendChange(buf []byte, change ivfChange) []byte {
+	if change.vector == nil {
+		buf = append(buf, ivfLogDelete)
+	} else {
+		buf = append(buf, ivfLogAdd)
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(change.id)))
+	buf = append(buf, change.id...)
+	if change.vector != nil {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(change.vector)))
+		for _, value := range change.vector {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(value))
+		}
+	}
+	return buf
+}
+
+// replay applies the log of the index loaded from path. A log of another
+// generation was left by an older save and is ignored, a record cut short
+// by a crash ends it.
+func (ivf *IVF) replay(path string) error {
+	data, err := os.ReadFile(path + ivfLogSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) < 8 || binary.LittleEndian.Uint64(data) != ivf.generation {
+		return nil
+	}
+
+	data = data[8:]
+	for len(data) >= 5 {
+		op := data[0]
+		idLen := int(binary.LittleEndian.Uint32(data[1:]))
+		data = data[5:]
+		if len(data) < idLen {
+			return nil
+		}
+		id := string(data[:idLen])
+		data = data[idLen:]
+
+		switch op {
+		case ivfLogDelete:
+			ivf.remove(id)
+		case ivfLogAdd:
+			if len(data) < 4 {
+				return nil
+			}
+			n := int(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+			if len(data) < 4*n {
+				return nil
+			}
+			vector := make([]float32, n)
+			for i := range vector {
+				vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+			}
+			data = data[4*n:]
+			if err := ivf.add(id, vector); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown log record %d", op)
+		}
+	}
+	return nil
+}
+
+func (ivf *IVF) save(path string) error {
+	generation := ivf.generation + 1
+	tempPath := path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %v", tempPath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	w := bufio.NewWriter(file)
+	write := func(data any) {
+		if err == nil {
+			err = binary.Write(w, binary.LittleEndian, data)
+		}
+	}
+	writePostings := func(list int32, postings []ivfPosting) {
+		for _, posting := range postings {
+			write(list)
+			write(uint32(len(posting.id)))
+			write([]byte(posting.id))
+			// assigned postings of IVF-PQ are codes, all others vectors
+			if posting.code != nil {
+				write(posting.code)
+			} else {
+				write(posting.vector)
+			}
+		}
+	}
+
+	write(ivfMagic)
+	write(ivfVersion)
+	write(generation)
+	write(uint32(ivf.dim))
+	write(uint32(len(ivf.centroids)))
+	for _, centroid := range ivf.centroids {
+		write(centroid)
+	}
+	if ivf.pq != nil {
+		write(uint8(1))
+		if err == nil {
+			err = ivf.pq.writeTo(w)
+		}
+	} else {
+		write(uint8(0))
+	}
+	write(uint32(len(ivf.where)))
+	writePostings(-1, ivf.unassigned)
+	for i, postings := range ivf.lists {
+		writePostings(int32(i), postings)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("could not write ivf index: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("could not rename temp file to ivf index: %v", err)
+	}
+	if err := os.Remove(path + ivfLogSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove ivf log: %v", err)
+	}
+	ivf.generation = generation
+	ivf.saved = true
+	ivf.changes = nil
+
+	return nil
+}
+
+// LoadIVF reads an index written by Save and applies the changes Persist
+// logged since. config supplies the search and training settings, the
+// centroids come from the file.
+func LoadIVF(path string, config IVFConfig) (*IVF, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open ivf index %s: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	r := bufio.NewReader(file)
+	read := func(data any) {
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, data)
+		}
+	}
+
+	var magic [4]byte
+	var version, dim, nlists, count uint32
+	// versions before 3 wrote float64s
+	readVector := func(vector []float32) {
+		if err == nil {
+			err = readFloats(r, vector, version < 3)
+		}
+	}
+	read(&magic)
+	read(&version)
+	if err == nil && (magic != ivfMagic || version == 0 || version > ivfVersion) {
+		return nil, fmt.Errorf("%s is not an ivf index of version %d or older", path, ivfVersion)
+	}
+	var generation uint64
+	if version >= 4 {
+		read(&generation)
+	}
+	read(&dim)
+	read(&nlists)
+
+	ivf := NewIVF(config)
+	ivf.generation = generation
+	ivf.dim = int(dim)
+	ivf.centroids = make([][]float32, nlists)
+	ivf.lists = make([][]ivfPosting, nlists)
+	for i := range ivf.centroids {
+		ivf.centroids[i] = make([]float32, dim)
+		readVector(ivf.centroids[i])
+	}
+	if version >= 2 {
+		var hasPQ uint8
+		read(&hasPQ)
+		if err == nil && hasPQ == 1 {
+			ivf.pq, err = readPQ(r, version < 3)
+		}
+	}
+
+	read(&count)
+	for i := uint32(0); i < count && err == nil; i++ {
+		var list int32
+		var idLen uint32
+		read(&list)
+		read(&idLen)
+		id := make([]byte, idLen)
+		if err == nil {
+			_, err = io.ReadFull(r, id)
+		}
+		if list >= int32(nlists) {
+			return nil, fmt.Errorf("ivf index %s files %s under missing list %d", path, id, list)
+		}
+		posting := ivfPosting{id: string(id)}
+		if list >= 0 && ivf.pq != nil {
+			posting.code = make([]byte, ivf.pq.Subspaces())
+			read(posting.code)
+		} else {
+			posting.vector = make([]float32, dim)
+			readVector(posting.vector)
+			posting.norm = float32(search.Norm(posting.vector))
+		}
+		if err != nil {
+			break
+		}
+
+		if list < 0 {
+			ivf.unassigned = append(ivf.unassigned, posting)
+		} else {
+			ivf.lists[list] = append(ivf.lists[list], posting)
+		}
+		ivf.where[posting.id] = int(list)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read ivf index %s: %v", path, err)
+	}
+
+	ivf.saved = true
+	if err := ivf.replay(path); err != nil {
+		return nil, fmt.Errorf("could not replay log of ivf index %s: %v", path, err)
+	}
+
+	return ivf, nil
+}
+
+// readFloats fills vector from r, converting from float64 when wide is set
+func readFloats(r io.Reader, vector []float32, wide bool) error {
+	if !wide {
+		return binary.Read(r, binary.LittleEndian, vector)
+	}
+	values := make([]float64, len(vector))
+	if err := binary.Read(r, binary.LittleEndian, values); err != nil {
+		return err
+	}
+	for i, value := range values {
+		vector[i] = float32(value)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type IVFTestSuite struct {
+	suite.Suite
+	vectors map[string][]float32
+	config  IVFConfig
+}
+
+func (s *IVFTestSuite) SetupTest() {
+	// four well separated blobs
+	rng := rand.New(rand.NewSource(7))
+	centers := [][]float32{{0, 0}, {10, 0}, {0, 10}, {10, 10}}
+	s.vectors = make(map[string][]float32)
+	for i := 0; i < 400; i++ {
+		center := centers[i%len(centers)]
+		s.vectors[fmt.Sprintf("v%d", i)] = []float32{
+			center[0] + float32(rng.NormFloat64()),
+			center[1] + float32(rng.NormFloat64()),
+		}
+	}
+
+	s.config = DefaultIVFConfig()
+	s.config.NLists = 4
+	s.config.NProbe = 1
+	s.config.TrainAfter = 0
+}
+
+func (s *IVFTestSuite) build() *IVF {
+	// insertion order decides the training sample, keep it fixed
+	ids := make([]string, 0, len(s.vectors))
+	for id := range s.vectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ivf := NewIVF(s.config)
+	for _, id := range ids {
+		require.NoError(s.T(), ivf.Add(id, s.vectors[id]))
+	}
+	return ivf
+}
+
+func (s *IVFTestSuite) exact(query []float32, k int) []string {
+	ids := make([]string, 0, len(s.vectors))
+	for id := range s.vectors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return squaredL2(s.vectors[ids[i]], query) < squaredL2(s.vectors[ids[j]], query)
+	})
+	return ids[:k]
+}
+
+func (s *IVFTestSuite) TestUntrainedSearchesEverything() {
+	ivf := s.build()
+	assert.False(s.T(), ivf.Trained())
+	assert.Len(s.T(), ivf.Probe([]float32{0, 0}, 1), len(s.vectors))
+}
+
+func (s *IVFTestSuite) TestTrainAndSearch() {
+	ivf := s.build()
+	require.NoError(s.T(), ivf.Train())
+	assert.True(s.T(), ivf.Trained())
+
+	query := []float32{9.5, 0.5}
+	// one list holds about a quarter of the vectors
+	assert.Less(s.T(), len(ivf.Probe(query, 1)), len(s.vectors)/2)
+
+	results, err := ivf.Search(query, 10, 1)
+	require.NoError(s.T(), err)
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	assert.ElementsMatch(s.T(), s.exact(query, 10), ids)
+
+	// probing every list is exact
+	results, err = ivf.Search(query, 10, 4)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), results, 10)
+}
+
+func (s *IVFTestSuite) TestTrainsItselfAndAssignsNewVectors() {
+	s.config.TrainAfter = 100
+	ivf := s.build()
+	assert.True(s.T(), ivf.Trained())
+	assert.Equal(s.T(), len(s.vectors), ivf.Len())
+
+	require.NoError(s.T(), ivf.Add("new", []float32{0.1, 10.2}))
+	results, err := ivf.Search([]float32{0, 10}, 400, 1)
+	require.NoError(s.T(), err)
+	found := false
+	for _, result := range results {
+		found = found || result.ID == "new"
+	}
+	assert.True(s.T(), found)
+
+	// replacing moves the vector to its new list
+	require.NoError(s.T(), ivf.Add("new", []float32{10, 10}))
+	assert.Equal(s.T(), len(s.vectors)+1, ivf.Len())
+	results, err = ivf.Search([]float32{10, 10}, 1, 1)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "new", results[0].ID)
+
+	ivf.Delete("new")
+	assert.Equal(s.T(), len(s.vectors), ivf.Len())
+
+	assert.Error(s.T(), ivf.Add("bad", []float32{1, 2, 3}))
+}
+
+func (s *IVFTestSuite) TestSaveAndLoad() {
+	ivf := s.build()
+	require.NoError(s.T(), ivf.Train())
+	require.NoError(s.T(), ivf.Add("late", []float32{5, 5}))
+
+	path := filepath.Join(s.T().TempDir(), "vectors.ivf")
+	require.NoError(s.T(), ivf.Save(path))
+
+	loaded, err := LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), loaded.Trained())
+	assert.Equal(s.T(), ivf.Len(), loaded.Len())
+
+	query := []float32{0.5, 9}
+	want, err := ivf.Search(query, 5, 2)
+	require.NoError(s.T(), err)
+	got, err := loaded.Search(query, 5, 2)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), want, got)
+
+	_, err = LoadIVF(filepath.Join(s.T().TempDir(), "missing.ivf"), s.config)
+	assert.Error(s.T(), err)
+}
+
+func (s *IVFTestSuite) TestPersistLogsChanges() {
+	ivf := s.build()
+	require.NoError(s.T(), ivf.Train())
+
+	path := filepath.Join(s.T().TempDir(), "vectors.ivf")
+	require.NoError(s.T(), ivf.Persist(path))
+	saved, err := os.ReadFile(path)
+	require.NoError(s.T(), err)
+	_, err = os.Stat(path + ivfLogSuffix)
+	assert.True(s.T(), os.IsNotExist(err), "the first persist writes the index whole")
+
+	require.NoError(s.T(), ivf.Add("late", []float32{5, 5}))
+	ivf.Delete("v0")
+	require.NoError(s.T(), ivf.Persist(path))
+	unchanged, err := os.ReadFile(path)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), saved, unchanged, "changes go to the log")
+
+	loaded, err := LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ivf.Len(), loaded.Len())
+	query := []float32{5, 5}
+	want, err := ivf.Search(query, 5, 4)
+	require.NoError(s.T(), err)
+	got, err := loaded.Search(query, 5, 4)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), want, got)
+	assert.Equal(s.T(), "late", got[0].ID)
+
+	// a log of an older save doesn't apply to the index saved after it
+	log, err := os.ReadFile(path + ivfLogSuffix)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), ivf.Save(path))
+	require.NoError(s.T(), os.WriteFile(path+ivfLogSuffix, log, 0644))
+	loaded, err = LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ivf.Len(), loaded.Len())
+
+	// once the log outgrows the index it is folded into it
+	for i := 0; i < 2*len(s.vectors); i++ {
+		require.NoError(s.T(), ivf.Add(fmt.Sprintf("more%d", i), []float32{1, 1}))
+		if i%50 == 0 {
+			require.NoError(s.T(), ivf.Persist(path))
+		}
+	}
+	require.NoError(s.T(), ivf.Persist(path))
+	info, err := os.Stat(path)
+	require.NoError(s.T(), err)
+	if log, err := os.Stat(path + ivfLogSuffix); err == nil {
+		assert.LessOrEqual(s.T(), log.Size(), info.Size())
+	}
+	loaded, err = LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ivf.Len(), loaded.Len())
+}
+
+func (s *IVFTestSuite) TestProductQuantization() {
+	s.config.PQ.Subspaces = 2
+	ivf := s.build()
+	require.NoError(s.T(), ivf.Train())
+	assert.True(s.T(), ivf.Quantized())
+	require.NoError(s.T(), ivf.Add("late", []float32{10, 10}))
+
+	query := []float32{10, 10}
+	scored, err := ivf.ProbeScores(query, 1, "l2")
+	require.NoError(s.T(), err)
+	require.NotEmpty(s.T(), scored)
+	for _, candidate := range scored {
+		assert.Nil(s.T(), candidate.Vector)
+	}
+
+	results, err := ivf.Search(query, 1, 1)
+	require.NoError(s.T(), err)
+	assert.InDelta(s.T(), 0, results[0].Distance, 0.5)
+
+	// the vectors are gone, retraining needs a new index
+	assert.Error(s.T(), ivf.Train())
+
+	path := filepath.Join(s.T().TempDir(), "vectors.ivf")
+	require.NoError(s.T(), ivf.Save(path))
+	loaded, err := LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), loaded.Quantized())
+	reloaded, err := loaded.ProbeScores(query, 1, "l2")
+	require.NoError(s.T(), err)
+	assert.ElementsMatch(s.T(), scored, reloaded)
+}
+
+func (s *IVFTestSuite) TestLoadFloat64Index() {
+	// a version 2 index, from before vectors were float32
+	var buf bytes.Buffer
+	for _, data := range []any{
+		ivfMagic, uint32(2), uint32(2), uint32(1),
+		[]float64{5, 5},
+		uint8(0),
+		uint32(2),
+		int32(0), uint32(1), []byte("a"), []float64{1, 2},
+		int32(-1), uint32(1), []byte("b"), []float64{3, 4},
+	} {
+		require.NoError(s.T(), binary.Write(&buf, binary.LittleEndian, data))
+	}
+	path := filepath.Join(s.T().TempDir(), "vectors.ivf")
+	require.NoError(s.T(), os.WriteFile(path, buf.Bytes(), 0644))
+
+	ivf, err := LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), ivf.Trained())
+	assert.Equal(s.T(), 2, ivf.Len())
+	results, err := ivf.Search([]float32{1, 2}, 2, 1)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 2)
+	assert.Equal(s.T(), "a", results[0].ID)
+	assert.Equal(s.T(), []float32{1, 2}, results[0].Vector)
+
+	// saving upgrades it to the current version
+	require.NoError(s.T(), ivf.Save(path))
+	data, err := os.ReadFile(path)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ivfVersion, binary.LittleEndian.Uint32(data[4:]))
+	reloaded, err := LoadIVF(path, s.config)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ivf.centroids, reloaded.centroids)
+}
+
+func TestIVFSuite(t *testing.T) {
+	suite.Run(t, new(IVFTestSuite))
+}
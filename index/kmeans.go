@@ -0,0 +1,122 @@
+package index
+
+import (
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+	"math/rand"
+)
+
+// kmeans clusters vectors into k centroids with Lloyd's algorithm, seeded
+// with k-means++ so that well separated clusters each get a centroid.
+// Clusters that end up empty are re-seeded with a random vector so all k
+// centroids stay useful.
+func kmeans(vectors [][]float32, k int, iterations int, rng *rand.Rand) [][]float32 {
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+	if k == 0 {
+		return [][]float32{}
+	}
+	dim := len(vectors[0])
+
+	centroids := seedCentroids(vectors, k, rng)
+
+	assignment := make([]int, len(vectors))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for i, vector := range vectors {
+			nearest := nearestCentroid(centroids, vector)
+			if nearest != assignment[i] {
+				assignment[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for i := range sums {
+			sums[i] = make([]float64, dim)
+		}
+		for i, vector := range vectors {
+			c := assignment[i]
+			counts[c]++
+			for d, value := range vector {
+				sums[c][d] += float64(value)
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				centroids[c] = append([]float32(nil), vectors[rng.Intn(len(vectors))]...)
+				continue
+			}
+			for d := range sums[c] {
+				centroids[c][d] = float32(sums[c][d] / float64(counts[c]))
+			}
+		}
+	}
+
+	return centroids
+}
+
+// seedCentroids picks k vectors as the first centroids, each with a
+// probability proportional to its squared distance from the centroids
+// picked before it (k-means++)
+func seedCentroids(vectors [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := make([][]float32, 0, k)
+	centroids = append(centroids, append([]float32(nil), vectors[rng.Intn(len(vectors))]...))
+
+	nearest := make([]float64, len(vectors))
+	for i, vector := range vectors {
+		nearest[i] = squaredL2(vector, centroids[0])
+	}
+	for len(centroids) < k {
+		total := 0.0
+		for _, distance := range nearest {
+			total += distance
+		}
+
+		pick := rng.Intn(len(vectors))
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, distance := range nearest {
+				target -= distance
+				if target < 0 {
+					pick = i
+					break
+				}
+			}
+		}
+
+		centroid := append([]float32(nil), vectors[pick]...)
+		centroids = append(centroids, centroid)
+		for i, vector := range vectors {
+			nearest[i] = math.Min(nearest[i], squaredL2(vector, centroid))
+		}
+	}
+
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid closest to vector
+func nearestCentroid(centroids [][]float32, vector []float32) int {
+	nearest := 0
+	best := math.Inf(1)
+	for i, centroid := range centroids {
+		distance := squaredL2(centroid, vector)
+		if distance < best {
+			best = distance
+			nearest = i
+		}
+	}
+	return nearest
+}
+
+func squaredL2(vec1, vec2 []float32) float64 {
+	return float64(search.SquaredL2Float32(vec1, vec2))
+}
@@ -0,0 +1,280 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// pqCentroids is how many centroids every subspace has, so that a code fits
+// in one byte per subspace
+const pqCentroids = 256
+
+type PQConfig struct {
+	// Subspaces is how many parts every vector is split into, and so how
+	// many bytes its code takes. Zero disables product quantization.
+	Subspaces int
+
+	// SampleSize caps how many vectors the codebooks are trained on
+	SampleSize int
+
+	// Iterations caps the k-means iterations per subspace
+	Iterations int
+
+	Seed int64
+}
+
+func DefaultPQConfig() PQConfig {
+	return PQConfig{
+		Subspaces:  16,
+		SampleSize: 65536,
+		Iterations: 20,
+		Seed:       42,
+	}
+}
+
+// PQ is a product quantization codec. Vectors are split into subspaces of
+// consecutive dimensions, and every part is replaced by the index of its
+// nearest centroid in that subspace's codebook, turning a vector of float32s
+// into one byte per subspace.
+type PQ struct {
+	dim int
+
+	// bounds[m] and bounds[m+1] delimit the dimensions of subspace m
+	bounds []int
+
+	// codebooks[m][c] is centroid c of subspace m
+	codebooks [][][]float32
+}
+
+// TrainPQ learns the codebooks from a sample of vectors. Dimensions that
+// don't divide evenly go to the first subspaces.
+func TrainPQ(vectors [][]float32, config PQConfig) (*PQ, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("cannot train product quantization without vectors")
+	}
+	dim := len(vectors[0])
+	if config.Subspaces <= 0 || config.Subspaces > dim {
+		return nil, fmt.Errorf("cannot split %d dimensions into %d subspaces", dim, config.Subspaces)
+	}
+	for _, vector := range vectors {
+		if len(vector) != dim {
+			return nil, fmt.Errorf("training vectors have %d and %d dimensions", dim, len(vector))
+		}
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	if config.SampleSize > 0 && len(vectors) > config.SampleSize {
+		sample := make([][]float32, config.SampleSize)
+		for i, pick := range rng.Perm(len(vectors))[:config.SampleSize] {
+			sample[i] = vectors[pick]
+		}
+		vectors = sample
+	}
+
+	pq := &PQ{
+		dim:       dim,
+		bounds:    make([]int, config.Subspaces+1),
+		codebooks: make([][][]float32, config.Subspaces),
+	}
+	for m := 0; m < config.Subspaces; m++ {
+		size := dim / config.Subspaces
+		if m < dim%config.Subspaces {
+			size++
+		}
+		pq.bounds[m+1] = pq.bounds[m] + size
+	}
+
+	for m := range pq.codebooks {
+		parts := make([][]float32, len(vectors))
+		for i, vector := range vectors {
+			parts[i] = vector[pq.bounds[m]:pq.bounds[m+1]]
+		}
+		pq.codebooks[m] = kmeans(parts, pqCentroids, config.Iterations, rng)
+	}
+
+	return pq, nil
+}
+
+// Dims is the length of the vectors the codec encodes
+func (pq *PQ) Dims() int {
+	return pq.dim
+}
+
+// Subspaces is the length of every code
+func (pq *PQ) Subspaces() int {
+	return len(pq.codebooks)
+}
+
+// Encode quantizes vector to one centroid index per subspace
+func (pq *PQ) Encode(vector []float32) ([]byte, error) {
+	if len(vector) != pq.dim {
+		return nil, fmt.Errorf("cannot encode a %d dimensional vector with a %d dimensional codec", len(vector), pq.dim)
+	}
+
+	code := make([]byte, len(pq.codebooks))
+	for m, codebook := range pq.codebooks {
+		code[m] = byte(nearestCentroid(codebook, vector[pq.bounds[m]:pq.bounds[m+1]]))
+	}
+	return code, nil
+}
+
+// Decode rebuilds the approximate vector a code stands for
+func (pq *PQ) Decode(code []byte) []float32 {
+	vector := make([]float32, 0, pq.dim)
+	for m, c := range code {
+		vector = append(vector, pq.codebooks[m][c]...)
+	}
+	return vector
+}
+
+// PQTable scores codes against one query without decoding them, by summing
+// per subspace partial results looked up from tables computed once for the
+// query (asymmetric distance computation)
+type PQTable struct {
+	metric string
+
+	// partial[m][c] is the squared distance (l2) or dot product (dot and
+	// cosine) between the query's part m and centroid c of subspace m
+	partial [][]float64
+
+	// norms[m][c] is the squared norm of centroid c of subspace m, which
+	// cosine needs for the norm of the decoded vector
+	norms [][]float64
+
+	queryNorm float64
+}
+
+// Table prepares scoring of codes against query with metric ("l2", "dot" or
+// "cosine"). Scores come out in the same units as the search package's
+// metric functions.
+func (pq *PQ) Table(query []float32, metric string) (*PQTable, error) {
+	if len(query) != pq.dim {
+		return nil, fmt.Errorf("cannot score a %d dimensional query with a %d dimensional codec", len(query), pq.dim)
+	}
+	switch metric {
+	case "l2", "dot", "cosine":
+	default:
+		return nil, fmt.Errorf("unknown metric %s", metric)
+	}
+
+	table := &PQTable{
+		metric:  metric,
+		partial: make([][]float64, len(pq.codebooks)),
+	}
+	if metric == "cosine" {
+		table.norms = make([][]float64, len(pq.codebooks))
+	}
+	for m, codebook := range pq.codebooks {
+		part := query[pq.bounds[m]:pq.bounds[m+1]]
+		table.partial[m] = make([]float64, len(codebook))
+		if metric == "cosine" {
+			table.norms[m] = make([]float64, len(codebook))
+		}
+		for c, centroid := range codebook {
+			if metric == "l2" {
+				table.partial[m][c] = squaredL2(part, centroid)
+				continue
+			}
+			table.partial[m][c] = search.Dot(part, centroid)
+			if metric == "cosine" {
+				table.norms[m][c] = search.Dot(centroid, centroid)
+			}
+		}
+		if metric == "cosine" {
+			table.queryNorm += search.Dot(part, part)
+		}
+	}
+	table.queryNorm = math.Sqrt(table.queryNorm)
+
+	return table, nil
+}
+
+// Score approximates the metric between the query and the vector code
+// stands for
+func (t *PQTable) Score(code []byte) float64 {
+	sum := 0.0
+	for m, c := range code {
+		sum += t.partial[m][c]
+	}
+
+	switch t.metric {
+	case "l2":
+		return math.Sqrt(sum)
+	case "cosine":
+		norm := 0.0
+		for m, c := range code {
+			norm += t.norms[m][c]
+		}
+		return sum / (t.queryNorm * math.Sqrt(norm))
+	default:
+		return sum
+	}
+}
+
+// writeTo saves the codebooks in the layout readPQ expects
+func (pq *PQ) writeTo(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(pq.dim)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(pq.codebooks))); err != nil {
+		return err
+	}
+	for m, codebook := range pq.codebooks {
+		if err := binary.Write(w, binary.LittleEndian, uint32(pq.bounds[m+1])); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(codebook))); err != nil {
+			return err
+		}
+		for _, centroid := range codebook {
+			if err := binary.Write(w, binary.LittleEndian, centroid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readPQ reads codebooks written by writeTo, or by its float64 version
+// when wide is set
+func readPQ(r io.Reader, wide bool) (*PQ, error) {
+	var dim, subspaces uint32
+	if err := binary.Read(r, binary.LittleEndian, &dim); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &subspaces); err != nil {
+		return nil, err
+	}
+
+	pq := &PQ{
+		dim:       int(dim),
+		bounds:    make([]int, subspaces+1),
+		codebooks: make([][][]float32, subspaces),
+	}
+	for m := range pq.codebooks {
+		var bound, centroids uint32
+		if err := binary.Read(r, binary.LittleEndian, &bound); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &centroids); err != nil {
+			return nil, err
+		}
+		pq.bounds[m+1] = int(bound)
+		if pq.bounds[m+1] <= pq.bounds[m] || pq.bounds[m+1] > pq.dim || centroids > pqCentroids {
+			return nil, fmt.Errorf("corrupt codebook for subspace %d", m)
+		}
+
+		pq.codebooks[m] = make([][]float32, centroids)
+		for c := range pq.codebooks[m] {
+			pq.codebooks[m][c] = make([]float32, pq.bounds[m+1]-pq.bounds[m])
+			if err := readFloats(r, pq.codebooks[m][c], wide); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return pq, nil
+}
@@ -0,0 +1,98 @@
+package index
+
+import (
+	"bytes"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"testing"
+)
+
+type PQTestSuite struct {
+	suite.Suite
+	vectors [][]float32
+	config  PQConfig
+}
+
+func (s *PQTestSuite) SetupTest() {
+	rng := rand.New(rand.NewSource(3))
+	s.vectors = make([][]float32, 1000)
+	for i := range s.vectors {
+		s.vectors[i] = make([]float32, 10)
+		for d := range s.vectors[i] {
+			s.vectors[i][d] = float32(rng.NormFloat64())
+		}
+	}
+
+	s.config = DefaultPQConfig()
+	s.config.Subspaces = 4
+}
+
+func (s *PQTestSuite) TestEncodeDecode() {
+	pq, err := TrainPQ(s.vectors, s.config)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 10, pq.Dims())
+	assert.Equal(s.T(), 4, pq.Subspaces())
+	// 10 dimensions split 3, 3, 2, 2
+	assert.Equal(s.T(), []int{0, 3, 6, 8, 10}, pq.bounds)
+
+	code, err := pq.Encode(s.vectors[0])
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), code, 4)
+
+	// reconstruction is much closer than an unrelated vector
+	decoded := pq.Decode(code)
+	assert.Len(s.T(), decoded, 10)
+	assert.Less(s.T(), search.L2(decoded, s.vectors[0]), search.L2(s.vectors[1], s.vectors[0])/2)
+
+	_, err = pq.Encode([]float32{1, 2})
+	assert.Error(s.T(), err)
+}
+
+func (s *PQTestSuite) TestTableMatchesDecodedVectors() {
+	pq, err := TrainPQ(s.vectors, s.config)
+	require.NoError(s.T(), err)
+	query := s.vectors[42]
+
+	for _, metric := range []string{"l2", "dot", "cosine"} {
+		table, err := pq.Table(query, metric)
+		require.NoError(s.T(), err)
+		scoreFn, err := search.MetricFunc[float32](metric)
+		require.NoError(s.T(), err)
+
+		for _, vector := range s.vectors[:20] {
+			code, err := pq.Encode(vector)
+			require.NoError(s.T(), err)
+			assert.InDelta(s.T(), scoreFn(query, pq.Decode(code)), table.Score(code), 1e-5, metric)
+		}
+	}
+
+	_, err = pq.Table(query, "maxsim")
+	assert.Error(s.T(), err)
+}
+
+func (s *PQTestSuite) TestInvalidConfig() {
+	s.config.Subspaces = 11
+	_, err := TrainPQ(s.vectors, s.config)
+	assert.Error(s.T(), err)
+
+	_, err = TrainPQ(nil, DefaultPQConfig())
+	assert.Error(s.T(), err)
+}
+
+func (s *PQTestSuite) TestSerialization() {
+	pq, err := TrainPQ(s.vectors, s.config)
+	require.NoError(s.T(), err)
+
+	var buf bytes.Buffer
+	require.NoError(s.T(), pq.writeTo(&buf))
+	loaded, err := readPQ(&buf, false)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), pq, loaded)
+}
+
+func TestPQSuite(t *testing.T) {
+	suite.Run(t, new(PQTestSuite))
+}
@@ -0,0 +1,134 @@
+package index
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"sort"
+)
+
+const (
+	// QuantizationNone keeps every vector at full precision
+	QuantizationNone = ""
+
+	// QuantizationInt8 keeps one int8 per dimension, scaled by the
+	// HNSWConfig.Scalar ranges, and compares queries to the decoded codes
+	QuantizationInt8 = "int8"
+
+	// QuantizationBinary keeps one sign bit per dimension and compares
+	// vectors by Hamming distance
+	QuantizationBinary = "binary"
+)
+
+// point is a vector the way the graph compares it to nodes: the full query
+// vector, plus its sign bits on a binary graph
+type point struct {
+	vector []float32
+	bits   []uint64
+}
+
+func (h *HNSW) point(vector []float32) point {
+	p := point{vector: vector}
+	if h.config.Quantization == QuantizationBinary {
+		p.bits = search.BinaryQuantize(vector)
+	}
+	return p
+}
+
+// nodePoint compares other nodes against n
+func (h *HNSW) nodePoint(n *node) point {
+	if h.config.Quantization == QuantizationBinary {
+		return point{bits: n.bits}
+	}
+	return point{vector: h.nodeVector(n)}
+}
+
+// nodeVector is n's vector, approximated from its code on a quantized graph
+func (h *HNSW) nodeVector(n *node) []float32 {
+	switch h.config.Quantization {
+	case QuantizationInt8:
+		return h.config.Scalar.Decode(n.scalar)
+	case QuantizationBinary:
+		return search.BinaryDecode(n.bits, h.dims)
+	default:
+		return n.vector
+	}
+}
+
+// encode fills in n's vector or code
+func (h *HNSW) encode(n *node, vector []float32) error {
+	h.nodesLock.Lock()
+	if h.dims == 0 {
+		h.dims = len(vector)
+	}
+	dims := h.dims
+	h.nodesLock.Unlock()
+	if len(vector) != dims {
+		return fmt.Errorf("vector for %s has %d dimensions, the graph holds %d", n.id, len(vector), dims)
+	}
+
+	switch h.config.Quantization {
+	case QuantizationNone:
+		n.vector = vector
+	case QuantizationInt8:
+		if h.config.Scalar == nil || len(h.config.Scalar.Min) != len(vector) {
+			return fmt.Errorf("int8 quantization needs a scalar quantizer trained on %d dimensional vectors", len(vector))
+		}
+		n.scalar = h.config.Scalar.Encode(vector)
+	case QuantizationBinary:
+		n.bits = search.BinaryQuantize(vector)
+	default:
+		return fmt.Errorf("unknown quantization %s", h.config.Quantization)
+	}
+	return nil
+}
+
+// distance from q to n: Euclidean, from the full query to the decoded code
+// with int8 quantization, or Hamming with binary quantization
+func (h *HNSW) distance(q point, n *node) float64 {
+	switch h.config.Quantization {
+	case QuantizationInt8:
+		return h.config.Scalar.L2(q.vector, n.scalar)
+	case QuantizationBinary:
+		return float64(search.Hamming(q.bits, n.bits))
+	default:
+		return h.distanceToNode(q.vector, n.vector)
+	}
+}
+
+// SearchRescored finds the k nearest neighbors on a quantized graph by
+// oversampling: it collects k*oversample candidates by their approximate
+// distance, then ranks them again by Euclidean distance to the full
+// precision vectors vectorOf returns. Candidates it has no vector for are
+// dropped. On a graph that isn't quantized it is Search.
+func (h *HNSW) SearchRescored(queryVector []float32, k int, oversample int, vectorOf func(id string) ([]float32, bool)) (SearchResults, error) {
+	if h.config.Quantization == QuantizationNone {
+		return h.Search(queryVector, k)
+	}
+	if oversample < 1 {
+		oversample = 1
+	}
+
+	candidates, err := h.Search(queryVector, k*oversample)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(SearchResults, 0, len(candidates))
+	for _, candidate := range candidates {
+		vector, ok := vectorOf(candidate.ID)
+		if !ok || len(vector) != len(queryVector) {
+			continue
+		}
+		results = append(results, SearchResult{
+			ID:       candidate.ID,
+			Distance: search.L2(queryVector, vector),
+			Vector:   vector,
+		})
+	}
+	sort.Stable(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results, nil
+}
@@ -9,7 +9,7 @@ import (
 type SearchResult struct {
 	ID       string
 	Distance float64
-	Vector   []float64
+	Vector   []float32
 }
 
 // SearchResults is a slice of search results that can be sorted
@@ -20,33 +20,34 @@ func (s SearchResults) Less(i, j int) bool { return s[i].Distance < s[j].Distanc
 func (s SearchResults) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // Search finds k nearest neighbors for the given query vector
-func (h *HNSW) Search(queryVector []float64, k int) (SearchResults, error) {
+func (h *HNSW) Search(queryVector []float32, k int) (SearchResults, error) {
 	return h.SearchWithAccuracy(queryVector, k, k*2)
 }
 
 // SearchWithAccuracy allows control over the search accuracy via ef parameter
-func (h *HNSW) SearchWithAccuracy(queryVector []float64, k, ef int) (SearchResults, error) {
+func (h *HNSW) SearchWithAccuracy(queryVector []float32, k, ef int) (SearchResults, error) {
 	h.lock.RLock()
 	defer h.lock.RUnlock()
 
 	// Handle empty index
-	if len(h.nodes) == 0 {
+	entryNode, exists := h.entry()
+	if !exists {
 		return SearchResults{}, nil
 	}
 
 	// Start from entry point
-	entryNode := h.nodes[h.entryPoint]
+	query := h.point(queryVector)
 	currNode := entryNode
-	currDist := h.distanceToNode(queryVector, entryNode.vector)
+	currDist := h.distance(query, entryNode)
 
 	// Search from top level to level 1
 	for level := entryNode.maxLevel; level >= 1; level-- {
 		// Greedy search within current level
-		currNode, currDist = h.searchAtLayer(queryVector, currNode, currDist, level)
+		currNode, currDist = h.searchAtLayer(query, currNode, currDist, level)
 	}
 
 	// Do a more thorough search at layer 0
-	candidates := h.searchLayer(queryVector, currNode, 0, ef)
+	candidates := h.searchLayer(query, currNode, 0, ef, true)
 
 	// Convert candidates to SearchResults
 	results := make(SearchResults, 0, len(candidates))
@@ -57,7 +58,7 @@ func (h *HNSW) SearchWithAccuracy(queryVector []float64, k, ef int) (SearchResul
 		results = append(results, SearchResult{
 			ID:       candidate.id,
 			Distance: candidate.distance,
-			Vector:   candidate.node.vector,
+			Vector:   h.nodeVector(candidate.node),
 		})
 	}
 
@@ -72,9 +73,86 @@ func (h *HNSW) SearchWithAccuracy(queryVector []float64, k, ef int) (SearchResul
 	return results, nil
 }
 
+// SearchRadius finds the vectors within radius (Euclidean distance, or
+// Hamming distance on a binary graph) of the query, closest first. It is approximate: the ef nearest candidates of a
+// regular search seed a walk over layer 0 that follows every neighbor inside
+// the radius, so vectors inside the radius that are only reachable through
+// nodes outside it can be missed. A larger ef seeds the walk more widely.
+func (h *HNSW) SearchRadius(queryVector []float32, radius float64, ef int) (SearchResults, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	entryNode, exists := h.entry()
+	if !exists {
+		return SearchResults{}, nil
+	}
+
+	query := h.point(queryVector)
+	currNode := entryNode
+	currDist := h.distance(query, entryNode)
+	for level := entryNode.maxLevel; level >= 1; level-- {
+		currNode, currDist = h.searchAtLayer(query, currNode, currDist, level)
+	}
+
+	visited := make(map[string]bool)
+	results := make(SearchResults, 0)
+	frontier := make([]*node, 0)
+	// deleted nodes inside the radius are walked through, but not returned
+	for _, candidate := range h.searchLayer(query, currNode, 0, ef, false) {
+		visited[candidate.id] = true
+		if candidate.distance <= radius {
+			if !candidate.node.deleted {
+				results = append(results, SearchResult{
+					ID:       candidate.id,
+					Distance: candidate.distance,
+					Vector:   h.nodeVector(candidate.node),
+				})
+			}
+			frontier = append(frontier, candidate.node)
+		}
+	}
+
+	// breadth-first over the part of the graph inside the radius
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+
+		current.lock.RLock()
+		neighbors := current.neighbors[0]
+		current.lock.RUnlock()
+
+		for _, neighborID := range neighbors {
+			if neighborID == "" || visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+
+			neighbor, exists := h.neighbor(neighborID, 0)
+			if !exists {
+				continue
+			}
+			distance := h.distance(query, neighbor)
+			if distance <= radius {
+				if !neighbor.deleted {
+					results = append(results, SearchResult{
+						ID:       neighborID,
+						Distance: distance,
+						Vector:   h.nodeVector(neighbor),
+					})
+				}
+				frontier = append(frontier, neighbor)
+			}
+		}
+	}
+
+	sort.Stable(results)
+
+	return results, nil
+}
+
 // searchAtLayer performs a greedy search within a single layer
 // returns the closest node found and its distance
-func (h *HNSW) searchAtLayer(queryVector []float64, entryNode *node,
+func (h *HNSW) searchAtLayer(query point, entryNode *node,
 	entryDist float64, level int) (*node, float64) {
 
 	currNode := entryNode
@@ -89,8 +167,11 @@ func (h *HNSW) searchAtLayer(queryVector []float64, entryNode *node,
 		currNode.lock.RUnlock()
 
 		for _, neighborID := range neighbors {
-			neighbor := h.nodes[neighborID]
-			distance := h.distanceToNode(queryVector, neighbor.vector)
+			neighbor, exists := h.neighbor(neighborID, level)
+			if !exists {
+				continue
+			}
+			distance := h.distance(query, neighbor)
 
 			// If we found a closer neighbor, move to it
 			if distance < currDist {
@@ -111,7 +192,7 @@ func (h *HNSW) searchAtLayer(queryVector []float64, entryNode *node,
 }
 
 // BatchSearch performs multiple searches in parallel
-func (h *HNSW) BatchSearch(queryVectors [][]float64, k int) ([]SearchResults, error) {
+func (h *HNSW) BatchSearch(queryVectors [][]float32, k int) ([]SearchResults, error) {
 	results := make([]SearchResults, len(queryVectors))
 	errors := make([]error, len(queryVectors))
 
@@ -121,7 +202,7 @@ func (h *HNSW) BatchSearch(queryVectors [][]float64, k int) ([]SearchResults, er
 
 	// Process each query vector in parallel
 	for i, queryVector := range queryVectors {
-		go func(idx int, query []float64) {
+		go func(idx int, query []float32) {
 			defer wg.Done()
 			results[idx], errors[idx] = h.Search(query, k)
 		}(i, queryVector)
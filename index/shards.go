@@ -0,0 +1,277 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// shardIterations caps the k-means iterations that split a build into shards
+const shardIterations = 10
+
+// vectorSource is where a disk index build reads its vectors from
+type vectorSource interface {
+	Len() int
+	Dims() int
+	Vector(i int) ([]float32, error)
+}
+
+// memoryVectors are the vectors of a build that fits in memory
+type memoryVectors [][]float32
+
+func (m memoryVectors) Len() int {
+	return len(m)
+}
+
+func (m memoryVectors) Dims() int {
+	return len(m[0])
+}
+
+func (m memoryVectors) Vector(i int) ([]float32, error) {
+	return m[i], nil
+}
+
+// VectorFile spills the vectors of a disk index build to a flat file, so a
+// build over more vectors than fit in memory only holds the shard it is
+// working on. Vectors are appended once, then read back by position. It is
+// not safe for concurrent use.
+type VectorFile struct {
+	file *os.File
+	w    *bufio.Writer
+	dim  int
+	n    int
+}
+
+// CreateVectorFile creates an empty file at path for vectors of dim
+// dimensions
+func CreateVectorFile(path string, dim int) (*VectorFile, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("cannot store vectors of %d dimensions", dim)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s: %v", path, err)
+	}
+	return &VectorFile{file: file, w: bufio.NewWriter(file), dim: dim}, nil
+}
+
+// Append adds vector after the ones already in the file
+func (f *VectorFile) Append(vector []float32) error {
+	if len(vector) != f.dim {
+		return fmt.Errorf("cannot add a %d dimensional vector to a file of %d dimensional ones", len(vector), f.dim)
+	}
+	if err := binary.Write(f.w, binary.LittleEndian, vector); err != nil {
+		return fmt.Errorf("could not write to %s: %v", f.file.Name(), err)
+	}
+	f.n++
+	return nil
+}
+
+// Len is the number of vectors in the file
+func (f *VectorFile) Len() int {
+	return f.n
+}
+
+// Dims is the length of the vectors in the file
+func (f *VectorFile) Dims() int {
+	return f.dim
+}
+
+// Vector reads back vector i
+func (f *VectorFile) Vector(i int) ([]float32, error) {
+	if i < 0 || i >= f.n {
+		return nil, fmt.Errorf("vector file has no vector %d", i)
+	}
+	if f.w.Buffered() > 0 {
+		if err := f.w.Flush(); err != nil {
+			return nil, fmt.Errorf("could not write to %s: %v", f.file.Name(), err)
+		}
+	}
+
+	buf := make([]byte, 4*f.dim)
+	if _, err := f.file.ReadAt(buf, int64(i)*int64(len(buf))); err != nil {
+		return nil, fmt.Errorf("could not read vector %d from %s: %v", i, f.file.Name(), err)
+	}
+	vector := make([]float32, f.dim)
+	for d := range vector {
+		vector[d] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*d:]))
+	}
+	return vector, nil
+}
+
+// Remove closes and deletes the file
+func (f *VectorFile) Remove() error {
+	_ = f.file.Close()
+	if err := os.Remove(f.file.Name()); err != nil {
+		return fmt.Errorf("could not remove %s: %v", f.file.Name(), err)
+	}
+	return nil
+}
+
+// loadVectors reads every vector of source into memory
+func loadVectors(source vectorSource) ([][]float32, error) {
+	if vectors, ok := source.(memoryVectors); ok {
+		return vectors, nil
+	}
+	return sampleVectors(source, source.Len(), nil)
+}
+
+// sampleVectors reads size vectors of source picked at random, or all of
+// them when it has no more than size
+func sampleVectors(source vectorSource, size int, rng *rand.Rand) ([][]float32, error) {
+	picks := make([]int, 0, min(size, source.Len()))
+	if size >= source.Len() {
+		for i := 0; i < source.Len(); i++ {
+			picks = append(picks, i)
+		}
+	} else {
+		picks = append(picks, rng.Perm(source.Len())[:size]...)
+		// in file order, so the reads are sequential
+		sort.Ints(picks)
+	}
+
+	sample := make([][]float32, len(picks))
+	for j, i := range picks {
+		vector, err := source.Vector(i)
+		if err != nil {
+			return nil, err
+		}
+		sample[j] = vector
+	}
+	return sample, nil
+}
+
+// buildShardedVamana builds the graph over more vectors than one shard
+// holds the way DiskANN does: k-means on sample splits the vectors into
+// overlapping shards, every vector going to the two nearest centroids whose
+// shards have room, a graph is built over each shard in turn, and the
+// graphs are merged by taking the union of every node's edges, pruned back
+// to Degree where the shards overlap. Only one shard's vectors are in
+// memory at a time, next to the merged edges. It returns the out-edges of
+// every vector and the one searches start from.
+func buildShardedVamana(source vectorSource, sample [][]float32, config DiskANNConfig) ([][]uint32, uint32, error) {
+	n := source.Len()
+	// every vector is in two shards, and a spare shard's worth of room
+	// leaves every vector two shards that aren't full
+	shards := (2*n+config.ShardSize-1)/config.ShardSize + 1
+	rng := rand.New(rand.NewSource(config.Seed))
+	centroids := kmeans(sample, shards, shardIterations, rng)
+	if len(centroids) < shards {
+		return nil, 0, fmt.Errorf("cannot split %d vectors into %d shards from a sample of %d", n, shards, len(sample))
+	}
+
+	members := make([][]uint32, shards)
+	mean := make([]float64, source.Dims())
+	order := make([]int, shards)
+	distances := make([]float64, shards)
+	for i := 0; i < n; i++ {
+		vector, err := source.Vector(i)
+		if err != nil {
+			return nil, 0, err
+		}
+		for d, value := range vector {
+			mean[d] += float64(value)
+		}
+
+		for c := range order {
+			order[c] = c
+			distances[c] = squaredL2(centroids[c], vector)
+		}
+		sort.Slice(order, func(a, b int) bool {
+			return distances[order[a]] < distances[order[b]]
+		})
+		placed := 0
+		for _, c := range order {
+			if len(members[c]) < config.ShardSize {
+				members[c] = append(members[c], uint32(i))
+				if placed++; placed == 2 {
+					break
+				}
+			}
+		}
+	}
+
+	neighbors := make([][]uint32, n)
+	for _, shard := range members {
+		if len(shard) == 0 {
+			continue
+		}
+		vectors := make([][]float32, len(shard))
+		for j, i := range shard {
+			vector, err := source.Vector(int(i))
+			if err != nil {
+				return nil, 0, err
+			}
+			vectors[j] = vector
+		}
+
+		graph := buildVamana(vectors, config)
+		for j, edges := range graph.neighbors {
+			p := shard[j]
+			for _, e := range edges {
+				if !containsID(neighbors[p], shard[e]) {
+					neighbors[p] = append(neighbors[p], shard[e])
+				}
+			}
+		}
+	}
+
+	for p, edges := range neighbors {
+		if len(edges) <= config.Degree {
+			continue
+		}
+		pruned, err := pruneMerged(source, uint32(p), edges, config)
+		if err != nil {
+			return nil, 0, err
+		}
+		neighbors[p] = pruned
+	}
+
+	center := make([]float32, len(mean))
+	for d := range mean {
+		center[d] = float32(mean[d] / float64(n))
+	}
+	start, closest := 0, math.Inf(1)
+	for i := 0; i < n; i++ {
+		vector, err := source.Vector(i)
+		if err != nil {
+			return nil, 0, err
+		}
+		if distance := squaredL2(center, vector); distance < closest {
+			start, closest = i, distance
+		}
+	}
+
+	return neighbors, uint32(start), nil
+}
+
+// pruneMerged prunes the merged out-edges of p back to Degree, reading only
+// the vectors of p and its edges
+func pruneMerged(source vectorSource, p uint32, edges []uint32, config DiskANNConfig) ([]uint32, error) {
+	local := &vamana{
+		vectors:   make([][]float32, len(edges)+1),
+		neighbors: make([][]uint32, len(edges)+1),
+	}
+	candidates := make([]uint32, len(edges))
+	for j, id := range append([]uint32{p}, edges...) {
+		vector, err := source.Vector(int(id))
+		if err != nil {
+			return nil, err
+		}
+		local.vectors[j] = vector
+		if j > 0 {
+			candidates[j-1] = uint32(j)
+		}
+	}
+
+	local.robustPrune(0, candidates, config.Alpha, config.Degree)
+	pruned := make([]uint32, len(local.neighbors[0]))
+	for i, j := range local.neighbors[0] {
+		pruned[i] = edges[j-1]
+	}
+	return pruned, nil
+}
@@ -0,0 +1,152 @@
+package index
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// TextMatch is a document scored by keyword relevance
+type TextMatch struct {
+	ID    string
+	Score float64
+}
+
+// TextIndex is an inverted index over document text scored with Okapi
+// BM25. It complements vector search for exact identifiers, error codes and
+// names that embeddings tend to blur.
+type TextIndex struct {
+	// K1 controls term frequency saturation and B length normalization
+	K1 float64
+	B  float64
+
+	postings    map[string]map[string]int
+	terms       map[string][]string
+	lengths     map[string]int
+	totalLength int
+	lock        sync.RWMutex
+}
+
+func NewTextIndex() *TextIndex {
+	return &TextIndex{
+		K1:       1.2,
+		B:        0.75,
+		postings: make(map[string]map[string]int),
+		terms:    make(map[string][]string),
+		lengths:  make(map[string]int),
+	}
+}
+
+// Tokenize lowercases text and splits it on anything that isn't a letter
+// or digit, so "ERR-404" is indexed as "err" and "404"
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Add indexes or re-indexes the text of a document
+func (t *TextIndex) Add(id string, text string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.delete(id)
+
+	terms := Tokenize(text)
+	unique := make([]string, 0)
+	for _, term := range terms {
+		docs, ok := t.postings[term]
+		if !ok {
+			docs = make(map[string]int)
+			t.postings[term] = docs
+		}
+		if docs[id] == 0 {
+			unique = append(unique, term)
+		}
+		docs[id]++
+	}
+	t.terms[id] = unique
+	t.lengths[id] = len(terms)
+	t.totalLength += len(terms)
+}
+
+func (t *TextIndex) Delete(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.delete(id)
+}
+
+func (t *TextIndex) delete(id string) {
+	length, ok := t.lengths[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range t.terms[id] {
+		docs := t.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(t.postings, term)
+		}
+	}
+	delete(t.terms, id)
+	delete(t.lengths, id)
+	t.totalLength -= length
+}
+
+func (t *TextIndex) Len() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return len(t.lengths)
+}
+
+// Search returns the k documents with the highest BM25 score for query,
+// or every document sharing a term with it when k is 0
+func (t *TextIndex) Search(query string, k int) []TextMatch {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	n := float64(len(t.lengths))
+	if n == 0 {
+		return []TextMatch{}
+	}
+	avgLength := float64(t.totalLength) / n
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		docs := t.postings[term]
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range docs {
+			freq := float64(tf)
+			norm := t.K1 * (1 - t.B + t.B*float64(t.lengths[id])/avgLength)
+			scores[id] += idf * freq * (t.K1 + 1) / (freq + norm)
+		}
+	}
+
+	matches := make([]TextMatch, 0, len(scores))
+	for id, score := range scores {
+		matches = append(matches, TextMatch{ID: id, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+
+	return matches
+}
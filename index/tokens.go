@@ -0,0 +1,155 @@
+package index
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TokenMatch is a document scored by late interaction
+type TokenMatch struct {
+	ID    string
+	Score float64
+}
+
+// TokenIndex holds the per-token vectors of multi-vector (ColBERT style)
+// documents. Retrieval happens in two stages: every query token looks up
+// its nearest document tokens in an HNSW graph over all tokens, and only
+// the documents owning them are scored with the full MaxSim operator.
+type TokenIndex struct {
+	docs  map[string][][]float32
+	graph *HNSW
+	lock  sync.RWMutex
+}
+
+func NewTokenIndex() *TokenIndex {
+	// tokens such as punctuation repeat across documents, and the
+	// diversity heuristic would prune those copies away from each other
+	config := DefaultHNSWConfig()
+	config.KeepCloseNeighbors = true
+
+	return &TokenIndex{
+		docs:  make(map[string][][]float32),
+		graph: NewHNSW(config),
+	}
+}
+
+// tokenID names the i-th token of a document in the graph
+func tokenID(doc string, i int) string {
+	return doc + "\x00" + strconv.Itoa(i)
+}
+
+// tokenDoc is the document a token named by tokenID belongs to
+func tokenDoc(id string) string {
+	return id[:strings.LastIndexByte(id, 0)]
+}
+
+// Add stores or replaces the token vectors of a document
+func (t *TokenIndex) Add(id string, vectors [][]float32) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.remove(id)
+
+	// the graph ranks by Euclidean distance, which on unit vectors orders
+	// tokens the same as their dot product
+	ids := make([]string, len(vectors))
+	normalized := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		ids[i] = tokenID(id, i)
+		normalized[i] = search.Normalize(append([]float32(nil), vector...))
+	}
+	if err := t.graph.InsertBatch(ids, normalized, 0); err != nil {
+		for _, tokenID := range ids {
+			_ = t.graph.Delete(tokenID)
+		}
+		return fmt.Errorf("could not index the tokens of %s: %v", id, err)
+	}
+	t.docs[id] = vectors
+
+	return nil
+}
+
+func (t *TokenIndex) Delete(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.remove(id)
+}
+
+// remove drops a document and its tokens, the caller must hold the lock
+func (t *TokenIndex) remove(id string) {
+	for i := range t.docs[id] {
+		_ = t.graph.Delete(tokenID(id, i))
+	}
+	delete(t.docs, id)
+}
+
+func (t *TokenIndex) Len() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return len(t.docs)
+}
+
+// Candidates returns the documents owning the perToken nearest document
+// tokens of each query token
+func (t *TokenIndex) Candidates(query [][]float32, perToken int) ([]string, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.candidates(query, perToken)
+}
+
+func (t *TokenIndex) candidates(query [][]float32, perToken int) ([]string, error) {
+	seen := make(map[string]bool)
+	candidates := make([]string, 0)
+	for _, q := range query {
+		tokens, err := t.graph.Search(search.Normalize(append([]float32(nil), q...)), perToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, token := range tokens {
+			doc := tokenDoc(token.ID)
+			if !seen[doc] {
+				seen[doc] = true
+				candidates = append(candidates, doc)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// Search scores the candidates of query with MaxSim and returns the best k,
+// highest score first. candidatesPerToken trades recall for speed, as more
+// document tokens per query token bring in more documents to score.
+func (t *TokenIndex) Search(query [][]float32, k int, candidatesPerToken int) ([]TokenMatch, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	candidates, err := t.candidates(query, candidatesPerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]TokenMatch, 0, len(candidates))
+	for _, id := range candidates {
+		matches = append(matches, TokenMatch{
+			ID:    id,
+			Score: search.MaxSim(query, t.docs[id]),
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"testing"
+)
+
+type TokenIndexTestSuite struct {
+	suite.Suite
+	docs   map[string][][]float32
+	tokens *TokenIndex
+	rng    *rand.Rand
+}
+
+func (s *TokenIndexTestSuite) randomToken() []float32 {
+	vector := make([]float32, 16)
+	for i := range vector {
+		vector[i] = float32(s.rng.NormFloat64())
+	}
+	return search.Normalize(vector)
+}
+
+func (s *TokenIndexTestSuite) SetupTest() {
+	s.rng = rand.New(rand.NewSource(5))
+	s.docs = make(map[string][][]float32)
+	s.tokens = NewTokenIndex()
+	for i := 0; i < 200; i++ {
+		doc := make([][]float32, 8)
+		for j := range doc {
+			doc[j] = s.randomToken()
+		}
+		id := fmt.Sprintf("d%d", i)
+		s.docs[id] = doc
+		require.NoError(s.T(), s.tokens.Add(id, doc))
+	}
+}
+
+// exhaustive ranks every document by MaxSim
+func (s *TokenIndexTestSuite) exhaustive(query [][]float32) string {
+	best, bestScore := "", 0.0
+	for id, doc := range s.docs {
+		if score := search.MaxSim(query, doc); best == "" || score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best
+}
+
+func (s *TokenIndexTestSuite) TestCandidatesAreASubset() {
+	hits := 0
+	for i := 0; i < 20; i++ {
+		// a query made of noisy copies of some of one document's tokens
+		target := s.docs[fmt.Sprintf("d%d", i*7)]
+		query := make([][]float32, 4)
+		for j := range query {
+			query[j] = make([]float32, len(target[j]))
+			for d := range query[j] {
+				query[j][d] = target[j][d] + 0.1*float32(s.rng.NormFloat64())
+			}
+		}
+
+		// only the documents of the nearest tokens are scored
+		candidates, err := s.tokens.Candidates(query, 5)
+		require.NoError(s.T(), err)
+		assert.LessOrEqual(s.T(), len(candidates), 4*5)
+		assert.Less(s.T(), len(candidates), len(s.docs))
+
+		matches, err := s.tokens.Search(query, 3, 5)
+		require.NoError(s.T(), err)
+		require.NotEmpty(s.T(), matches)
+		assert.LessOrEqual(s.T(), len(matches), 3)
+		if matches[0].ID == s.exhaustive(query) {
+			hits++
+		}
+	}
+	assert.GreaterOrEqual(s.T(), hits, 19)
+}
+
+func (s *TokenIndexTestSuite) TestReplaceAndDelete() {
+	query := [][]float32{s.docs["d0"][0]}
+
+	matches, err := s.tokens.Search(query, 1, 5)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "d0", matches[0].ID)
+
+	// replaced and deleted documents no longer match through their old
+	// tokens
+	require.NoError(s.T(), s.tokens.Add("d0", [][]float32{s.randomToken()}))
+	s.tokens.Delete("d1")
+	assert.Equal(s.T(), 199, s.tokens.Len())
+	candidates, err := s.tokens.Candidates([][]float32{s.docs["d1"][0]}, 5)
+	require.NoError(s.T(), err)
+	assert.NotContains(s.T(), candidates, "d1")
+
+	// tokens must match the graph's dimensions
+	assert.Error(s.T(), s.tokens.Add("short", [][]float32{{1, 0}}))
+	assert.Equal(s.T(), 199, s.tokens.Len())
+}
+
+func TestTokenIndexSuite(t *testing.T) {
+	suite.Run(t, new(TokenIndexTestSuite))
+}
@@ -0,0 +1,183 @@
+package index
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// vamana is the single layer graph BuildDiskANN lays out on disk. It is
+// built in memory, over the full vectors, before being written out.
+type vamana struct {
+	vectors [][]float32
+
+	// neighbors[i] are the out-edges of vector i, at most Degree of them
+	neighbors [][]uint32
+
+	// medoid is where every search starts
+	medoid uint32
+}
+
+// candidate is an entry in the sorted list of a graph search
+type candidate struct {
+	id       uint32
+	distance float64
+	expanded bool
+}
+
+// insertCandidate adds c to the list sorted by distance, keeping at most
+// size entries
+func insertCandidate(list []candidate, c candidate, size int) []candidate {
+	if len(list) >= size && c.distance >= list[len(list)-1].distance {
+		return list
+	}
+	i := sort.Search(len(list), func(i int) bool {
+		return list[i].distance > c.distance
+	})
+	if len(list) < size {
+		list = append(list, candidate{})
+	}
+	copy(list[i+1:], list[i:])
+	list[i] = c
+	return list
+}
+
+// buildVamana starts from a random regular graph and refines it in two
+// passes over the vectors in random order: each vector is searched for,
+// its out-edges are pruned from the nodes the search visited, and edges
+// back to it are added to its new neighbors. The first pass prunes with
+// alpha 1, the second with config.Alpha, which keeps some longer edges
+// so searches cross the graph in fewer hops.
+func buildVamana(vectors [][]float32, config DiskANNConfig) *vamana {
+	rng := rand.New(rand.NewSource(config.Seed))
+	g := &vamana{
+		vectors:   vectors,
+		neighbors: make([][]uint32, len(vectors)),
+		medoid:    medoid(vectors),
+	}
+
+	degree := config.Degree
+	if degree > len(vectors)-1 {
+		degree = len(vectors) - 1
+	}
+	for i := range g.neighbors {
+		g.neighbors[i] = make([]uint32, 0, config.Degree)
+		for len(g.neighbors[i]) < degree {
+			j := uint32(rng.Intn(len(vectors)))
+			if j != uint32(i) && !containsID(g.neighbors[i], j) {
+				g.neighbors[i] = append(g.neighbors[i], j)
+			}
+		}
+	}
+
+	for _, alpha := range []float64{1, config.Alpha} {
+		for _, i := range rng.Perm(len(vectors)) {
+			p := uint32(i)
+			g.robustPrune(p, g.greedySearch(vectors[p], config.BuildList), alpha, config.Degree)
+			for _, j := range g.neighbors[p] {
+				if containsID(g.neighbors[j], p) {
+					continue
+				}
+				if len(g.neighbors[j]) < config.Degree {
+					g.neighbors[j] = append(g.neighbors[j], p)
+				} else {
+					g.robustPrune(j, []uint32{p}, alpha, config.Degree)
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// greedySearch walks the graph from the medoid towards query, keeping the
+// list closest nodes, and returns every node it expanded
+func (g *vamana) greedySearch(query []float32, list int) []uint32 {
+	seen := map[uint32]bool{g.medoid: true}
+	candidates := []candidate{{id: g.medoid, distance: squaredL2(query, g.vectors[g.medoid])}}
+	visited := make([]uint32, 0, list)
+
+	for {
+		next := -1
+		for i := range candidates {
+			if !candidates[i].expanded {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return visited
+		}
+		candidates[next].expanded = true
+		id := candidates[next].id
+		visited = append(visited, id)
+
+		for _, neighbor := range g.neighbors[id] {
+			if seen[neighbor] {
+				continue
+			}
+			seen[neighbor] = true
+			candidates = insertCandidate(candidates, candidate{id: neighbor, distance: squaredL2(query, g.vectors[neighbor])}, list)
+		}
+	}
+}
+
+// robustPrune picks at most degree out-edges for p among candidates and its
+// current neighbors. Closest first, a candidate is kept unless a neighbor
+// already kept is more than alpha times closer to it than p is.
+func (g *vamana) robustPrune(p uint32, candidates []uint32, alpha float64, degree int) {
+	pool := make([]candidate, 0, len(candidates)+len(g.neighbors[p]))
+	seen := map[uint32]bool{p: true}
+	for _, ids := range [][]uint32{candidates, g.neighbors[p]} {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				pool = append(pool, candidate{id: id, distance: squaredL2(g.vectors[p], g.vectors[id])})
+			}
+		}
+	}
+	sort.Slice(pool, func(i, j int) bool {
+		return pool[i].distance < pool[j].distance
+	})
+
+	// distances are squared, so alpha is too
+	alpha *= alpha
+	selected := make([]uint32, 0, degree)
+	for len(pool) > 0 && len(selected) < degree {
+		closest := pool[0]
+		selected = append(selected, closest.id)
+
+		kept := pool[:0]
+		for _, c := range pool[1:] {
+			if alpha*squaredL2(g.vectors[closest.id], g.vectors[c.id]) > c.distance {
+				kept = append(kept, c)
+			}
+		}
+		pool = kept
+	}
+
+	g.neighbors[p] = selected
+}
+
+// medoid returns the vector closest to the mean of all of them
+func medoid(vectors [][]float32) uint32 {
+	mean := make([]float64, len(vectors[0]))
+	for _, vector := range vectors {
+		for d, value := range vector {
+			mean[d] += float64(value)
+		}
+	}
+	center := make([]float32, len(mean))
+	for d := range mean {
+		center[d] = float32(mean[d] / float64(len(vectors)))
+	}
+	return uint32(nearestCentroid(vectors, center))
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
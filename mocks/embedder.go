@@ -10,3 +10,12 @@ func (m *MockEmbedder) Embed(text string) ([]float64, error) {
 	args := m.Called(text)
 	return args.Get(0).([]float64), args.Error(1)
 }
+
+type MockMultiVectorEmbedder struct {
+	MockEmbedder
+}
+
+func (m *MockMultiVectorEmbedder) EmbedMulti(text string) ([][]float64, error) {
+	args := m.Called(text)
+	return args.Get(0).([][]float64), args.Error(1)
+}
@@ -0,0 +1,157 @@
+package cohere
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/rerank"
+	"github.com/valyala/fasthttp"
+	"os"
+	"strings"
+)
+
+const (
+	defaultModel     = "rerank-v3.5"
+	defaultBaseUrl   = "https://api.cohere.com/v2"
+	defaultAPIKeyEnv = "COHERE_API_KEY"
+
+	jinaModel     = "jina-reranker-v2-base-multilingual"
+	jinaBaseUrl   = "https://api.jina.ai/v1"
+	jinaAPIKeyEnv = "JINA_API_KEY"
+)
+
+// Config describes a rerank endpoint speaking Cohere's rerank API, which
+// Jina, Voyage and most self-hosted rerank servers (such as Hugging Face
+// text-embeddings-inference behind a shim) accept as well.
+type Config struct {
+	// BaseURL is the API root, "/rerank" is appended to it
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Model is sent as the "model" field of every request
+	Model string `json:"model,omitempty"`
+
+	// APIKey takes precedence over APIKeyEnv. It is never serialized.
+	APIKey string `json:"-"`
+
+	// APIKeyEnv names the environment variable holding the key
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+
+	// SkipAuth sends no credentials at all, for local servers that don't check them
+	SkipAuth bool `json:"skip_auth,omitempty"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:   defaultBaseUrl,
+		Model:     defaultModel,
+		APIKeyEnv: defaultAPIKeyEnv,
+	}
+}
+
+// JinaConfig points at Jina's hosted reranker
+func JinaConfig() Config {
+	return Config{
+		BaseURL:   jinaBaseUrl,
+		Model:     jinaModel,
+		APIKeyEnv: jinaAPIKeyEnv,
+	}
+}
+
+func init() {
+	rerank.Register("cohere", DefaultConfig, func(cfg Config) (rerank.Reranker, error) {
+		return NewRerankerWithConfig(cfg)
+	})
+	rerank.Register("jina", JinaConfig, func(cfg Config) (rerank.Reranker, error) {
+		return NewRerankerWithConfig(cfg)
+	})
+}
+
+type Reranker struct {
+	apiKey   string
+	endpoint string
+	config   Config
+}
+
+func NewReranker() (*Reranker, error) {
+	return NewRerankerWithConfig(DefaultConfig())
+}
+
+func NewRerankerWithConfig(cfg Config) (*Reranker, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("no rerank endpoint configured")
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" && !cfg.SkipAuth {
+		var exists bool
+		apiKey, exists = os.LookupEnv(cfg.APIKeyEnv)
+		if !exists {
+			return nil, fmt.Errorf("%s not set", cfg.APIKeyEnv)
+		}
+	}
+
+	return &Reranker{
+		apiKey:   apiKey,
+		endpoint: strings.TrimSuffix(cfg.BaseURL, "/") + "/rerank",
+		config:   cfg,
+	}, nil
+}
+
+func (r *Reranker) Rerank(query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return []float64{}, nil
+	}
+
+	jsonBody, err := json.Marshal(RerankRequest{
+		Model:     r.config.Model,
+		Query:     query,
+		Documents: documents,
+		TopN:      len(documents),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(r.endpoint)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	req.SetBody(jsonBody)
+
+	if err := fasthttp.Do(req, resp); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode(), resp.Body())
+	}
+
+	var rerankResponse RerankResponse
+	if err := json.Unmarshal(resp.Body(), &rerankResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// results come back sorted by score, put them back in input order
+	scores := make([]float64, len(documents))
+	seen := make([]bool, len(documents))
+	for _, result := range rerankResponse.Results {
+		if result.Index < 0 || result.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank result index %d out of range for %d documents", result.Index, len(documents))
+		}
+		scores[result.Index] = result.RelevanceScore
+		seen[result.Index] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("rerank response is missing document %d", i)
+		}
+	}
+
+	return scores, nil
+}
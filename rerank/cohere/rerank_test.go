@@ -0,0 +1,103 @@
+package cohere
+
+import (
+	"encoding/json"
+	"github.com/ahhcash/ghastlydb/rerank"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type RerankerTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	lastReq  *http.Request
+	lastBody RerankRequest
+}
+
+// SetupTest starts a fake rerank server that scores documents by how many
+// query words they contain and answers best first, like the real APIs
+func (s *RerankerTestSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lastReq = r
+		_ = json.NewDecoder(r.Body).Decode(&s.lastBody)
+
+		type result struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		}
+		results := make([]result, 0)
+		for i, doc := range s.lastBody.Documents {
+			score := 0.0
+			for _, word := range strings.Fields(s.lastBody.Query) {
+				if strings.Contains(doc, word) {
+					score++
+				}
+			}
+			results = append([]result{{Index: i, RelevanceScore: score}}, results...)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+}
+
+func (s *RerankerTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *RerankerTestSuite) TestRerank() {
+	r, err := NewRerankerWithConfig(Config{
+		BaseURL: s.server.URL + "/v1/",
+		Model:   "test-reranker",
+		APIKey:  "secret",
+	})
+	require.NoError(s.T(), err)
+
+	scores, err := r.Rerank("red apple", []string{"green pear", "red apple pie", "red car"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0, 2, 1}, scores)
+
+	assert.Equal(s.T(), "/v1/rerank", s.lastReq.URL.Path)
+	assert.Equal(s.T(), "Bearer secret", s.lastReq.Header.Get("Authorization"))
+	assert.Equal(s.T(), "test-reranker", s.lastBody.Model)
+	assert.Equal(s.T(), 3, s.lastBody.TopN)
+
+	scores, err = r.Rerank("red apple", nil)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), scores)
+}
+
+func (s *RerankerTestSuite) TestRegistered() {
+	_ = os.Unsetenv("JINA_API_KEY")
+	_, err := rerank.New("jina", nil)
+	assert.Error(s.T(), err)
+
+	r, err := rerank.New("jina", map[string]any{"base_url": s.server.URL, "skip_auth": true})
+	require.NoError(s.T(), err)
+	_, err = r.Rerank("query", []string{"query document"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), jinaModel, s.lastBody.Model)
+	assert.Empty(s.T(), s.lastReq.Header.Get("Authorization"))
+
+	assert.Contains(s.T(), rerank.Registered(), "cohere")
+}
+
+func (s *RerankerTestSuite) TestServerError() {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+
+	r, err := NewRerankerWithConfig(Config{BaseURL: failing.URL, SkipAuth: true})
+	require.NoError(s.T(), err)
+	_, err = r.Rerank("query", []string{"document"})
+	assert.Error(s.T(), err)
+}
+
+func TestRerankerSuite(t *testing.T) {
+	suite.Run(t, new(RerankerTestSuite))
+}
@@ -0,0 +1,158 @@
+package crossencoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/knights-analytics/hugot/pipelineBackends"
+)
+
+// pairPiece is one part of an encoded pair: the ids of a special token, or
+// the tokens of the query ("A") or the document ("B")
+type pairPiece struct {
+	sequence string
+	ids      []uint32
+	typeID   uint32
+}
+
+// pairTemplate is how a tokenizer joins two texts into one sequence, such
+// as "[CLS] A [SEP] B [SEP]" with type id 1 for B on BERT models
+type pairTemplate []pairPiece
+
+// postProcessor is the post_processor of a tokenizer.json
+type postProcessor struct {
+	Type string `json:"type"`
+
+	// TemplateProcessing
+	Pair []struct {
+		SpecialToken *templatePiece `json:"SpecialToken"`
+		Sequence     *templatePiece `json:"Sequence"`
+	} `json:"pair"`
+	SpecialTokens map[string]struct {
+		IDs []uint32 `json:"ids"`
+	} `json:"special_tokens"`
+
+	// BertProcessing and RobertaProcessing, as [token, id]
+	Cls []any `json:"cls"`
+	Sep []any `json:"sep"`
+
+	// Sequence
+	Processors []postProcessor `json:"processors"`
+}
+
+type templatePiece struct {
+	ID     string `json:"id"`
+	TypeID uint32 `json:"type_id"`
+}
+
+// parseTemplate reads the pair template from a tokenizer.json
+func parseTemplate(tokenizerJSON []byte) (pairTemplate, error) {
+	var tokenizer struct {
+		PostProcessor *postProcessor `json:"post_processor"`
+	}
+	if err := json.Unmarshal(tokenizerJSON, &tokenizer); err != nil {
+		return nil, err
+	}
+	if tokenizer.PostProcessor == nil {
+		return nil, fmt.Errorf("the tokenizer has no post processor to join a pair with")
+	}
+	return tokenizer.PostProcessor.template()
+}
+
+func (p postProcessor) template() (pairTemplate, error) {
+	switch p.Type {
+	case "TemplateProcessing":
+		template := make(pairTemplate, 0, len(p.Pair))
+		for _, piece := range p.Pair {
+			switch {
+			case piece.Sequence != nil:
+				template = append(template, pairPiece{sequence: piece.Sequence.ID, typeID: piece.Sequence.TypeID})
+			case piece.SpecialToken != nil:
+				special, exists := p.SpecialTokens[piece.SpecialToken.ID]
+				if !exists {
+					return nil, fmt.Errorf("special token %s of the pair template is not defined", piece.SpecialToken.ID)
+				}
+				template = append(template, pairPiece{ids: special.IDs, typeID: piece.SpecialToken.TypeID})
+			}
+		}
+		return template, nil
+	case "BertProcessing", "RobertaProcessing":
+		cls, err := tokenID(p.Cls)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cls token: %v", err)
+		}
+		sep, err := tokenID(p.Sep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sep token: %v", err)
+		}
+		if p.Type == "BertProcessing" {
+			return pairTemplate{
+				{ids: []uint32{cls}}, {sequence: "A"}, {ids: []uint32{sep}},
+				{sequence: "B", typeID: 1}, {ids: []uint32{sep}, typeID: 1},
+			}, nil
+		}
+		return pairTemplate{
+			{ids: []uint32{cls}}, {sequence: "A"}, {ids: []uint32{sep, sep}},
+			{sequence: "B"}, {ids: []uint32{sep}},
+		}, nil
+	case "Sequence":
+		for _, processor := range p.Processors {
+			if template, err := processor.template(); err == nil {
+				return template, nil
+			}
+		}
+		return nil, fmt.Errorf("no processor of the sequence joins pairs")
+	default:
+		return nil, fmt.Errorf("post processor %s is not supported", p.Type)
+	}
+}
+
+// tokenID reads the id of a [token, id] pair
+func tokenID(token []any) (uint32, error) {
+	if len(token) != 2 {
+		return 0, fmt.Errorf("expected [token, id], got %v", token)
+	}
+	id, ok := token[1].(float64)
+	if !ok || id < 0 {
+		return 0, fmt.Errorf("expected [token, id], got %v", token)
+	}
+	return uint32(id), nil
+}
+
+// encode joins the query's and the document's tokens into one input of at
+// most maxLength tokens, cutting the longer of the two first
+func (t pairTemplate) encode(query, document []uint32, maxLength int) pipelineBackends.TokenizedInput {
+	special := 0
+	for _, piece := range t {
+		special += len(piece.ids)
+	}
+	budget := maxLength - special
+	if budget < 0 {
+		budget = 0
+	}
+	for len(query)+len(document) > budget {
+		if len(document) >= len(query) {
+			document = document[:len(document)-1]
+		} else {
+			query = query[:len(query)-1]
+		}
+	}
+
+	var input pipelineBackends.TokenizedInput
+	for _, piece := range t {
+		ids := piece.ids
+		switch piece.sequence {
+		case "A":
+			ids = query
+		case "B":
+			ids = document
+		}
+		for _, id := range ids {
+			input.TokenIDs = append(input.TokenIDs, id)
+			input.TypeIDs = append(input.TypeIDs, piece.typeID)
+			input.AttentionMask = append(input.AttentionMask, 1)
+		}
+	}
+	input.MaxAttentionIndex = len(input.TokenIDs) - 1
+
+	return input
+}
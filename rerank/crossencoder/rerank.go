@@ -0,0 +1,204 @@
+package crossencoder
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed/local/onnx"
+	"github.com/ahhcash/ghastlydb/rerank"
+	"github.com/knights-analytics/hugot"
+	"github.com/knights-analytics/hugot/options"
+	"github.com/knights-analytics/hugot/pipelineBackends"
+	"github.com/knights-analytics/hugot/pipelines"
+	"os"
+	"path/filepath"
+)
+
+// Config describes a local cross-encoder such as
+// cross-encoder/ms-marco-MiniLM-L-6-v2, found and run through hugot like
+// the local embedders.
+type Config struct {
+	// Model is either a directory holding an ONNX model and its
+	// tokenizer.json, or a Hugging Face model id
+	Model string `json:"model"`
+
+	// CacheDir is where Hugging Face models are downloaded to and looked up
+	// in. Defaults to the local embedders' cache.
+	CacheDir string `json:"cache_dir,omitempty"`
+
+	// Offline never touches the network: Model must be a directory or
+	// already be present in CacheDir
+	Offline bool `json:"offline,omitempty"`
+
+	// OnnxFilename picks the model file when a repository ships several
+	OnnxFilename string `json:"onnx_filename,omitempty"`
+
+	// OnnxLibraryPath points at the ONNX runtime shared library. Defaults
+	// to the usual install location for the current OS.
+	OnnxLibraryPath string `json:"onnx_library_path,omitempty"`
+
+	// MaxLength caps the tokens of a query and document pair, special
+	// tokens included. The longer of the two is cut first.
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+func DefaultConfig() Config {
+	embedder := onnx.DefaultConfig()
+	return Config{
+		Model:           "cross-encoder/ms-marco-MiniLM-L-6-v2",
+		CacheDir:        embedder.CacheDir,
+		OnnxLibraryPath: embedder.OnnxLibraryPath,
+		MaxLength:       512,
+	}
+}
+
+func init() {
+	rerank.Register("local", DefaultConfig, func(cfg Config) (rerank.Reranker, error) {
+		return NewCrossEncoderWithConfig(cfg)
+	})
+}
+
+// tokenizer splits text into token ids, without special tokens when
+// addSpecialTokens is false. *tokenizers.Tokenizer implements it.
+type tokenizer interface {
+	Encode(text string, addSpecialTokens bool) ([]uint32, []string)
+}
+
+// classifier runs the model on encoded pairs and returns their logits
+type classifier interface {
+	Logits(pairs []pipelineBackends.TokenizedInput) ([][]float32, error)
+}
+
+// CrossEncoder scores query and document together with a sequence
+// classification model. The two are encoded as a real text pair, joined by
+// the tokenizer's own template with their token type ids, and the logits
+// are used as scores directly; models with two labels score by the last
+// ("relevant") one.
+type CrossEncoder struct {
+	tokenizer  tokenizer
+	template   pairTemplate
+	classifier classifier
+	maxLength  int
+}
+
+func NewCrossEncoder() (*CrossEncoder, error) {
+	return NewCrossEncoderWithConfig(DefaultConfig())
+}
+
+func NewCrossEncoderWithConfig(cfg Config) (*CrossEncoder, error) {
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultConfig().MaxLength
+	}
+
+	modelPath, err := onnx.ModelPath(onnx.Config{
+		Model:           cfg.Model,
+		CacheDir:        cfg.CacheDir,
+		Offline:         cfg.Offline,
+		OnnxLibraryPath: cfg.OnnxLibraryPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load cross-encoder %s: %v", cfg.Model, err)
+	}
+	tokenizerJSON, err := os.ReadFile(filepath.Join(modelPath, "tokenizer.json"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the tokenizer of cross-encoder %s: %v", cfg.Model, err)
+	}
+	template, err := parseTemplate(tokenizerJSON)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the pair template of cross-encoder %s: %v", cfg.Model, err)
+	}
+
+	libraryPath := cfg.OnnxLibraryPath
+	if libraryPath == "" {
+		libraryPath = onnx.DefaultConfig().OnnxLibraryPath
+	}
+	session, err := hugot.NewORTSession(options.WithOnnxLibraryPath(libraryPath))
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := hugot.NewPipeline(session, hugot.TextClassificationConfig{
+		ModelPath:    modelPath,
+		Name:         "RerankPipeline",
+		OnnxFilename: cfg.OnnxFilename,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load cross-encoder %s: %v", cfg.Model, err)
+	}
+	if pipeline.Model.Tokenizer == nil || pipeline.Model.Tokenizer.RustTokenizer == nil {
+		return nil, fmt.Errorf("cross-encoder %s has no tokenizer", cfg.Model)
+	}
+
+	return &CrossEncoder{
+		tokenizer:  pipeline.Model.Tokenizer.RustTokenizer.Tokenizer,
+		template:   template,
+		classifier: pipelineClassifier{pipeline: pipeline},
+		maxLength:  cfg.MaxLength,
+	}, nil
+}
+
+func (c *CrossEncoder) Rerank(query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return []float64{}, nil
+	}
+
+	queryIDs, _ := c.tokenizer.Encode(query, false)
+	pairs := make([]pipelineBackends.TokenizedInput, len(documents))
+	for i, document := range documents {
+		documentIDs, _ := c.tokenizer.Encode(document, false)
+		pairs[i] = c.template.encode(queryIDs, documentIDs, c.maxLength)
+	}
+
+	logits, err := c.classifier.Logits(pairs)
+	if err != nil {
+		return nil, err
+	}
+	if len(logits) != len(documents) {
+		return nil, fmt.Errorf("cross-encoder produced %d results for %d documents", len(logits), len(documents))
+	}
+
+	scores := make([]float64, len(documents))
+	for i, l := range logits {
+		if len(l) == 0 {
+			return nil, fmt.Errorf("cross-encoder produced no logits for document %d", i)
+		}
+		scores[i] = float64(l[len(l)-1])
+	}
+
+	return scores, nil
+}
+
+// pipelineClassifier runs encoded pairs through a text classification
+// pipeline, skipping its tokenizer (which only takes single texts) and its
+// softmax, so the raw logits come out
+type pipelineClassifier struct {
+	pipeline *pipelines.TextClassificationPipeline
+}
+
+func (p pipelineClassifier) Logits(pairs []pipelineBackends.TokenizedInput) ([][]float32, error) {
+	batch := pipelineBackends.NewBatch()
+	defer func() { _ = batch.Destroy() }()
+
+	batch.Input = pairs
+	for _, pair := range pairs {
+		if len(pair.TokenIDs) > batch.MaxSequenceLength {
+			batch.MaxSequenceLength = len(pair.TokenIDs)
+		}
+	}
+	if err := pipelineBackends.CreateInputTensors(batch, p.pipeline.Model.InputsMeta, p.pipeline.Runtime); err != nil {
+		return nil, err
+	}
+	if err := p.pipeline.Forward(batch); err != nil {
+		return nil, err
+	}
+
+	dimensions := p.pipeline.Model.OutputsMeta[0].Dimensions
+	labels := int(dimensions[len(dimensions)-1])
+	output := batch.OutputValues[0]
+	if labels <= 0 || len(output) != len(pairs)*labels {
+		return nil, fmt.Errorf("unexpected model output size %d for %d pairs", len(output), len(pairs))
+	}
+
+	logits := make([][]float32, len(pairs))
+	for i := range pairs {
+		logits[i] = output[i*labels : (i+1)*labels]
+	}
+	return logits, nil
+}
@@ -0,0 +1,148 @@
+package crossencoder
+
+import (
+	"github.com/knights-analytics/hugot/pipelineBackends"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+// bertTokenizerJSON is the post processor of a BERT cross-encoder such as
+// ms-marco-MiniLM-L-6-v2
+const bertTokenizerJSON = `{
+	"post_processor": {
+		"type": "TemplateProcessing",
+		"single": [],
+		"pair": [
+			{"SpecialToken": {"id": "[CLS]", "type_id": 0}},
+			{"Sequence": {"id": "A", "type_id": 0}},
+			{"SpecialToken": {"id": "[SEP]", "type_id": 0}},
+			{"Sequence": {"id": "B", "type_id": 1}},
+			{"SpecialToken": {"id": "[SEP]", "type_id": 1}}
+		],
+		"special_tokens": {
+			"[CLS]": {"id": "[CLS]", "ids": [101], "tokens": ["[CLS]"]},
+			"[SEP]": {"id": "[SEP]", "ids": [102], "tokens": ["[SEP]"]}
+		}
+	}
+}`
+
+// wordTokenizer gives every word an id from 1000 on
+type wordTokenizer map[string]uint32
+
+func (w wordTokenizer) Encode(text string, addSpecialTokens bool) ([]uint32, []string) {
+	words := strings.Fields(text)
+	ids := make([]uint32, len(words))
+	for i, word := range words {
+		if _, exists := w[word]; !exists {
+			w[word] = uint32(1000 + len(w))
+		}
+		ids[i] = w[word]
+	}
+	return ids, words
+}
+
+// overlapClassifier scores a pair by how many document tokens (type id 1)
+// also occur in the query (type id 0), so it only works on real pairs
+type overlapClassifier struct {
+	pairs []pipelineBackends.TokenizedInput
+}
+
+func (o *overlapClassifier) Logits(pairs []pipelineBackends.TokenizedInput) ([][]float32, error) {
+	o.pairs = pairs
+	logits := make([][]float32, len(pairs))
+	for i, pair := range pairs {
+		query := make(map[uint32]bool)
+		for j, id := range pair.TokenIDs {
+			if pair.TypeIDs[j] == 0 && id >= 1000 {
+				query[id] = true
+			}
+		}
+		score := float32(0)
+		for j, id := range pair.TokenIDs {
+			if pair.TypeIDs[j] == 1 && query[id] {
+				score++
+			}
+		}
+		logits[i] = []float32{-score, score}
+	}
+	return logits, nil
+}
+
+type CrossEncoderTestSuite struct {
+	suite.Suite
+	template pairTemplate
+}
+
+func (s *CrossEncoderTestSuite) SetupTest() {
+	template, err := parseTemplate([]byte(bertTokenizerJSON))
+	require.NoError(s.T(), err)
+	s.template = template
+}
+
+func (s *CrossEncoderTestSuite) TestEncodePair() {
+	pair := s.template.encode([]uint32{1, 2}, []uint32{3, 4, 5}, 512)
+	assert.Equal(s.T(), []uint32{101, 1, 2, 102, 3, 4, 5, 102}, pair.TokenIDs)
+	assert.Equal(s.T(), []uint32{0, 0, 0, 0, 1, 1, 1, 1}, pair.TypeIDs)
+	assert.Equal(s.T(), []uint32{1, 1, 1, 1, 1, 1, 1, 1}, pair.AttentionMask)
+	assert.Equal(s.T(), 7, pair.MaxAttentionIndex)
+
+	// the longer text is cut first, special tokens always fit
+	pair = s.template.encode([]uint32{1, 2}, []uint32{3, 4, 5, 6, 7}, 7)
+	assert.Equal(s.T(), []uint32{101, 1, 2, 102, 3, 4, 102}, pair.TokenIDs)
+	pair = s.template.encode([]uint32{1, 2, 3, 4}, []uint32{5}, 5)
+	assert.Equal(s.T(), []uint32{101, 1, 102, 5, 102}, pair.TokenIDs)
+}
+
+func (s *CrossEncoderTestSuite) TestParseTemplate() {
+	roberta := `{"post_processor": {"type": "RobertaProcessing", "sep": ["</s>", 2], "cls": ["<s>", 0]}}`
+	template, err := parseTemplate([]byte(roberta))
+	require.NoError(s.T(), err)
+	pair := template.encode([]uint32{7}, []uint32{8}, 512)
+	assert.Equal(s.T(), []uint32{0, 7, 2, 2, 8, 2}, pair.TokenIDs)
+	assert.Equal(s.T(), []uint32{0, 0, 0, 0, 0, 0}, pair.TypeIDs)
+
+	bert := `{"post_processor": {"type": "Sequence", "processors": [
+		{"type": "ByteLevel"},
+		{"type": "BertProcessing", "sep": ["[SEP]", 102], "cls": ["[CLS]", 101]}
+	]}}`
+	template, err = parseTemplate([]byte(bert))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), s.template.encode([]uint32{7}, []uint32{8}, 512), template.encode([]uint32{7}, []uint32{8}, 512))
+
+	_, err = parseTemplate([]byte(`{"post_processor": null}`))
+	assert.Error(s.T(), err)
+	_, err = parseTemplate([]byte(`{"post_processor": {"type": "ByteLevel"}}`))
+	assert.Error(s.T(), err)
+}
+
+func (s *CrossEncoderTestSuite) TestRerankScoresPairs() {
+	classifier := &overlapClassifier{}
+	encoder := &CrossEncoder{
+		tokenizer:  wordTokenizer{},
+		template:   s.template,
+		classifier: classifier,
+		maxLength:  512,
+	}
+
+	scores, err := encoder.Rerank("cats purr", []string{"dogs bark", "cats purr loudly", "a cat will purr"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0, 2, 1}, scores)
+
+	// query and document are separate segments, no literal separator text
+	require.Len(s.T(), classifier.pairs, 3)
+	second := classifier.pairs[1]
+	assert.Equal(s.T(), []uint32{0, 0, 0, 0, 1, 1, 1, 1}, second.TypeIDs)
+	assert.Equal(s.T(), uint32(101), second.TokenIDs[0])
+	assert.Equal(s.T(), uint32(102), second.TokenIDs[3])
+
+	scores, err = encoder.Rerank("cats", nil)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), scores)
+}
+
+func TestCrossEncoderSuite(t *testing.T) {
+	suite.Run(t, new(CrossEncoderTestSuite))
+}
@@ -0,0 +1,71 @@
+package rerank
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/embed"
+	"sort"
+	"sync"
+)
+
+// Reranker is a second retrieval stage: it reads the query together with
+// each candidate, which is slower than comparing vectors but considerably
+// more accurate, so it is only applied to the top candidates.
+type Reranker interface {
+	// Rerank returns a relevance score for every document, higher is more
+	// relevant
+	Rerank(query string, documents []string) ([]float64, error)
+}
+
+// provider builds a reranker from untyped options
+type provider func(options any) (Reranker, error)
+
+var (
+	providers    = map[string]provider{}
+	providerLock sync.RWMutex
+)
+
+// Register makes a reranker available by name to New and to
+// DBConfig.Reranker, the same way embed.Register does for embedding models.
+func Register[T any](name string, defaults func() T, build func(options T) (Reranker, error)) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("rerank: provider %s registered twice", name))
+	}
+
+	providers[name] = func(options any) (Reranker, error) {
+		typed := defaults()
+		if err := embed.DecodeOptions(options, &typed); err != nil {
+			return nil, fmt.Errorf("invalid options for reranker %s: %v", name, err)
+		}
+		return build(typed)
+	}
+}
+
+// New builds the reranker registered under name.
+func New(name string, options any) (Reranker, error) {
+	providerLock.RLock()
+	p, exists := providers[name]
+	providerLock.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("reranker %s not supported", name)
+	}
+
+	return p(options)
+}
+
+// Registered lists the names of all registered rerankers in sorted order.
+func Registered() []string {
+	providerLock.RLock()
+	defer providerLock.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -1,9 +1,16 @@
 package search
 
-func Dot(vec1 []float64, vec2 []float64) float64 {
+func Dot[T Float](vec1 []T, vec2 []T) float64 {
+	switch v1 := any(vec1).(type) {
+	case []float32:
+		return float64(DotFloat32(v1, any(vec2).([]float32)))
+	case []float64:
+		return dotFloat64(v1, any(vec2).([]float64))
+	}
+
 	dot := 0.0
 	for i := 0; i < len(vec1); i++ {
-		dot += vec1[i] * vec2[i]
+		dot += float64(vec1[i]) * float64(vec2[i])
 	}
 
 	return dot
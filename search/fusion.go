@@ -0,0 +1,42 @@
+package search
+
+// RRF fuses rankings with reciprocal rank fusion. Every ranking adds
+// 1/(k+rank) to the ids it contains, ranks starting at 1, so documents
+// ranked well by several retrievers rise without comparing their raw
+// scores. k is usually 60.
+func RRF(k float64, rankings ...[]string) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, ranking := range rankings {
+		for i, id := range ranking {
+			scores[id] += 1 / (k + float64(i+1))
+		}
+	}
+	return scores
+}
+
+// MinMax rescales scores to [0, 1] in place so scores from different
+// retrievers can be blended. A list of equal scores becomes all ones.
+func MinMax(scores []float64) []float64 {
+	if len(scores) == 0 {
+		return scores
+	}
+
+	lowest, highest := scores[0], scores[0]
+	for _, score := range scores[1:] {
+		if score < lowest {
+			lowest = score
+		}
+		if score > highest {
+			highest = score
+		}
+	}
+
+	for i := range scores {
+		if highest == lowest {
+			scores[i] = 1
+		} else {
+			scores[i] = (scores[i] - lowest) / (highest - lowest)
+		}
+	}
+	return scores
+}
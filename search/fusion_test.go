@@ -0,0 +1,30 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type FusionTestSuite struct {
+	suite.Suite
+}
+
+func (s *FusionTestSuite) TestRRF() {
+	scores := RRF(60, []string{"a", "b", "c"}, []string{"c", "a"})
+
+	assert.InDelta(s.T(), 1.0/61+1.0/62, scores["a"], 0.000001)
+	assert.InDelta(s.T(), 1.0/62, scores["b"], 0.000001)
+	assert.InDelta(s.T(), 1.0/63+1.0/61, scores["c"], 0.000001)
+	assert.Greater(s.T(), scores["a"], scores["c"])
+}
+
+func (s *FusionTestSuite) TestMinMax() {
+	assert.Equal(s.T(), []float64{1, 0, 0.5}, MinMax([]float64{4, 2, 3}))
+	assert.Equal(s.T(), []float64{1, 1}, MinMax([]float64{7, 7}))
+	assert.Empty(s.T(), MinMax(nil))
+}
+
+func TestFusionSuite(t *testing.T) {
+	suite.Run(t, new(FusionTestSuite))
+}
@@ -0,0 +1,115 @@
+package search
+
+import "math"
+
+// The float32 kernels accumulate in float32, in as many lanes as the
+// implementation has. Assembly versions replace the pure Go ones at init
+// on CPUs that support them; building with the purego tag keeps the pure
+// Go ones everywhere.
+var (
+	dotKernel       = dotGeneric
+	squaredL2Kernel = squaredL2Generic
+)
+
+// DotFloat32 is the dot product of a and b, which must be at least as long
+// as a
+func DotFloat32(a, b []float32) float32 {
+	if len(a) == 0 {
+		return 0
+	}
+	return dotKernel(a, b[:len(a)])
+}
+
+// SquaredL2Float32 is the squared Euclidean distance between a and b,
+// which must be at least as long as a
+func SquaredL2Float32(a, b []float32) float32 {
+	if len(a) == 0 {
+		return 0
+	}
+	return squaredL2Kernel(a, b[:len(a)])
+}
+
+// Norm is the Euclidean length of vec
+func Norm[T Float](vec []T) float64 {
+	return math.Sqrt(Dot(vec, vec))
+}
+
+// CosineWithNorms is the cosine similarity of two float32 vectors whose
+// norms are already known, which leaves a single dot product to compute
+func CosineWithNorms(vec1, vec2 []float32, norm1, norm2 float64) float64 {
+	return float64(DotFloat32(vec1, vec2)) / (norm1 * norm2)
+}
+
+// dotGeneric keeps four independent sums so the multiplies of consecutive
+// steps don't wait on each other
+func dotGeneric(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		x, y := a[i:i+4:i+4], b[i:i+4:i+4]
+		s0 += x[0] * y[0]
+		s1 += x[1] * y[1]
+		s2 += x[2] * y[2]
+		s3 += x[3] * y[3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+func squaredL2Generic(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		x, y := a[i:i+4:i+4], b[i:i+4:i+4]
+		d0, d1, d2, d3 := x[0]-y[0], x[1]-y[1], x[2]-y[2], x[3]-y[3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// dotFloat64 and squaredL2Float64 are the float64 kernels, only ever pure
+// Go
+func dotFloat64(a, b []float64) float64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float64
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		x, y := a[i:i+4:i+4], b[i:i+4:i+4]
+		s0 += x[0] * y[0]
+		s1 += x[1] * y[1]
+		s2 += x[2] * y[2]
+		s3 += x[3] * y[3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+func squaredL2Float64(a, b []float64) float64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float64
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		x, y := a[i:i+4:i+4], b[i:i+4:i+4]
+		d0, d1, d2, d3 := x[0]-y[0], x[1]-y[1], x[2]-y[2], x[3]-y[3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
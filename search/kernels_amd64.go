@@ -0,0 +1,18 @@
+//go:build !purego
+
+package search
+
+import "golang.org/x/sys/cpu"
+
+//go:noescape
+func dotAVX2(a, b []float32) float32
+
+//go:noescape
+func squaredL2AVX2(a, b []float32) float32
+
+func init() {
+	if cpu.X86.HasAVX2 && cpu.X86.HasFMA {
+		dotKernel = dotAVX2
+		squaredL2Kernel = squaredL2AVX2
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !purego
+
+package search
+
+//go:noescape
+func dotNEON(a, b []float32) float32
+
+//go:noescape
+func squaredL2NEON(a, b []float32) float32
+
+// every arm64 CPU has NEON
+func init() {
+	dotKernel = dotNEON
+	squaredL2Kernel = squaredL2NEON
+}
@@ -0,0 +1,117 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// Both kernels read len(a) elements of a and b. The main loop keeps four
+// accumulators of four lanes each busy, 16 elements per step, then single
+// registers take 4 elements at a time and scalar steps the rest.
+
+// func dotNEON(a, b []float32) float32
+TEXT ·dotNEON(SB), NOSPLIT, $0-52
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+dot16:
+	CMP  $16, R2
+	BLT  dot4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFMLA V8.S4, V4.S4, V0.S4
+	VFMLA V9.S4, V5.S4, V1.S4
+	VFMLA V10.S4, V6.S4, V2.S4
+	VFMLA V11.S4, V7.S4, V3.S4
+	SUB  $16, R2
+	B    dot16
+
+dot4:
+	CMP  $4, R2
+	BLT  dotReduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFMLA V8.S4, V4.S4, V0.S4
+	SUB  $4, R2
+	B    dot4
+
+dotReduce:
+	VFADD  V1.S4, V0.S4, V0.S4
+	VFADD  V3.S4, V2.S4, V2.S4
+	VFADD  V2.S4, V0.S4, V0.S4
+	VFADDP V0.S4, V0.S4, V0.S4
+	VFADDP V0.S4, V0.S4, V0.S4
+
+dotTail:
+	CBZ   R2, dotDone
+	FMOVS (R0), F4
+	FMOVS (R1), F5
+	FMADDS F5, F0, F4, F0
+	ADD   $4, R0
+	ADD   $4, R1
+	SUB   $1, R2
+	B     dotTail
+
+dotDone:
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func squaredL2NEON(a, b []float32) float32
+TEXT ·squaredL2NEON(SB), NOSPLIT, $0-52
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+l216:
+	CMP  $16, R2
+	BLT  l24
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R1), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFSUB V8.S4, V4.S4, V4.S4
+	VFSUB V9.S4, V5.S4, V5.S4
+	VFSUB V10.S4, V6.S4, V6.S4
+	VFSUB V11.S4, V7.S4, V7.S4
+	VFMLA V4.S4, V4.S4, V0.S4
+	VFMLA V5.S4, V5.S4, V1.S4
+	VFMLA V6.S4, V6.S4, V2.S4
+	VFMLA V7.S4, V7.S4, V3.S4
+	SUB  $16, R2
+	B    l216
+
+l24:
+	CMP  $4, R2
+	BLT  l2Reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R1), [V8.S4]
+	VFSUB V8.S4, V4.S4, V4.S4
+	VFMLA V4.S4, V4.S4, V0.S4
+	SUB  $4, R2
+	B    l24
+
+l2Reduce:
+	VFADD  V1.S4, V0.S4, V0.S4
+	VFADD  V3.S4, V2.S4, V2.S4
+	VFADD  V2.S4, V0.S4, V0.S4
+	VFADDP V0.S4, V0.S4, V0.S4
+	VFADDP V0.S4, V0.S4, V0.S4
+
+l2Tail:
+	CBZ   R2, l2Done
+	FMOVS (R0), F4
+	FMOVS (R1), F5
+	FSUBS F5, F4, F4
+	FMADDS F4, F0, F4, F0
+	ADD   $4, R0
+	ADD   $4, R1
+	SUB   $1, R2
+	B     l2Tail
+
+l2Done:
+	FMOVS F0, ret+48(FP)
+	RET
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+type KernelsTestSuite struct {
+	suite.Suite
+	rng *rand.Rand
+}
+
+func (s *KernelsTestSuite) SetupTest() {
+	s.rng = rand.New(rand.NewSource(1))
+}
+
+func randomVector(rng *rand.Rand, n int) []float32 {
+	vec := make([]float32, n)
+	for i := range vec {
+		vec[i] = float32(rng.NormFloat64())
+	}
+	return vec
+}
+
+// naiveL2 and naiveCosine are the scalar float64 loops the kernels
+// replaced, kept as the reference
+func naiveL2(vec1, vec2 []float32) float64 {
+	diff := 0.0
+	for i := 0; i < len(vec1); i++ {
+		diff += math.Pow(float64(vec1[i])-float64(vec2[i]), 2)
+	}
+	return math.Sqrt(diff)
+}
+
+func naiveDot(vec1, vec2 []float32) float64 {
+	dot := 0.0
+	for i := 0; i < len(vec1); i++ {
+		dot += float64(vec1[i]) * float64(vec2[i])
+	}
+	return dot
+}
+
+func naiveCosine(vec1, vec2 []float32) float64 {
+	dotProduct, norm1, norm2 := 0.0, 0.0, 0.0
+	for i := 0; i < len(vec1); i++ {
+		a, b := float64(vec1[i]), float64(vec2[i])
+		dotProduct += a * b
+		norm1 += a * a
+		norm2 += b * b
+	}
+	return dotProduct / (math.Sqrt(norm1) * math.Sqrt(norm2))
+}
+
+func (s *KernelsTestSuite) TestKernelsMatchReference() {
+	// every length up to a few main loop steps, so each tail is covered
+	for n := 0; n <= 100; n++ {
+		a, b := randomVector(s.rng, n), randomVector(s.rng, n)
+		dot, l2 := naiveDot(a, b), naiveL2(a, b)
+		tolerance := 1e-5 * float64(n+1)
+
+		for name, kernel := range map[string]func(a, b []float32) float32{"dispatched": DotFloat32, "generic": dotGeneric} {
+			assert.InDelta(s.T(), dot, float64(kernel(a, b)), tolerance, "%s dot of length %d", name, n)
+		}
+		for name, kernel := range map[string]func(a, b []float32) float32{"dispatched": SquaredL2Float32, "generic": squaredL2Generic} {
+			assert.InDelta(s.T(), l2*l2, float64(kernel(a, b)), tolerance, "%s squared l2 of length %d", name, n)
+		}
+
+		wide1, wide2 := ToFloat64(a), ToFloat64(b)
+		assert.InDelta(s.T(), dot, Dot(wide1, wide2), 1e-9)
+		assert.InDelta(s.T(), l2, L2(wide1, wide2), 1e-9)
+		assert.InDelta(s.T(), l2, L2(a, b), 1e-5)
+		if n > 0 {
+			assert.InDelta(s.T(), naiveCosine(a, b), Cosine(a, b), 1e-6)
+			assert.InDelta(s.T(), naiveCosine(a, b), CosineWithNorms(a, b, Norm(a), Norm(b)), 1e-6)
+		}
+	}
+}
+
+func (s *KernelsTestSuite) TestLongerSecondVector() {
+	// only the length of the first vector counts
+	a := []float32{1, 2, 3}
+	b := []float32{1, 1, 1, 100, 100}
+	assert.Equal(s.T(), float32(6), DotFloat32(a, b))
+	assert.Equal(s.T(), float32(5), SquaredL2Float32(a, b))
+	assert.Panics(s.T(), func() { DotFloat32(b, a) })
+}
+
+func (s *KernelsTestSuite) TestNamedTypes() {
+	// element types other than float32 and float64 take the plain loops
+	type weight float32
+	a, b := []weight{1, 2}, []weight{3, 4}
+	assert.Equal(s.T(), 11.0, Dot(a, b))
+	assert.InDelta(s.T(), math.Sqrt(8), L2(a, b), 1e-12)
+}
+
+func (s *KernelsTestSuite) TestScorer() {
+	query := randomVector(s.rng, 37)
+	for _, metric := range []string{"l2", "dot", "cosine"} {
+		scorer, err := NewScorer(metric, query)
+		assert.NoError(s.T(), err)
+		scoreFn, err := MetricFunc[float32](metric)
+		assert.NoError(s.T(), err)
+
+		for i := 0; i < 10; i++ {
+			vector := randomVector(s.rng, 37)
+			norm := float32(Norm(vector))
+			assert.InDelta(s.T(), scoreFn(vector, query), scorer.Score(vector, norm), 1e-5, metric)
+		}
+	}
+
+	_, err := NewScorer("hamming", query)
+	assert.Error(s.T(), err)
+}
+
+func TestKernelsSuite(t *testing.T) {
+	suite.Run(t, new(KernelsTestSuite))
+}
+
+// The benchmarks compare the scalar reference loops with the unrolled pure
+// Go kernels and the ones this CPU dispatches to, which are assembly on
+// amd64 with AVX2 and FMA and on arm64, e.g.
+//
+//	go test -run '^$' -bench . ./search
+//	go test -run '^$' -bench . -tags purego ./search
+var benchmarkDims = []int{384, 768, 1536}
+
+var benchmarkSink float64
+
+func benchmarkVectors(n int) ([]float32, []float32) {
+	rng := rand.New(rand.NewSource(int64(n)))
+	return randomVector(rng, n), randomVector(rng, n)
+}
+
+func BenchmarkL2(b *testing.B) {
+	for _, n := range benchmarkDims {
+		a, c := benchmarkVectors(n)
+		b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += naiveL2(a, c)
+			}
+		})
+		b.Run(fmt.Sprintf("generic/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += math.Sqrt(float64(squaredL2Generic(a, c)))
+			}
+		})
+		b.Run(fmt.Sprintf("kernel/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += L2(a, c)
+			}
+		})
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	for _, n := range benchmarkDims {
+		a, c := benchmarkVectors(n)
+		b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += naiveDot(a, c)
+			}
+		})
+		b.Run(fmt.Sprintf("generic/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += float64(dotGeneric(a, c))
+			}
+		})
+		b.Run(fmt.Sprintf("kernel/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += Dot(a, c)
+			}
+		})
+	}
+}
+
+func BenchmarkCosine(b *testing.B) {
+	for _, n := range benchmarkDims {
+		a, c := benchmarkVectors(n)
+		b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += naiveCosine(a, c)
+			}
+		})
+		b.Run(fmt.Sprintf("kernel/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += Cosine(a, c)
+			}
+		})
+		// the norms are stored with the vectors, so only the dot product
+		// is left per comparison
+		norm1, norm2 := Norm(a), Norm(c)
+		b.Run(fmt.Sprintf("norms/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchmarkSink += CosineWithNorms(a, c, norm1, norm2)
+			}
+		})
+	}
+}
@@ -2,10 +2,18 @@ package search
 
 import "math"
 
-func L2(vec1, vec2 []float64) float64 {
+func L2[T Float](vec1, vec2 []T) float64 {
+	switch v1 := any(vec1).(type) {
+	case []float32:
+		return math.Sqrt(float64(SquaredL2Float32(v1, any(vec2).([]float32))))
+	case []float64:
+		return math.Sqrt(squaredL2Float64(v1, any(vec2).([]float64)))
+	}
+
 	diff := 0.0
 	for i := 0; i < len(vec1); i++ {
-		diff += math.Pow(vec1[i]-vec2[i], 2)
+		d := float64(vec1[i]) - float64(vec2[i])
+		diff += d * d
 	}
 
 	return math.Sqrt(diff)
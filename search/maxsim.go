@@ -0,0 +1,24 @@
+package search
+
+import "math"
+
+// MaxSim is ColBERT's late-interaction score: every query token is matched
+// with its most similar document token and those best matches are summed.
+// Token vectors are expected to be normalized, so the dot product is their
+// cosine similarity.
+func MaxSim[T Float](query, doc [][]T) float64 {
+	score := 0.0
+	for _, q := range query {
+		best := math.Inf(-1)
+		for _, d := range doc {
+			if sim := Dot(q, d); sim > best {
+				best = sim
+			}
+		}
+		if len(doc) > 0 {
+			score += best
+		}
+	}
+
+	return score
+}
@@ -0,0 +1,36 @@
+package search
+
+// Mean averages vectors dimension by dimension. All vectors must have the
+// same length.
+func Mean[T Float](vectors [][]T) []T {
+	if len(vectors) == 0 {
+		return []T{}
+	}
+
+	sum := make([]float64, len(vectors[0]))
+	for _, vec := range vectors {
+		for i := range sum {
+			sum[i] += float64(vec[i])
+		}
+	}
+	mean := make([]T, len(sum))
+	for i := range mean {
+		mean[i] = T(sum[i] / float64(len(vectors)))
+	}
+
+	return mean
+}
+
+// Normalize scales vec to unit length in place and returns it. Zero vectors
+// are returned unchanged.
+func Normalize[T Float](vec []T) []T {
+	norm := Norm(vec)
+	if norm == 0 {
+		return vec
+	}
+	for i := range vec {
+		vec[i] = T(float64(vec[i]) / norm)
+	}
+
+	return vec
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"fmt"
+	"math"
+)
+
+// MetricFunc returns the scoring function of a single-vector metric: "l2",
+// "dot" or "cosine"
+func MetricFunc[T Float](metric string) (func([]T, []T) float64, error) {
+	switch metric {
+	case "dot":
+		return Dot[T], nil
+	case "l2":
+		return L2[T], nil
+	case "cosine":
+		return Cosine[T], nil
+	default:
+		return nil, fmt.Errorf("unknown metric %s", metric)
+	}
+}
+
+// Scorer scores float32 vectors against one query with a single-vector
+// metric. Cosine takes the norms stored with the vectors, so each score
+// costs a single dot product.
+type Scorer struct {
+	metric    string
+	query     []float32
+	queryNorm float64
+}
+
+// NewScorer returns a Scorer for query and metric: "l2", "dot" or "cosine"
+func NewScorer(metric string, query []float32) (*Scorer, error) {
+	switch metric {
+	case "dot", "l2", "cosine":
+	default:
+		return nil, fmt.Errorf("unknown metric %s", metric)
+	}
+	return &Scorer{metric: metric, query: query, queryNorm: Norm(query)}, nil
+}
+
+// Score scores vector, whose Euclidean length is norm, against the query.
+// vector must be as long as the query.
+func (s *Scorer) Score(vector []float32, norm float32) float64 {
+	switch s.metric {
+	case "dot":
+		return float64(DotFloat32(s.query, vector))
+	case "l2":
+		return math.Sqrt(float64(SquaredL2Float32(s.query, vector)))
+	default:
+		return CosineWithNorms(s.query, vector, s.queryNorm, float64(norm))
+	}
+}
@@ -86,6 +86,132 @@ func (s *SearchMetricsTestSuite) TestEdgeCases() {
 	})
 }
 
+func (s *SearchMetricsTestSuite) TestMaxSim() {
+	query := [][]float64{{1.0, 0.0}, {0.0, 1.0}}
+
+	// each query token finds an exact match
+	doc := [][]float64{{0.0, 1.0}, {1.0, 0.0}, {0.6, 0.8}}
+	assert.InDelta(s.T(), 2.0, MaxSim(query, doc), 0.000001)
+
+	// the best document token is picked per query token
+	doc = [][]float64{{0.6, 0.8}}
+	assert.InDelta(s.T(), 1.4, MaxSim(query, doc), 0.000001)
+
+	assert.InDelta(s.T(), 0.0, MaxSim(query, [][]float64{}), 0.000001)
+}
+
+func (s *SearchMetricsTestSuite) TestMeanAndNormalize() {
+	mean := Mean([][]float64{{1.0, 2.0}, {3.0, 4.0}})
+	assert.Equal(s.T(), []float64{2.0, 3.0}, mean)
+
+	normalized := Normalize([]float64{3.0, 4.0})
+	assert.InDeltaSlice(s.T(), []float64{0.6, 0.8}, normalized, 0.000001)
+
+	assert.Equal(s.T(), []float64{0.0, 0.0}, Normalize([]float64{0.0, 0.0}))
+}
+
+func (s *SearchMetricsTestSuite) TestMMR() {
+	relevance := []float64{0.9, 0.89, 0.5}
+	vectors := [][]float64{{1.0, 0.0}, {0.99, 0.01}, {0.0, 1.0}}
+
+	// pure relevance keeps the ranking
+	assert.Equal(s.T(), []int{0, 1, 2}, MMR(relevance, vectors, 1.0, 0))
+
+	// the near duplicate of the first pick drops behind the distinct result
+	assert.Equal(s.T(), []int{0, 2, 1}, MMR(relevance, vectors, 0.5, 0))
+	assert.Equal(s.T(), []int{0, 2}, MMR(relevance, vectors, 0.5, 2))
+
+	assert.Empty(s.T(), MMR[float64](nil, nil, 0.5, 3))
+}
+
+func (s *SearchMetricsTestSuite) TestInRadius() {
+	assert.True(s.T(), InRadius("l2", 0.5, 1))
+	assert.False(s.T(), InRadius("l2", 1.5, 1))
+	assert.True(s.T(), InRadius("cosine", 0.9, 0.8))
+	assert.False(s.T(), InRadius("dot", 0.5, 0.8))
+
+	assert.True(s.T(), Closer("l2", 0.1, 0.2))
+	assert.True(s.T(), Closer("cosine", 0.2, 0.1))
+}
+
+func (s *SearchMetricsTestSuite) TestScalarQuantizer() {
+	_, err := TrainScalar(nil)
+	assert.Error(s.T(), err)
+	_, err = TrainScalar([][]float32{{1, 2}, {1}})
+	assert.Error(s.T(), err)
+
+	vectors := [][]float32{{-1, 0, 5}, {1, 10, 5}, {0.5, 2.5, 5}}
+	q, err := TrainScalar(vectors)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{-1, 0, 5}, q.Min)
+	assert.Equal(s.T(), []float64{1, 10, 5}, q.Max)
+
+	code := q.Encode(vectors[1])
+	assert.Equal(s.T(), []int8{127, 127, -128}, code)
+	for _, vector := range vectors {
+		decoded := q.Decode(q.Encode(vector))
+		for d := range vector {
+			// off by at most half a level
+			assert.InDelta(s.T(), vector[d], decoded[d], (q.Max[d]-q.Min[d])/510+1e-6)
+		}
+		assert.InDelta(s.T(), L2(vectors[0], decoded), q.L2(vectors[0], q.Encode(vector)), 1e-6)
+	}
+
+	// values outside the trained range are clamped
+	assert.Equal(s.T(), []int8{-128, 127, -128}, q.Encode([]float32{-5, 50, 6}))
+}
+
+func (s *SearchMetricsTestSuite) TestBinaryQuantize() {
+	vector := make([]float64, 70)
+	vector[0], vector[64], vector[69] = 1, 0.5, -1
+	code := BinaryQuantize(vector)
+	assert.Equal(s.T(), []uint64{1, 1}, code)
+
+	decoded := BinaryDecode(code, len(vector))
+	assert.Equal(s.T(), float32(1), decoded[0])
+	assert.Equal(s.T(), float32(1), decoded[64])
+	assert.Equal(s.T(), float32(-1), decoded[1])
+
+	assert.Equal(s.T(), 0, Hamming(code, code))
+	assert.Equal(s.T(), 2, Hamming(code, BinaryQuantize(make([]float64, 70))))
+	assert.Equal(s.T(), 3, Hamming(BinaryQuantize([]float64{1, 1, 1}), BinaryQuantize([]float64{-1, -1, -1})))
+}
+
+func (s *SearchMetricsTestSuite) TestFloat32() {
+	vec1 := []float32{1, 2, 3}
+	vec2 := []float32{4, 5, 6}
+	assert.InDelta(s.T(), L2([]float64{1, 2, 3}, []float64{4, 5, 6}), L2(vec1, vec2), 1e-12)
+	assert.InDelta(s.T(), 32.0, Dot(vec1, vec2), 1e-12)
+	assert.InDelta(s.T(), Cosine([]float64{1, 2, 3}, []float64{4, 5, 6}), Cosine(vec1, vec2), 1e-12)
+
+	fn, err := MetricFunc[float32]("l2")
+	assert.NoError(s.T(), err)
+	assert.InDelta(s.T(), L2(vec1, vec2), fn(vec1, vec2), 1e-12)
+
+	assert.Equal(s.T(), []float64{1, 2, 3}, ToFloat64(vec1))
+	assert.Equal(s.T(), vec1, ToFloat32([]float64{1, 2, 3}))
+	assert.Nil(s.T(), ToFloat32(nil))
+}
+
+func (s *SearchMetricsTestSuite) TestHalfPrecision() {
+	for _, f := range []float32{0, 1, -2, 0.5, 65504, 6.103515625e-05, 5.960464477539063e-08} {
+		assert.Equal(s.T(), f, Float16FromBits(Float16Bits(f)), "%v", f)
+	}
+	assert.Equal(s.T(), uint16(0x3c00), Float16Bits(1))
+	assert.Equal(s.T(), uint16(0xc000), Float16Bits(-2))
+	assert.InDelta(s.T(), 0.1, Float16FromBits(Float16Bits(0.1)), 1e-4)
+	assert.True(s.T(), math.IsInf(float64(Float16FromBits(Float16Bits(1e6))), 1))
+	assert.Equal(s.T(), float32(0), Float16FromBits(Float16Bits(1e-10)))
+	assert.True(s.T(), math.IsNaN(float64(Float16FromBits(Float16Bits(float32(math.NaN()))))))
+
+	for _, f := range []float32{0, 1, -2, 0.5, 3e38} {
+		assert.InDelta(s.T(), f, BFloat16FromBits(BFloat16Bits(f)), math.Abs(float64(f))/128)
+	}
+	assert.Equal(s.T(), uint16(0x3f80), BFloat16Bits(1))
+	assert.InDelta(s.T(), 0.1, BFloat16FromBits(BFloat16Bits(0.1)), 1e-3)
+	assert.True(s.T(), math.IsNaN(float64(BFloat16FromBits(BFloat16Bits(float32(math.NaN()))))))
+}
+
 func TestSearchMetrics(t *testing.T) {
 	suite.Run(t, new(SearchMetricsTestSuite))
 }
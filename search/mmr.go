@@ -0,0 +1,55 @@
+package search
+
+import "math"
+
+// MMR orders candidates by maximal marginal relevance: each pick maximizes
+// lambda*relevance - (1-lambda)*similarity to the closest candidate already
+// picked, with similarity measured by cosine between vectors. Relevance is
+// min-max normalized first so lambda weighs comparable quantities. Lambda
+// 1 keeps the relevance order and 0 only rewards novelty. It returns the
+// indices of the first k picks, or of every candidate when k is 0.
+func MMR[T Float](relevance []float64, vectors [][]T, lambda float64, k int) []int {
+	n := len(relevance)
+	if k <= 0 || k > n {
+		k = n
+	}
+
+	rel := MinMax(append([]float64{}, relevance...))
+
+	// maxSim[i] is the highest similarity of candidate i to any pick
+	maxSim := make([]float64, n)
+	picked := make([]bool, n)
+	order := make([]int, 0, k)
+
+	for len(order) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := 0; i < n; i++ {
+			if picked[i] {
+				continue
+			}
+			score := lambda * rel[i]
+			if len(order) > 0 {
+				score -= (1 - lambda) * maxSim[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		order = append(order, best)
+
+		for i := 0; i < n; i++ {
+			if picked[i] || len(vectors[i]) != len(vectors[best]) {
+				continue
+			}
+			sim := Cosine(vectors[i], vectors[best])
+			if !math.IsNaN(sim) && sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+
+	return order
+}
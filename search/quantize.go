@@ -0,0 +1,117 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ScalarQuantizer maps every dimension of a vector to an int8, spreading
+// the 256 levels evenly between the dimension's minimum and maximum as seen
+// in training. Values outside that range are clamped.
+type ScalarQuantizer struct {
+	Min []float64 `json:"min"`
+	Max []float64 `json:"max"`
+}
+
+// TrainScalar learns the per-dimension ranges from a sample of vectors
+func TrainScalar(vectors [][]float32) (*ScalarQuantizer, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("cannot train scalar quantization without vectors")
+	}
+
+	dim := len(vectors[0])
+	q := &ScalarQuantizer{
+		Min: make([]float64, dim),
+		Max: make([]float64, dim),
+	}
+	for d, value := range vectors[0] {
+		q.Min[d] = float64(value)
+		q.Max[d] = float64(value)
+	}
+	for _, vector := range vectors[1:] {
+		if len(vector) != dim {
+			return nil, fmt.Errorf("training vectors have %d and %d dimensions", dim, len(vector))
+		}
+		for d, value := range vector {
+			q.Min[d] = math.Min(q.Min[d], float64(value))
+			q.Max[d] = math.Max(q.Max[d], float64(value))
+		}
+	}
+
+	return q, nil
+}
+
+// step is the width of one quantization level in dimension d
+func (q *ScalarQuantizer) step(d int) float64 {
+	return (q.Max[d] - q.Min[d]) / 255
+}
+
+// Encode quantizes vector, which must have the trained dimensions
+func (q *ScalarQuantizer) Encode(vector []float32) []int8 {
+	code := make([]int8, len(vector))
+	for d, value := range vector {
+		step := q.step(d)
+		level := 0.0
+		if step > 0 {
+			level = math.Round((float64(value) - q.Min[d]) / step)
+		}
+		code[d] = int8(math.Max(0, math.Min(255, level)) - 128)
+	}
+	return code
+}
+
+// Decode rebuilds the approximate vector a code stands for
+func (q *ScalarQuantizer) Decode(code []int8) []float32 {
+	vector := make([]float32, len(code))
+	for d, c := range code {
+		vector[d] = float32(q.Min[d] + float64(int(c)+128)*q.step(d))
+	}
+	return vector
+}
+
+// L2 is the Euclidean distance between a full precision query and a code,
+// decoding the code one dimension at a time
+func (q *ScalarQuantizer) L2(query []float32, code []int8) float64 {
+	sum := 0.0
+	for d, c := range code {
+		diff := float64(query[d]) - (q.Min[d] + float64(int(c)+128)*q.step(d))
+		sum += diff * diff
+	}
+	return math.Sqrt(sum)
+}
+
+// BinaryQuantize keeps one bit per dimension, set when the value is
+// positive, packed 64 dimensions to a word
+func BinaryQuantize[T Float](vector []T) []uint64 {
+	code := make([]uint64, (len(vector)+63)/64)
+	for d, value := range vector {
+		if value > 0 {
+			code[d/64] |= 1 << uint(d%64)
+		}
+	}
+	return code
+}
+
+// BinaryDecode turns a binary code of dims dimensions back into a vector of
+// +1 and -1, whose cosine similarities match the codes' Hamming distances
+func BinaryDecode(code []uint64, dims int) []float32 {
+	vector := make([]float32, dims)
+	for d := range vector {
+		if code[d/64]&(1<<uint(d%64)) != 0 {
+			vector[d] = 1
+		} else {
+			vector[d] = -1
+		}
+	}
+	return vector
+}
+
+// Hamming counts the bits two binary codes differ in
+func Hamming(a, b []uint64) int {
+	distance := 0
+	for i := range a {
+		distance += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return distance
+}
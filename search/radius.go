@@ -0,0 +1,21 @@
+package search
+
+// InRadius reports whether a score computed with metric lies within radius
+// of the query. For l2, a distance, the score must be at most radius. For
+// the similarity metrics (cosine and dot) radius is the lowest similarity
+// that still counts as near.
+func InRadius(metric string, score float64, radius float64) bool {
+	if metric == "l2" {
+		return score <= radius
+	}
+	return score >= radius
+}
+
+// Closer reports whether score a is nearer to the query than score b under
+// metric, so range results can be ordered closest first
+func Closer(metric string, a float64, b float64) bool {
+	if metric == "l2" {
+		return a < b
+	}
+	return a > b
+}
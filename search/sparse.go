@@ -0,0 +1,173 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SparseVector holds the non-zero weights of a sparse (SPLADE style) vector
+// as parallel slices sorted by index
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float64 `json:"values"`
+}
+
+// NewSparseVector sorts indices and values together and rejects duplicate
+// indices
+func NewSparseVector(indices []uint32, values []float64) (SparseVector, error) {
+	if len(indices) != len(values) {
+		return SparseVector{}, fmt.Errorf("sparse vector has %d indices but %d values", len(indices), len(values))
+	}
+
+	vec := SparseVector{
+		Indices: append([]uint32{}, indices...),
+		Values:  append([]float64{}, values...),
+	}
+	sort.Sort(sparseByIndex(vec))
+	for i := 1; i < len(vec.Indices); i++ {
+		if vec.Indices[i] == vec.Indices[i-1] {
+			return SparseVector{}, fmt.Errorf("sparse vector has index %d more than once", vec.Indices[i])
+		}
+	}
+
+	return vec, nil
+}
+
+func (v SparseVector) Len() int {
+	return len(v.Indices)
+}
+
+type sparseByIndex SparseVector
+
+func (s sparseByIndex) Len() int           { return len(s.Indices) }
+func (s sparseByIndex) Less(i, j int) bool { return s.Indices[i] < s.Indices[j] }
+func (s sparseByIndex) Swap(i, j int) {
+	s.Indices[i], s.Indices[j] = s.Indices[j], s.Indices[i]
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
+
+// SparseDot is the dot product of two sparse vectors, walking both index
+// lists once
+func SparseDot(a, b SparseVector) float64 {
+	sum := 0.0
+	i, j := 0, 0
+	for i < len(a.Indices) && j < len(b.Indices) {
+		switch {
+		case a.Indices[i] < b.Indices[j]:
+			i++
+		case a.Indices[i] > b.Indices[j]:
+			j++
+		default:
+			sum += a.Values[i] * b.Values[j]
+			i++
+			j++
+		}
+	}
+	return sum
+}
+
+// SparseMatch is a document scored by sparse dot product
+type SparseMatch struct {
+	ID    string
+	Score float64
+}
+
+type posting struct {
+	id     string
+	weight float64
+}
+
+// SparseIndex scores sparse vectors with posting lists per dimension, so a
+// query only touches the documents sharing one of its non-zero dimensions
+type SparseIndex struct {
+	postings map[uint32][]posting
+	docs     map[string]SparseVector
+	lock     sync.RWMutex
+}
+
+func NewSparseIndex() *SparseIndex {
+	return &SparseIndex{
+		postings: make(map[uint32][]posting),
+		docs:     make(map[string]SparseVector),
+	}
+}
+
+// Add stores or replaces the sparse vector of a document
+func (s *SparseIndex) Add(id string, vec SparseVector) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.delete(id)
+	for i, dim := range vec.Indices {
+		s.postings[dim] = append(s.postings[dim], posting{id: id, weight: vec.Values[i]})
+	}
+	s.docs[id] = vec
+}
+
+func (s *SparseIndex) Delete(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.delete(id)
+}
+
+func (s *SparseIndex) delete(id string) {
+	vec, ok := s.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, dim := range vec.Indices {
+		list := s.postings[dim]
+		for i, p := range list {
+			if p.id == id {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) == 0 {
+			delete(s.postings, dim)
+		} else {
+			s.postings[dim] = list
+		}
+	}
+	delete(s.docs, id)
+}
+
+func (s *SparseIndex) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.docs)
+}
+
+// Search returns the k documents with the highest dot product with query,
+// or every document sharing a dimension with it when k is 0
+func (s *SparseIndex) Search(query SparseVector, k int) []SparseMatch {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	scores := make(map[string]float64)
+	for i, dim := range query.Indices {
+		for _, p := range s.postings[dim] {
+			scores[p.id] += query.Values[i] * p.weight
+		}
+	}
+
+	matches := make([]SparseMatch, 0, len(scores))
+	for id, score := range scores {
+		matches = append(matches, SparseMatch{ID: id, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+
+	return matches
+}
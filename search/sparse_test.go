@@ -0,0 +1,66 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SparseTestSuite struct {
+	suite.Suite
+}
+
+func (s *SparseTestSuite) TestNewSparseVector() {
+	vec, err := NewSparseVector([]uint32{9, 2, 5}, []float64{0.9, 0.2, 0.5})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []uint32{2, 5, 9}, vec.Indices)
+	assert.Equal(s.T(), []float64{0.2, 0.5, 0.9}, vec.Values)
+
+	_, err = NewSparseVector([]uint32{1, 1}, []float64{0.1, 0.2})
+	assert.Error(s.T(), err)
+
+	_, err = NewSparseVector([]uint32{1}, nil)
+	assert.Error(s.T(), err)
+}
+
+func (s *SparseTestSuite) TestSparseDot() {
+	a := SparseVector{Indices: []uint32{1, 4, 7}, Values: []float64{1, 2, 3}}
+	b := SparseVector{Indices: []uint32{0, 4, 7, 9}, Values: []float64{5, 0.5, 2, 1}}
+
+	assert.InDelta(s.T(), 7.0, SparseDot(a, b), 0.000001)
+	assert.InDelta(s.T(), 0.0, SparseDot(a, SparseVector{}), 0.000001)
+}
+
+func (s *SparseTestSuite) TestSparseIndex() {
+	idx := NewSparseIndex()
+	a := SparseVector{Indices: []uint32{1, 4, 7}, Values: []float64{1, 2, 3}}
+	b := SparseVector{Indices: []uint32{0, 4}, Values: []float64{5, 0.5}}
+	c := SparseVector{Indices: []uint32{100}, Values: []float64{1}}
+	idx.Add("a", a)
+	idx.Add("b", b)
+	idx.Add("c", c)
+
+	query := SparseVector{Indices: []uint32{0, 4, 7}, Values: []float64{1, 1, 1}}
+	matches := idx.Search(query, 0)
+	assert.Len(s.T(), matches, 2)
+	assert.Equal(s.T(), "b", matches[0].ID)
+	assert.InDelta(s.T(), SparseDot(query, b), matches[0].Score, 0.000001)
+	assert.Equal(s.T(), "a", matches[1].ID)
+	assert.InDelta(s.T(), SparseDot(query, a), matches[1].Score, 0.000001)
+
+	assert.Len(s.T(), idx.Search(query, 1), 1)
+
+	// replacing and deleting update the posting lists
+	idx.Add("b", SparseVector{Indices: []uint32{100}, Values: []float64{2}})
+	matches = idx.Search(query, 0)
+	assert.Len(s.T(), matches, 1)
+	assert.Equal(s.T(), "a", matches[0].ID)
+
+	idx.Delete("a")
+	assert.Empty(s.T(), idx.Search(query, 0))
+	assert.Equal(s.T(), 2, idx.Len())
+}
+
+func TestSparseSuite(t *testing.T) {
+	suite.Run(t, new(SparseTestSuite))
+}
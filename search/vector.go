@@ -0,0 +1,120 @@
+package search
+
+import "math"
+
+// Float is the element type of a dense vector. Vectors are stored as
+// float32 and scored with float64 accumulators, while vectors coming from
+// the embedding models and the APIs are float64.
+type Float interface {
+	~float32 | ~float64
+}
+
+// ToFloat32 narrows vec to float32, which every stored vector uses
+func ToFloat32(vec []float64) []float32 {
+	if vec == nil {
+		return nil
+	}
+	narrow := make([]float32, len(vec))
+	for i, v := range vec {
+		narrow[i] = float32(v)
+	}
+	return narrow
+}
+
+// ToFloat64 widens vec back to float64
+func ToFloat64(vec []float32) []float64 {
+	if vec == nil {
+		return nil
+	}
+	wide := make([]float64, len(vec))
+	for i, v := range vec {
+		wide[i] = float64(v)
+	}
+	return wide
+}
+
+// Float16Bits rounds f to the nearest IEEE 754 half precision value. Values
+// beyond its range become infinities and tiny ones become subnormals or
+// zero.
+func Float16Bits(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int((bits >> 23) & 0xff)
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		// infinities keep a zero mantissa, NaNs keep a non-zero one
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127+15 >= 0x1f:
+		return sign | 0x7c00
+	case exp-127+15 <= 0:
+		// subnormal: shift the mantissa, implicit bit included, into place
+		shift := uint(14 - (exp - 127 + 15))
+		if shift > 24 {
+			return sign
+		}
+		mant |= 0x800000
+		half := mant >> shift
+		// round to nearest, ties to even
+		rest := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rest > halfway || (rest == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := uint32(exp-127+15)<<10 | mant>>13
+	rest := mant & 0x1fff
+	if rest > 0x1000 || (rest == 0x1000 && half&1 == 1) {
+		// a carry out of the mantissa bumps the exponent, up to infinity
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// Float16FromBits expands a half precision value to float32
+func Float16FromBits(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// normalize the subnormal
+		exp = 127 - 15 + 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		return math.Float32frombits(sign | exp<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// BFloat16Bits rounds f to bfloat16, which keeps float32's exponent and the
+// top 7 bits of its mantissa
+func BFloat16Bits(f float32) uint16 {
+	bits := math.Float32bits(f)
+	if bits&0x7fffffff > 0x7f800000 {
+		// keep NaNs NaN after dropping the low mantissa bits
+		return uint16(bits>>16) | 0x40
+	}
+	// round to nearest, ties to even
+	bits += 0x7fff + (bits>>16)&1
+	return uint16(bits >> 16)
+}
+
+// BFloat16FromBits expands a bfloat16 value to float32
+func BFloat16FromBits(b uint16) float32 {
+	return math.Float32frombits(uint32(b) << 16)
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// IndexDiskANN keeps a Vamana graph of the vectors on disk, with only
+// compressed vectors in memory. It is built by TrainIndex from the stored
+// vectors, everything written afterwards goes to an in-memory HNSW delta
+// graph until the next build.
+const IndexDiskANN = "diskann"
+
+// diskANNFile is where the disk index is kept in the store directory
+const diskANNFile = "vectors.diskann"
+
+// openDiskANN opens the store's disk index, or returns nil when it hasn't
+// been built
+func openDiskANN(dir string, config index.DiskANNConfig) *index.DiskANN {
+	disk, err := index.OpenDiskANN(filepath.Join(dir, diskANNFile), config)
+	if err != nil {
+		return nil
+	}
+	return disk
+}
+
+// newDelta creates the graph holding the writes since the disk index was
+// built. It has no training phase, so it is never quantized.
+func newDelta(options StoreOptions) *index.HNSW {
+	config := options.HNSW
+	config.Quantization = index.QuantizationNone
+	config.Scalar = nil
+	return index.NewHNSW(config)
+}
+
+// indexDelta updates the delta graph after key was written, the caller must
+// hold the store lock
+func (s *Store) indexDelta(key string, entry Entry) error {
+	if s.diskPending != nil {
+		s.diskPending[key] = true
+	}
+	if entry.Deleted || len(entry.Vector) == 0 {
+		// a missing node just means key had no vector
+		_ = s.delta.Delete(key)
+		return nil
+	}
+	// inserting replaces the node of a rewritten key
+	if err := s.delta.Insert(key, entry.Vector); err != nil {
+		return fmt.Errorf("could not index %s: %v", key, err)
+	}
+	return nil
+}
+
+// buildDiskANN writes a disk index of every stored vector, replacing the
+// old one. The vectors are streamed into a spill file next to the index
+// rather than collected in memory, so the build holds only the keys, the
+// codes and one shard of vectors at a time (see DiskANNConfig.ShardSize).
+//
+// The store lock is only held to take a snapshot of the memtable and the
+// SSTables, and to swap the new index in, so writes and searches go on
+// during the build. The writes made meanwhile are kept in the old delta
+// graph and replayed into the new one.
+func (s *Store) buildDiskANN() error {
+	if err := os.MkdirAll(s.destDir, 0755); err != nil {
+		return fmt.Errorf("could not create %s: %v", s.destDir, err)
+	}
+
+	s.lock.Lock()
+	if s.diskPending != nil {
+		s.lock.Unlock()
+		return fmt.Errorf("a disk index is already being built")
+	}
+	seen := make(map[string]bool)
+	recent := make([]string, 0)
+	recentEntries := make([]Entry, 0)
+	s.scanMemtable(seen, func(key string, entry Entry) {
+		recent = append(recent, key)
+		recentEntries = append(recentEntries, entry)
+	})
+	sstables := s.sstables
+	s.diskPending = make(map[string]bool)
+	s.lock.Unlock()
+
+	disk, err := s.writeDiskANN(seen, recent, recentEntries, sstables)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	pending := s.diskPending
+	s.diskPending = nil
+	if err != nil {
+		return err
+	}
+
+	if s.disk != nil {
+		_ = s.disk.Close()
+	}
+	s.disk = disk
+	s.delta = newDelta(s.options)
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry, exists := s.get(key)
+		if !exists {
+			continue
+		}
+		if err := s.indexDelta(key, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDiskANN builds the disk index of a snapshot: the memtable's live
+// entries, then whatever of sstables isn't seen in it
+func (s *Store) writeDiskANN(seen map[string]bool, recent []string, recentEntries []Entry, sstables []*SSTable) (*index.DiskANN, error) {
+	var vectors *index.VectorFile
+	defer func() {
+		if vectors != nil {
+			_ = vectors.Remove()
+		}
+	}()
+	keys := make([]string, 0)
+	var spillErr error
+	spill := func(key string, entry Entry) {
+		if len(entry.Vector) == 0 || spillErr != nil {
+			return
+		}
+		if vectors == nil {
+			vectors, spillErr = index.CreateVectorFile(filepath.Join(s.destDir, diskANNFile+".vectors"), len(entry.Vector))
+			if spillErr != nil {
+				return
+			}
+		}
+		if spillErr = vectors.Append(entry.Vector); spillErr == nil {
+			keys = append(keys, key)
+		}
+	}
+	for i, key := range recent {
+		spill(key, recentEntries[i])
+	}
+	err := scanSSTables(sstables, seen, spill)
+	if err == nil {
+		err = spillErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vectors == nil {
+		return nil, fmt.Errorf("could not build disk index: no vectors are stored")
+	}
+
+	disk, err := index.BuildDiskANNFromFile(filepath.Join(s.destDir, diskANNFile), keys, vectors, s.options.DiskANN)
+	if err != nil {
+		return nil, fmt.Errorf("could not build disk index: %v", err)
+	}
+	return disk, nil
+}
+
+// searchDiskANN scores the nearest neighbors the disk index and the delta
+// graph find for queryVector. It reports false while the disk index hasn't
+// been built.
+func (s *Store) searchDiskANN(queryVector []float32, scorer *search.Scorer) ([]Result, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.disk == nil {
+		return nil, false, nil
+	}
+
+	neighbors, err := s.disk.Search(queryVector, s.options.Candidates)
+	if err != nil {
+		return nil, true, err
+	}
+	recent, err := s.delta.SearchWithAccuracy(queryVector, s.options.Candidates, 2*s.options.Candidates)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// the disk index can still hold keys rewritten or deleted since it was
+	// built, so every match is scored by its current entry
+	seen := make(map[string]bool, len(neighbors)+len(recent))
+	results := make([]Result, 0, len(neighbors)+len(recent))
+	for _, neighbor := range append(neighbors, recent...) {
+		if seen[neighbor.ID] {
+			continue
+		}
+		seen[neighbor.ID] = true
+		entry, exists := s.get(neighbor.ID)
+		if !exists || entry.Deleted || len(entry.Vector) != len(queryVector) {
+			continue
+		}
+		score := scorer.Score(entry.Vector, entry.Norm)
+		if !math.IsNaN(score) && !math.IsInf(score, 0) {
+			results = append(results, newResult(neighbor.ID, entry, score))
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, true, nil
+}
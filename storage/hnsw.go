@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+	"sort"
+)
+
+// IndexHNSW keeps an HNSW graph of the vectors in memory and only scores
+// the nearest neighbors it finds
+const IndexHNSW = "hnsw"
+
+// CheckGraphMetric rejects searching a graph index by dot product. The
+// graphs link and walk vectors by l2 distance, and the largest dot
+// products of unnormalized vectors needn't be among the nearest of them.
+func CheckGraphMetric(index string, metric string) error {
+	if metric == "dot" && (index == IndexHNSW || index == IndexDiskANN) {
+		return fmt.Errorf("the %s index searches by l2 distance and can't rank by dot product, use l2 or cosine", index)
+	}
+	return nil
+}
+
+// newHNSW creates the store's graph, or nil while an int8 graph still has
+// to learn its value ranges from the stored vectors
+func newHNSW(options StoreOptions) *index.HNSW {
+	if options.HNSW.Quantization == index.QuantizationInt8 && options.HNSW.Scalar == nil {
+		return nil
+	}
+	return index.NewHNSW(options.HNSW)
+}
+
+// indexHNSW updates the graph after key was written, the caller must hold
+// the store lock
+func (s *Store) indexHNSW(key string, entry Entry) error {
+	if s.hnsw == nil {
+		s.hnswPending++
+		if s.hnswPending < s.options.HNSWTrainAfter {
+			return nil
+		}
+		// the new entry is already stored, so building picks it up
+		return s.buildHNSW()
+	}
+
+	if entry.Deleted || len(entry.Vector) == 0 {
+		// a missing node just means key had no vector
+		_ = s.hnsw.Delete(key)
+		return nil
+	}
+	// inserting replaces the node of a rewritten key
+	if err := s.hnsw.Insert(key, entry.Vector); err != nil {
+		return fmt.Errorf("could not index %s: %v", key, err)
+	}
+	return nil
+}
+
+// buildHNSW builds the graph from every stored vector, training the int8
+// quantizer on them first. The caller must hold the store lock.
+func (s *Store) buildHNSW() error {
+	keys := make([]string, 0)
+	vectors := make([][]float32, 0)
+	err := s.scanLatest(func(key string, entry Entry) {
+		if len(entry.Vector) > 0 {
+			keys = append(keys, key)
+			vectors = append(vectors, entry.Vector)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	config := s.options.HNSW
+	if config.Quantization == index.QuantizationInt8 {
+		if len(vectors) == 0 {
+			return fmt.Errorf("cannot train int8 quantization without vectors")
+		}
+		scalar, err := search.TrainScalar(vectors)
+		if err != nil {
+			return fmt.Errorf("could not train int8 quantization: %v", err)
+		}
+		config.Scalar = scalar
+	}
+
+	graph := index.NewHNSW(config)
+	if err := graph.InsertBatch(keys, vectors, 0); err != nil {
+		return err
+	}
+	s.hnsw = graph
+	s.hnswPending = 0
+
+	return nil
+}
+
+// searchHNSW scores the nearest neighbors the graph finds for queryVector.
+// It reports false while there is no graph to search yet.
+func (s *Store) searchHNSW(queryVector []float32, scorer *search.Scorer) ([]Result, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.hnsw == nil {
+		return nil, false, nil
+	}
+
+	oversample := 1
+	if s.options.HNSW.Quantization != index.QuantizationNone {
+		oversample = s.options.Oversample
+	}
+	neighbors, err := s.hnsw.SearchRescored(queryVector, s.options.Candidates, oversample, func(id string) ([]float32, bool) {
+		entry, exists := s.get(id)
+		if !exists || entry.Deleted {
+			return nil, false
+		}
+		return entry.Vector, true
+	})
+	if err != nil {
+		return nil, true, err
+	}
+
+	results := make([]Result, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		entry, exists := s.get(neighbor.ID)
+		if !exists || entry.Deleted || len(entry.Vector) != len(queryVector) {
+			continue
+		}
+		score := scorer.Score(entry.Vector, entry.Norm)
+		if !math.IsNaN(score) && !math.IsInf(score, 0) {
+			results = append(results, newResult(neighbor.ID, entry, score))
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, true, nil
+}
+
+// searchHNSWRadius walks the graph for the entries within an l2 radius of
+// queryVector, scoring each by its current entry. It reports false while
+// there is no graph, and for quantized graphs, whose distances are only
+// approximate.
+func (s *Store) searchHNSWRadius(queryVector []float32, radius float64, scorer *search.Scorer) ([]Result, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.hnsw == nil || s.options.HNSW.Quantization != index.QuantizationNone {
+		return nil, false, nil
+	}
+
+	matches, err := s.hnsw.SearchRadius(queryVector, radius, s.options.Candidates)
+	if err != nil {
+		return nil, true, err
+	}
+
+	results := make([]Result, 0, len(matches))
+	for _, match := range matches {
+		entry, exists := s.get(match.ID)
+		if !exists || entry.Deleted || len(entry.Vector) != len(queryVector) {
+			continue
+		}
+		score := scorer.Score(entry.Vector, entry.Norm)
+		if !math.IsNaN(score) && !math.IsInf(score, 0) && search.InRadius("l2", score, radius) {
+			results = append(results, newResult(match.ID, entry, score))
+		}
+	}
+	sortRadius(results, "l2")
+
+	return results, true, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"sort"
+)
+
+const (
+	// FusionRRF merges the keyword and vector rankings by reciprocal rank
+	FusionRRF = "rrf"
+
+	// FusionWeighted blends min-max normalized keyword and vector scores
+	FusionWeighted = "weighted"
+)
+
+type HybridOptions struct {
+	// Fusion is FusionRRF or FusionWeighted
+	Fusion string
+
+	// Alpha is the weight of the vector score in weighted blending, the
+	// keyword score gets 1-Alpha
+	Alpha float64
+
+	// RRFK dampens the advantage of top ranks in reciprocal rank fusion
+	RRFK float64
+
+	// Candidates is how many results of each ranking are fused, 0 fuses
+	// all of them
+	Candidates int
+}
+
+func DefaultHybridOptions() HybridOptions {
+	return HybridOptions{
+		Fusion:     FusionRRF,
+		Alpha:      0.5,
+		RRFK:       60,
+		Candidates: 100,
+	}
+}
+
+// SearchKeyword ranks entries by BM25 over their values
+func (s *Store) SearchKeyword(query string) ([]Result, error) {
+	matches := s.text.Search(query, 0)
+
+	results := make([]Result, 0, len(matches))
+	for _, match := range matches {
+		entry, exists := s.Get(match.ID)
+		if !exists {
+			continue
+		}
+		results = append(results, newResult(match.ID, entry, match.Score))
+	}
+
+	return results, nil
+}
+
+// SearchHybrid runs keyword and vector search for query and fuses the two
+// rankings, so exact identifiers missed by the embedding still surface.
+// Scores of the results are the fused scores.
+func (s *Store) SearchHybrid(query string, metric string, options HybridOptions) ([]Result, error) {
+	vector, err := s.Search(query, metric)
+	if err != nil {
+		return nil, err
+	}
+	vector = bestFirst(vector, metric)
+
+	keyword, err := s.SearchKeyword(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return fuse(vector, keyword, options)
+}
+
+// SearchSparse ranks entries by the dot product of their sparse vectors
+// with query
+func (s *Store) SearchSparse(query search.SparseVector) ([]Result, error) {
+	matches := s.sparse.Search(query, 0)
+
+	results := make([]Result, 0, len(matches))
+	for _, match := range matches {
+		entry, exists := s.Get(match.ID)
+		if !exists {
+			continue
+		}
+		results = append(results, newResult(match.ID, entry, match.Score))
+	}
+
+	return results, nil
+}
+
+// SearchDenseSparse fuses a dense search for query with a sparse search
+// for sparse, with Alpha weighting the dense side in weighted blending
+func (s *Store) SearchDenseSparse(query string, sparse search.SparseVector, metric string, options HybridOptions) ([]Result, error) {
+	dense, err := s.Search(query, metric)
+	if err != nil {
+		return nil, err
+	}
+	dense = bestFirst(dense, metric)
+
+	sparseResults, err := s.SearchSparse(sparse)
+	if err != nil {
+		return nil, err
+	}
+
+	return fuse(dense, sparseResults, options)
+}
+
+// fuse merges a vector ranking with a second one, both ordered best first,
+// weighting the vector ranking by Alpha in weighted blending
+func fuse(vector []Result, other []Result, options HybridOptions) ([]Result, error) {
+	vector = firstCandidates(vector, options.Candidates)
+	other = firstCandidates(other, options.Candidates)
+
+	fused := make(map[string]float64)
+	switch options.Fusion {
+	case FusionRRF, "":
+		fused = search.RRF(options.RRFK, resultKeys(vector), resultKeys(other))
+	case FusionWeighted:
+		for i, score := range search.MinMax(resultScores(vector)) {
+			fused[vector[i].Key] += options.Alpha * score
+		}
+		for i, score := range search.MinMax(resultScores(other)) {
+			fused[other[i].Key] += (1 - options.Alpha) * score
+		}
+	default:
+		return nil, fmt.Errorf("unknown fusion method %s", options.Fusion)
+	}
+
+	results := make([]Result, 0, len(fused))
+	for _, result := range append(vector, other...) {
+		score, ok := fused[result.Key]
+		if !ok {
+			continue
+		}
+		delete(fused, result.Key)
+		result.Score = score
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// bestFirst negates l2 distances so every ranking that gets fused has its
+// highest scores first
+func bestFirst(results []Result, metric string) []Result {
+	if metric != "l2" {
+		return results
+	}
+
+	for i := range results {
+		results[i].Score = -results[i].Score
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// firstCandidates keeps the first n distinct keys of results, or all of
+// them when n is 0
+func firstCandidates(results []Result, n int) []Result {
+	seen := make(map[string]bool)
+	kept := make([]Result, 0, len(results))
+	for _, result := range results {
+		if seen[result.Key] {
+			continue
+		}
+		if n > 0 && len(kept) == n {
+			break
+		}
+		seen[result.Key] = true
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+func resultKeys(results []Result) []string {
+	keys := make([]string, len(results))
+	for i, result := range results {
+		keys[i] = result.Key
+	}
+	return keys
+}
+
+func resultScores(results []Result) []float64 {
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		scores[i] = result.Score
+	}
+	return scores
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// IndexFlat scans every stored vector
+	IndexFlat = "flat"
+
+	// IndexIVF keeps an IVF-Flat index of the vectors next to the
+	// SSTables and only scans the posting lists nearest the query
+	IndexIVF = "ivf"
+
+	// IndexPQ scans the product quantization codes of every vector
+	// instead of the vectors (flat PQ) and scores the best Rescore
+	// matches again against the stored vectors. It is an IVF-PQ index
+	// with a single posting list, configured by IVF.PQ.
+	IndexPQ = "pq"
+)
+
+// ivfFile is where the IVF index is saved in the store directory
+const ivfFile = "vectors.ivf"
+
+// ivfConfig is the configuration of the store's IVF index. A single list
+// makes every probe of a flat PQ index scan all the codes.
+func ivfConfig(options StoreOptions) index.IVFConfig {
+	config := options.IVF
+	if options.Index == IndexPQ {
+		config.NLists = 1
+		config.NProbe = 1
+		if config.PQ.Subspaces == 0 {
+			config.PQ.Subspaces = index.DefaultPQConfig().Subspaces
+		}
+	}
+	return config
+}
+
+// openIVF loads the store's saved IVF index. A missing or unreadable index
+// starts out empty, it only ever holds a copy of the stored vectors.
+func openIVF(dir string, config index.IVFConfig) *index.IVF {
+	ivf, err := index.LoadIVF(filepath.Join(dir, ivfFile), config)
+	if err != nil {
+		return index.NewIVF(config)
+	}
+	return ivf
+}
+
+// saveIVF rewrites the whole IVF index next to the SSTables, which training
+// needs as it moves every vector. The caller must hold the store lock.
+func (s *Store) saveIVF() error {
+	if s.ivf == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.destDir, 0755); err != nil {
+		return fmt.Errorf("could not create %s: %v", s.destDir, err)
+	}
+	if err := s.ivf.Save(filepath.Join(s.destDir, ivfFile)); err != nil {
+		return fmt.Errorf("could not save ivf index: %v", err)
+	}
+	return nil
+}
+
+// persistIVF appends what changed in the IVF index since it was saved to
+// the index's log, which is cheap enough for every memtable flush. The
+// caller must hold the lock.
+func (s *Store) persistIVF() error {
+	if s.ivf == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.destDir, 0755); err != nil {
+		return fmt.Errorf("could not create %s: %v", s.destDir, err)
+	}
+	if err := s.ivf.Persist(filepath.Join(s.destDir, ivfFile)); err != nil {
+		return fmt.Errorf("could not persist ivf index: %v", err)
+	}
+	return nil
+}
+
+// TrainIndex (re)trains the store's IVF index on its current vectors
+// instead of waiting for it to collect IVFConfig.TrainAfter of them. IVF-PQ
+// and flat PQ indexes only keep codes, so it is rebuilt from the stored vectors,
+// as are an HNSW graph and a disk index.
+func (s *Store) TrainIndex() error {
+	if s.options.Index == IndexHNSW {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.buildHNSW()
+	}
+	if s.options.Index == IndexDiskANN {
+		return s.buildDiskANN()
+	}
+	if s.ivf == nil {
+		return fmt.Errorf("store has no %s or %s index to train", IndexIVF, IndexPQ)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.ivf.Quantized() {
+		if err := s.ivf.Train(); err != nil {
+			return fmt.Errorf("could not train ivf index: %v", err)
+		}
+		return s.saveIVF()
+	}
+
+	config := ivfConfig(s.options)
+	config.TrainAfter = 0
+	rebuilt := index.NewIVF(config)
+	var addErr error
+	err := s.scanLatest(func(key string, entry Entry) {
+		if addErr == nil && len(entry.Vector) > 0 {
+			addErr = rebuilt.Add(key, entry.Vector)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if addErr != nil {
+		return fmt.Errorf("could not rebuild ivf index: %v", addErr)
+	}
+	if err := rebuilt.Train(); err != nil {
+		return fmt.Errorf("could not train ivf index: %v", err)
+	}
+	s.ivf = rebuilt
+
+	return s.saveIVF()
+}
+
+func (s *Store) searchIVF(queryVector []float32, metric string, scorer *search.Scorer, nprobe int) ([]Result, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	candidates, err := s.ivf.ProbeScores(queryVector, nprobe, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	// codes only approximate the vectors, so the best candidates are scored
+	// again against the stored vectors
+	rescore := s.ivf.Quantized() && s.options.Rescore > 0
+	if rescore {
+		sort.Slice(candidates, func(i, j int) bool {
+			return search.Closer(metric, candidates[i].Score, candidates[j].Score)
+		})
+		if len(candidates) > s.options.Rescore {
+			candidates = candidates[:s.options.Rescore]
+		}
+	}
+
+	results := make([]Result, 0, len(candidates))
+	for _, candidate := range candidates {
+		entry, exists := s.get(candidate.ID)
+		if !exists {
+			continue
+		}
+		score := candidate.Score
+		if rescore {
+			if len(entry.Vector) != len(queryVector) {
+				continue
+			}
+			score = scorer.Score(entry.Vector, entry.Norm)
+		}
+		if !math.IsNaN(score) && !math.IsInf(score, 0) {
+			results = append(results, newResult(candidate.ID, entry, score))
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
@@ -3,23 +3,71 @@ package storage
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
 	"github.com/google/uuid"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 type Entry struct {
 	Value     string
-	Vector    []float64
+	Vector    []float32
 	Deleted   bool
 	Timestamp int64
+
+	// Vectors holds per-token vectors for late-interaction models, and is
+	// empty for ordinary single-vector entries
+	Vectors [][]float32
+
+	// Parent is the key of the document a chunk was split from, and is
+	// empty for entries stored whole
+	Parent string
+
+	// Chunks is how many chunks a document was split into. Chunked
+	// documents keep their full text but no vector of their own.
+	Chunks int
+
+	// Sparse holds term weights from a sparse (SPLADE style) model next to
+	// the dense vector
+	Sparse search.SparseVector
+
+	// Metadata holds caller supplied fields, used to filter and group
+	// results
+	Metadata map[string]string
+
+	// Norm is the Euclidean length of Vector as stored, so cosine scoring
+	// doesn't recompute it for every query. It is filled in when an entry is
+	// read back, from the stored norm of vectors of normMinDims or more, and
+	// ignored when one is written.
+	Norm float32
 }
 
+// Optional data is appended after the fixed part of a serialized entry as
+// tagged sections: a one byte tag, a uint32 payload length and the payload.
+// Entries written before a section existed simply don't have it, and
+// readers skip tags they don't know.
+const (
+	sectionMultiVector byte = 1
+	sectionParent      byte = 2
+	sectionChunks      byte = 3
+	sectionSparse      byte = 4
+	sectionMetadata    byte = 5
+	sectionNorm        byte = 6
+)
+
+// normMinDims is the shortest vector whose norm is stored. Shorter ones
+// are cheaper to measure on read than the section is to store.
+const normMinDims = 16
+
 type Memtable struct {
 	Data    *SkipList
 	maxSize int
 	size    int
+
+	// precision vectors are serialized in, float32 when empty
+	precision string
 }
 
 func NewMemtable(maxSize int) *Memtable {
@@ -34,7 +82,7 @@ func (m *Memtable) Put(key string, entry Entry, destPath string) error {
 	var value []byte
 	var err error
 
-	value, err = SerializeEntry(entry)
+	value, err = SerializeEntryWithPrecision(entry, m.precision)
 	if err != nil {
 		return fmt.Errorf("error when serializing data: %v", err)
 	}
@@ -58,17 +106,58 @@ func (m *Memtable) Put(key string, entry Entry, destPath string) error {
 }
 
 func SerializeEntry(entry Entry) ([]byte, error) {
+	return SerializeEntryWithPrecision(entry, PrecisionFloat32)
+}
+
+// SerializeEntryWithPrecision writes entry in the current FormatVersion
+// with its vectors in precision
+func SerializeEntryWithPrecision(entry Entry, precision string) ([]byte, error) {
+	encoding, err := precisionEncoding(precision)
+	if err != nil {
+		return nil, err
+	}
+	size, _ := encodingSize(encoding)
+
 	valueLen := int32(len(entry.Value))
 	vectorLen := int32(len(entry.Vector))
-	totalBufSize := 1 + 8 + 4 + valueLen + 4 + 8*vectorLen
-	buf := make([]byte, totalBufSize)
+	totalBufSize := 1 + 8 + 4 + valueLen + 4 + 1 + int32(size)*vectorLen
+
+	var sections []byte
+	if len(entry.Vectors) > 0 {
+		payload, err := serializeMultiVector(entry.Vectors, encoding)
+		if err != nil {
+			return nil, err
+		}
+		sections = appendSection(sections, sectionMultiVector, payload)
+	}
+	if entry.Parent != "" {
+		sections = appendSection(sections, sectionParent, []byte(entry.Parent))
+	}
+	if entry.Sparse.Len() > 0 {
+		payload, err := serializeSparse(entry.Sparse)
+		if err != nil {
+			return nil, err
+		}
+		sections = appendSection(sections, sectionSparse, payload)
+	}
+	if len(entry.Metadata) > 0 {
+		sections = appendSection(sections, sectionMetadata, serializeMetadata(entry.Metadata))
+	}
+	if entry.Chunks > 0 {
+		sections = appendSection(sections, sectionChunks, binary.LittleEndian.AppendUint32(nil, uint32(entry.Chunks)))
+	}
+	if len(entry.Vector) >= normMinDims {
+		norm := storedNorm(entry.Vector, encoding)
+		sections = appendSection(sections, sectionNorm, binary.LittleEndian.AppendUint32(nil, math.Float32bits(norm)))
+	}
+
+	buf := make([]byte, int(totalBufSize)+len(sections))
 	offset := 0
 
-	// write deleted fisrt
+	// write the format version and deleted fisrt
+	buf[offset] = FormatVersion << 1
 	if entry.Deleted {
-		buf[offset] = 1
-	} else {
-		buf[offset] = 0
+		buf[offset] |= 1
 	}
 	offset += 1
 
@@ -84,13 +173,199 @@ func SerializeEntry(entry Entry) ([]byte, error) {
 	binary.LittleEndian.PutUint32(buf[offset:], uint32(vectorLen))
 	offset += 4
 
-	for i, v := range entry.Vector {
-		binary.LittleEndian.PutUint64(buf[offset+i*8:], math.Float64bits(v))
+	buf[offset] = encoding
+	offset += 1
+
+	putVector(buf[offset:], entry.Vector, encoding)
+	offset += size * int(vectorLen)
+
+	copy(buf[offset:], sections)
+
+	return buf, nil
+}
+
+// storedNorm is the norm of vector after rounding it to encoding, which
+// is the vector readers get back
+func storedNorm(vector []float32, encoding byte) float32 {
+	if encoding != encodingFloat32 {
+		size, _ := encodingSize(encoding)
+		buf := make([]byte, size*len(vector))
+		putVector(buf, vector, encoding)
+		vector = getVector(buf, len(vector), encoding)
+	}
+	return float32(search.Norm(vector))
+}
+
+func appendSection(buf []byte, tag byte, payload []byte) []byte {
+	buf = append(buf, tag)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	return append(buf, payload...)
+}
+
+// serializeMultiVector writes the token count, dimension and encoding
+// followed by every token vector back to back
+func serializeMultiVector(vectors [][]float32, encoding byte) ([]byte, error) {
+	dims := len(vectors[0])
+	size, err := encodingSize(encoding)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 9+size*dims*len(vectors))
+	binary.LittleEndian.PutUint32(buf, uint32(len(vectors)))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(dims))
+	buf[8] = encoding
+
+	offset := 9
+	for i, vector := range vectors {
+		if len(vector) != dims {
+			return nil, fmt.Errorf("token vector %d has %d dimensions, expected %d", i, len(vector), dims)
+		}
+		putVector(buf[offset:], vector, encoding)
+		offset += size * dims
 	}
 
 	return buf, nil
 }
 
+// deserializeMultiVector reads a multi-vector section, which version 1
+// entries wrote as float64s without an encoding byte
+func deserializeMultiVector(data []byte, version byte) ([][]float32, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("multi-vector section too short, got %d bytes", len(data))
+	}
+	count := int(binary.LittleEndian.Uint32(data))
+	dims := int(binary.LittleEndian.Uint32(data[4:]))
+	offset := 8
+	encoding := encodingFloat64
+	if version >= 2 {
+		if len(data) < 9 {
+			return nil, fmt.Errorf("multi-vector section too short, got %d bytes", len(data))
+		}
+		encoding = data[8]
+		offset = 9
+	}
+	size, err := encodingSize(encoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != offset+size*count*dims {
+		return nil, fmt.Errorf("multi-vector section length mismatch: %d vectors of %d dimensions in %d bytes", count, dims, len(data))
+	}
+
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		vectors[i] = getVector(data[offset:], dims, encoding)
+		offset += size * dims
+	}
+
+	return vectors, nil
+}
+
+// serializeSparse writes the number of non-zero weights, their indices and
+// then their values
+func serializeSparse(vec search.SparseVector) ([]byte, error) {
+	if len(vec.Indices) != len(vec.Values) {
+		return nil, fmt.Errorf("sparse vector has %d indices but %d values", len(vec.Indices), len(vec.Values))
+	}
+
+	count := len(vec.Indices)
+	buf := make([]byte, 4+12*count)
+	binary.LittleEndian.PutUint32(buf, uint32(count))
+	offset := 4
+	for _, index := range vec.Indices {
+		binary.LittleEndian.PutUint32(buf[offset:], index)
+		offset += 4
+	}
+	for _, v := range vec.Values {
+		binary.LittleEndian.PutUint64(buf[offset:], math.Float64bits(v))
+		offset += 8
+	}
+
+	return buf, nil
+}
+
+func deserializeSparse(data []byte) (search.SparseVector, error) {
+	if len(data) < 4 {
+		return search.SparseVector{}, fmt.Errorf("sparse section too short, got %d bytes", len(data))
+	}
+	count := int(binary.LittleEndian.Uint32(data))
+	if len(data) != 4+12*count {
+		return search.SparseVector{}, fmt.Errorf("sparse section length mismatch: %d weights in %d bytes", count, len(data))
+	}
+
+	vec := search.SparseVector{
+		Indices: make([]uint32, count),
+		Values:  make([]float64, count),
+	}
+	offset := 4
+	for i := range vec.Indices {
+		vec.Indices[i] = binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+	}
+	for i := range vec.Values {
+		vec.Values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[offset:]))
+		offset += 8
+	}
+
+	return vec, nil
+}
+
+// serializeMetadata writes the number of fields followed by every key and
+// value as length-prefixed strings, keys in sorted order
+func serializeMetadata(metadata map[string]string) []byte {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(len(keys)))
+	for _, k := range keys {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(k)))
+		buf = append(buf, k...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(metadata[k])))
+		buf = append(buf, metadata[k]...)
+	}
+	return buf
+}
+
+func deserializeMetadata(data []byte) (map[string]string, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("metadata section too short, got %d bytes", len(data))
+	}
+	count := int(binary.LittleEndian.Uint32(data))
+	offset := 4
+
+	readString := func() (string, error) {
+		if offset+4 > len(data) {
+			return "", fmt.Errorf("invalid metadata length: reading past end of data")
+		}
+		n := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if offset+n > len(data) {
+			return "", fmt.Errorf("invalid metadata length: reading past end of data")
+		}
+		str := string(data[offset : offset+n])
+		offset += n
+		return str, nil
+	}
+
+	metadata := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		k, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		metadata[k] = v
+	}
+
+	return metadata, nil
+}
+
 func (m *Memtable) Get(key string) (Entry, bool) {
 	value, exists := m.Data.Search(key)
 	if !exists {
@@ -112,7 +387,14 @@ func DeserializeEntry(data []byte) (Entry, error) {
 	}
 
 	var offset = 0
-	deleted := data[offset] == 1
+	deleted := data[offset]&1 == 1
+	version := data[offset] >> 1
+	if version == 0 {
+		version = 1
+	}
+	if version > FormatVersion {
+		return Entry{}, fmt.Errorf("entry has format version %d, newer than %d", version, FormatVersion)
+	}
 	offset += 1
 
 	timestamp := int64(binary.LittleEndian.Uint64(data[offset:]))
@@ -135,23 +417,89 @@ func DeserializeEntry(data []byte) (Entry, error) {
 	vectorLen := binary.LittleEndian.Uint32(data[offset:])
 	offset += 4
 
-	requiredBytes := offset + int(vectorLen)*8
+	encoding := encodingFloat64
+	if version >= 2 {
+		if offset+1 > len(data) {
+			return Entry{}, fmt.Errorf("invalid vector encoding: reading past end of data")
+		}
+		encoding = data[offset]
+		offset += 1
+	}
+	size, err := encodingSize(encoding)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	requiredBytes := offset + int(vectorLen)*size
 	if requiredBytes > len(data) {
 		return Entry{}, fmt.Errorf("invalid vector length: reading past end of data")
 	}
-	vector := make([]float64, vectorLen)
-	for i := range vector {
-		bits := binary.LittleEndian.Uint64(data[offset:])
-		vector[i] = math.Float64frombits(bits)
-		offset += 8
-	}
+	vector := getVector(data[offset:], int(vectorLen), encoding)
+	offset += int(vectorLen) * size
 
-	return Entry{
+	entry := Entry{
 		Value:     string(value),
 		Vector:    vector,
 		Deleted:   deleted,
 		Timestamp: timestamp,
-	}, nil
+	}
+	hasNorm := false
+
+	for offset < len(data) {
+		if offset+5 > len(data) {
+			return Entry{}, fmt.Errorf("invalid section header: reading past end of data")
+		}
+		tag := data[offset]
+		sectionLen := int(binary.LittleEndian.Uint32(data[offset+1:]))
+		offset += 5
+
+		if offset+sectionLen > len(data) {
+			return Entry{}, fmt.Errorf("invalid section length: reading past end of data")
+		}
+		payload := data[offset : offset+sectionLen]
+		offset += sectionLen
+
+		switch tag {
+		case sectionMultiVector:
+			vectors, err := deserializeMultiVector(payload, version)
+			if err != nil {
+				return Entry{}, err
+			}
+			entry.Vectors = vectors
+		case sectionParent:
+			entry.Parent = string(payload)
+		case sectionSparse:
+			sparse, err := deserializeSparse(payload)
+			if err != nil {
+				return Entry{}, err
+			}
+			entry.Sparse = sparse
+		case sectionMetadata:
+			metadata, err := deserializeMetadata(payload)
+			if err != nil {
+				return Entry{}, err
+			}
+			entry.Metadata = metadata
+		case sectionChunks:
+			if len(payload) != 4 {
+				return Entry{}, fmt.Errorf("invalid chunk count section, got %d bytes", len(payload))
+			}
+			entry.Chunks = int(binary.LittleEndian.Uint32(payload))
+		case sectionNorm:
+			if len(payload) != 4 {
+				return Entry{}, fmt.Errorf("invalid norm section, got %d bytes", len(payload))
+			}
+			entry.Norm = math.Float32frombits(binary.LittleEndian.Uint32(payload))
+			hasNorm = true
+		}
+	}
+
+	// entries written before norms were stored
+	if !hasNorm && len(entry.Vector) > 0 {
+		entry.Norm = float32(search.Norm(entry.Vector))
+	}
+
+	return entry, nil
 }
 
 func (m *Memtable) Size() int {
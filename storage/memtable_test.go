@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"encoding/binary"
+	"github.com/ahhcash/ghastlydb/search"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
@@ -33,7 +36,7 @@ func (s *MemtableTestSuite) TestNewMemtable() {
 func (s *MemtableTestSuite) TestPutAndGet() {
 	entry := Entry{
 		Value:     "test value",
-		Vector:    []float64{1.0, 2.0, 3.0},
+		Vector:    []float32{1.0, 2.0, 3.0},
 		Deleted:   false,
 		Timestamp: time.Now().UnixMilli(),
 	}
@@ -57,13 +60,13 @@ func (s *MemtableTestSuite) TestPutAndGet() {
 func (s *MemtableTestSuite) TestUpdateExisting() {
 	entry1 := Entry{
 		Value:     "initial value",
-		Vector:    []float64{1.0, 2.0},
+		Vector:    []float32{1.0, 2.0},
 		Timestamp: time.Now().UnixMilli(),
 		Deleted:   false,
 	}
 	entry2 := Entry{
 		Value:     "updated value",
-		Vector:    []float64{3.0, 4.0},
+		Vector:    []float32{3.0, 4.0},
 		Timestamp: time.Now().UnixMilli(),
 		Deleted:   false,
 	}
@@ -92,7 +95,7 @@ func (s *MemtableTestSuite) TestFlushToDisk() {
 	for i := 0; i < 100; i++ {
 		entry := Entry{
 			Value:     "test value",
-			Vector:    []float64{1.0, 2.0},
+			Vector:    []float32{1.0, 2.0},
 			Timestamp: time.Now().UnixMilli(),
 			Deleted:   false,
 		}
@@ -113,7 +116,7 @@ func (s *MemtableTestSuite) TestFlushToDisk() {
 func (s *MemtableTestSuite) TestClear() {
 	entry := Entry{
 		Value:  "test value",
-		Vector: []float64{1.0, 2.0},
+		Vector: []float32{1.0, 2.0},
 	}
 
 	// Add some data
@@ -133,7 +136,7 @@ func (s *MemtableTestSuite) TestClear() {
 func (s *MemtableTestSuite) TestSerializeDeserialize() {
 	original := Entry{
 		Value:   "test value",
-		Vector:  []float64{1.0, 2.0, 3.0},
+		Vector:  []float32{1.0, 2.0, 3.0},
 		Deleted: false,
 	}
 
@@ -149,6 +152,204 @@ func (s *MemtableTestSuite) TestSerializeDeserialize() {
 	assert.Equal(s.T(), original.Vector, deserialized.Vector)
 }
 
+func (s *MemtableTestSuite) TestSerializeDeserializeMultiVector() {
+	original := Entry{
+		Value:   "test value",
+		Vector:  []float32{0.5, 0.5},
+		Vectors: [][]float32{{1.0, 0.0}, {0.0, 1.0}, {0.6, 0.8}},
+	}
+
+	serialized, err := SerializeEntry(original)
+	assert.NoError(s.T(), err)
+
+	deserialized, err := DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), original.Vector, deserialized.Vector)
+	assert.Equal(s.T(), original.Vectors, deserialized.Vectors)
+
+	// entries without token vectors keep the original layout
+	plain, err := SerializeEntry(Entry{Value: "v", Vector: []float32{1.0}})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), plain, 1+8+4+1+4+1+4)
+
+	// unknown sections written by newer versions are skipped
+	withUnknown := appendSection(plain, 0xff, []byte{1, 2, 3})
+	deserialized, err = DeserializeEntry(withUnknown)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "v", deserialized.Value)
+
+	_, err = SerializeEntry(Entry{Vectors: [][]float32{{1.0}, {1.0, 2.0}}})
+	assert.Error(s.T(), err)
+}
+
+func (s *MemtableTestSuite) TestSerializeDeserializeChunkReferences() {
+	chunk := Entry{Value: "first part", Vector: []float32{1.0}, Parent: "doc"}
+	serialized, err := SerializeEntry(chunk)
+	assert.NoError(s.T(), err)
+	deserialized, err := DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "doc", deserialized.Parent)
+	assert.Equal(s.T(), 0, deserialized.Chunks)
+
+	document := Entry{Value: "first part. second part.", Chunks: 2}
+	serialized, err = SerializeEntry(document)
+	assert.NoError(s.T(), err)
+	deserialized, err = DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "", deserialized.Parent)
+	assert.Equal(s.T(), 2, deserialized.Chunks)
+	assert.Empty(s.T(), deserialized.Vector)
+}
+
+func (s *MemtableTestSuite) TestSerializeDeserializeSparseAndMetadata() {
+	original := Entry{
+		Value:  "test value",
+		Vector: []float32{0.5, 0.5},
+		Sparse: search.SparseVector{Indices: []uint32{3, 17, 2048}, Values: []float64{0.25, 1.5, 0.75}},
+		Parent: "doc",
+	}
+
+	serialized, err := SerializeEntry(original)
+	assert.NoError(s.T(), err)
+
+	deserialized, err := DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), original.Sparse, deserialized.Sparse)
+	assert.Equal(s.T(), "doc", deserialized.Parent)
+
+	withMetadata := Entry{Value: "v", Metadata: map[string]string{"source": "wiki", "lang": "en", "empty": ""}}
+	serialized, err = SerializeEntry(withMetadata)
+	assert.NoError(s.T(), err)
+	deserialized, err = DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), withMetadata.Metadata, deserialized.Metadata)
+
+	_, err = SerializeEntry(Entry{Sparse: search.SparseVector{Indices: []uint32{1}}})
+	assert.Error(s.T(), err)
+}
+
+// version1Entry builds an entry the way SerializeEntry wrote them before
+// FormatVersion 2: a plain deleted byte and float64 vectors
+func version1Entry(deleted bool, value string, vector []float64, tokens [][]float64) []byte {
+	buf := []byte{0}
+	if deleted {
+		buf[0] = 1
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, 42)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(value)))
+	buf = append(buf, value...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(vector)))
+	for _, v := range vector {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	}
+	if len(tokens) > 0 {
+		payload := binary.LittleEndian.AppendUint32(nil, uint32(len(tokens)))
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(len(tokens[0])))
+		for _, token := range tokens {
+			for _, v := range token {
+				payload = binary.LittleEndian.AppendUint64(payload, math.Float64bits(v))
+			}
+		}
+		buf = appendSection(buf, sectionMultiVector, payload)
+	}
+	return buf
+}
+
+func (s *MemtableTestSuite) TestDeserializeVersion1() {
+	entry, err := DeserializeEntry(version1Entry(false, "old", []float64{0.25, -1.5}, [][]float64{{1, 0}, {0, 1}}))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "old", entry.Value)
+	assert.Equal(s.T(), int64(42), entry.Timestamp)
+	assert.False(s.T(), entry.Deleted)
+	assert.Equal(s.T(), []float32{0.25, -1.5}, entry.Vector)
+	assert.Equal(s.T(), [][]float32{{1, 0}, {0, 1}}, entry.Vectors)
+	// old entries have no norm section, so it is computed on read
+	assert.InDelta(s.T(), math.Sqrt(0.25*0.25+1.5*1.5), float64(entry.Norm), 1e-6)
+
+	entry, err = DeserializeEntry(version1Entry(true, "", nil, nil))
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), entry.Deleted)
+
+	// entries from a newer format are refused rather than misread
+	newer, err := SerializeEntry(Entry{Value: "v"})
+	assert.NoError(s.T(), err)
+	newer[0] = (FormatVersion + 1) << 1
+	_, err = DeserializeEntry(newer)
+	assert.Error(s.T(), err)
+}
+
+func (s *MemtableTestSuite) TestSerializePrecision() {
+	original := Entry{
+		Value:   "v",
+		Vector:  []float32{0.1, -2, 3.5},
+		Vectors: [][]float32{{0.1, 0.2}, {0.3, 0.4}},
+	}
+
+	for precision, size := range map[string]int{PrecisionFloat32: 4, PrecisionFloat16: 2, PrecisionBFloat16: 2} {
+		serialized, err := SerializeEntryWithPrecision(original, precision)
+		assert.NoError(s.T(), err)
+		// fixed part, vector, then the token vectors in a section
+		assert.Len(s.T(), serialized, 1+8+4+1+4+1+3*size+5+9+4*size, precision)
+
+		deserialized, err := DeserializeEntry(serialized)
+		assert.NoError(s.T(), err)
+		assert.InDeltaSlice(s.T(), original.Vector, deserialized.Vector, 0.02, precision)
+		for i := range original.Vectors {
+			assert.InDeltaSlice(s.T(), original.Vectors[i], deserialized.Vectors[i], 0.02, precision)
+		}
+	}
+
+	_, err := SerializeEntryWithPrecision(original, "float8")
+	assert.Error(s.T(), err)
+
+	memtable := NewMemtable(10)
+	memtable.precision = PrecisionFloat16
+	assert.NoError(s.T(), memtable.Put("k", original, s.testPath))
+	value, _ := memtable.Data.Search("k")
+	assert.Len(s.T(), value, 1+8+4+1+4+1+3*2+5+9+4*2)
+}
+
+func (s *MemtableTestSuite) TestSerializeNorm() {
+	vector := make([]float32, normMinDims)
+	for i := range vector {
+		vector[i] = 0.1 * float32(i-5)
+	}
+	original := Entry{Value: "v", Vector: vector, Norm: 100}
+
+	for _, precision := range []string{PrecisionFloat32, PrecisionFloat16, PrecisionBFloat16} {
+		serialized, err := SerializeEntryWithPrecision(original, precision)
+		assert.NoError(s.T(), err)
+		encoding, _ := precisionEncoding(precision)
+		size, _ := encodingSize(encoding)
+		assert.Len(s.T(), serialized, 1+8+4+1+4+1+normMinDims*size+5+4, precision)
+		deserialized, err := DeserializeEntry(serialized)
+		assert.NoError(s.T(), err)
+		// the norm is that of the vector as read back, whatever the caller
+		// set
+		assert.Equal(s.T(), float32(search.Norm(deserialized.Vector)), deserialized.Norm, precision)
+	}
+
+	// shorter vectors store no norm, it is measured on read
+	serialized, err := SerializeEntry(Entry{Value: "v", Vector: []float32{3, 4}})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), serialized, 1+8+4+1+4+1+2*4)
+	deserialized, err := DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), float32(5), deserialized.Norm)
+
+	// entries without a vector have no norm
+	serialized, err = SerializeEntry(Entry{Value: "v"})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), serialized, 1+8+4+1+4+1)
+	deserialized, err = DeserializeEntry(serialized)
+	assert.NoError(s.T(), err)
+	assert.Zero(s.T(), deserialized.Norm)
+
+	broken := appendSection(serialized, sectionNorm, []byte{1, 2})
+	_, err = DeserializeEntry(broken)
+	assert.Error(s.T(), err)
+}
+
 func TestMemtableSuite(t *testing.T) {
 	suite.Run(t, new(MemtableTestSuite))
 }
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MigrateSSTable rewrites the SSTable at path so that every entry is in the
+// current FormatVersion with its vectors in precision. Tables that already
+// are stay untouched. It reports whether the table was rewritten.
+func MigrateSSTable(path string, precision string) (bool, error) {
+	sstable, err := OpenSSTable(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = sstable.Close() }()
+
+	values := make([][]byte, len(sstable.Index))
+	changed := false
+	for i, key := range sstable.Index {
+		raw, err := sstable.value(i)
+		if err != nil {
+			return false, fmt.Errorf("could not read %s from %s: %v", key, path, err)
+		}
+		entry, err := DeserializeEntry(raw)
+		if err != nil {
+			return false, fmt.Errorf("could not deserialize %s from %s: %v", key, path, err)
+		}
+		values[i], err = SerializeEntryWithPrecision(entry, precision)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(raw, values[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	tempPath := path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return false, fmt.Errorf("could not create %s: %v", tempPath, err)
+	}
+	defer func() { _ = file.Close() }()
+	for i, key := range sstable.Index {
+		if err := writeRecord(file, key, values[i]); err != nil {
+			return false, fmt.Errorf("could not write record: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return false, fmt.Errorf("could not close %s: %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return false, fmt.Errorf("could not rename temp file to sst: %v", err)
+	}
+
+	return true, nil
+}
+
+// Migrate runs MigrateSSTable over every SSTable in dir and returns how many
+// it rewrote
+func Migrate(dir string, precision string) (int, error) {
+	if _, err := precisionEncoding(precision); err != nil {
+		return 0, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sst"))
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, file := range files {
+		rewritten, err := MigrateSSTable(file, precision)
+		if err != nil {
+			return migrated, err
+		}
+		if rewritten {
+			migrated++
+		}
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+)
+
+// Precisions vectors can be written to disk in. Vectors are float32 in
+// memory either way, float16 and bfloat16 halve their size on disk again at
+// the cost of rounding.
+const (
+	PrecisionFloat32  = "float32"
+	PrecisionFloat16  = "float16"
+	PrecisionBFloat16 = "bfloat16"
+)
+
+// FormatVersion is the layout SerializeEntry writes. Version 1 entries
+// start with a plain deleted byte, so their version bits read as zero, and
+// hold float64 vectors. Version 2 keeps the version above the deleted bit
+// and writes an encoding byte before the elements of every vector.
+const FormatVersion = 2
+
+// vector encodings, as written after the length of a vector
+const (
+	encodingFloat64  byte = 0
+	encodingFloat32  byte = 1
+	encodingFloat16  byte = 2
+	encodingBFloat16 byte = 3
+)
+
+// precisionEncoding maps a precision to its encoding, float32 by default.
+// float64 is only ever read, from version 1 entries.
+func precisionEncoding(precision string) (byte, error) {
+	switch precision {
+	case PrecisionFloat32, "":
+		return encodingFloat32, nil
+	case PrecisionFloat16:
+		return encodingFloat16, nil
+	case PrecisionBFloat16:
+		return encodingBFloat16, nil
+	default:
+		return 0, fmt.Errorf("unknown vector precision %s", precision)
+	}
+}
+
+// encodingSize is how many bytes one element takes in encoding
+func encodingSize(encoding byte) (int, error) {
+	switch encoding {
+	case encodingFloat64:
+		return 8, nil
+	case encodingFloat32:
+		return 4, nil
+	case encodingFloat16, encodingBFloat16:
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("unknown vector encoding %d", encoding)
+	}
+}
+
+// putVector writes vector into buf, which must have room for it
+func putVector(buf []byte, vector []float32, encoding byte) {
+	for i, v := range vector {
+		switch encoding {
+		case encodingFloat64:
+			binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(float64(v)))
+		case encodingFloat32:
+			binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+		case encodingFloat16:
+			binary.LittleEndian.PutUint16(buf[i*2:], search.Float16Bits(v))
+		case encodingBFloat16:
+			binary.LittleEndian.PutUint16(buf[i*2:], search.BFloat16Bits(v))
+		}
+	}
+}
+
+// getVector reads n elements written by putVector
+func getVector(data []byte, n int, encoding byte) []float32 {
+	vector := make([]float32, n)
+	for i := range vector {
+		switch encoding {
+		case encodingFloat64:
+			vector[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:])))
+		case encodingFloat32:
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		case encodingFloat16:
+			vector[i] = search.Float16FromBits(binary.LittleEndian.Uint16(data[i*2:]))
+		case encodingBFloat16:
+			vector[i] = search.BFloat16FromBits(binary.LittleEndian.Uint16(data[i*2:]))
+		}
+	}
+	return vector
+}
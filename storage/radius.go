@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ahhcash/ghastlydb/search"
+	"math"
+	"sort"
+)
+
+// SearchRadius returns every entry whose vector lies within radius of
+// queryVector, closest first. radius is in the metric's own units, see
+// search.InRadius. Unlike SearchVector, only the newest version of each key
+// is considered, so overwritten and deleted entries never match.
+//
+// A store with an unquantized IndexHNSW graph answers l2 searches with
+// index.HNSW.SearchRadius, which is approximate: entries inside the radius
+// that the graph only reaches through entries outside it are missed.
+// Everything else, cosine and dot included, is a SearchRadiusExact scan.
+func (s *Store) SearchRadius(queryVector []float32, metric string, radius float64) ([]Result, error) {
+	scorer, err := search.NewScorer(metric, queryVector)
+	if err != nil {
+		return nil, err
+	}
+	if s.options.Index == IndexHNSW && metric == "l2" {
+		results, searched, err := s.searchHNSWRadius(queryVector, radius, scorer)
+		if searched || err != nil {
+			return results, err
+		}
+	}
+
+	return s.searchRadiusExact(queryVector, metric, radius, scorer)
+}
+
+// SearchRadiusExact is SearchRadius scanning the whole store, whatever its
+// index, so that no entry inside the radius is missed
+func (s *Store) SearchRadiusExact(queryVector []float32, metric string, radius float64) ([]Result, error) {
+	scorer, err := search.NewScorer(metric, queryVector)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.searchRadiusExact(queryVector, metric, radius, scorer)
+}
+
+func (s *Store) searchRadiusExact(queryVector []float32, metric string, radius float64, scorer *search.Scorer) ([]Result, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	results := make([]Result, 0)
+	err := s.scanLatest(func(key string, entry Entry) {
+		if len(entry.Vector) != len(queryVector) {
+			return
+		}
+		score := scorer.Score(entry.Vector, entry.Norm)
+		if math.IsNaN(score) || math.IsInf(score, 0) {
+			return
+		}
+		if search.InRadius(metric, score, radius) {
+			results = append(results, newResult(key, entry, score))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRadius(results, metric)
+
+	return results, nil
+}
+
+// sortRadius orders radius results closest first, breaking ties by key so
+// that pages of the results stay stable
+func sortRadius(results []Result, metric string) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Key < results[j].Key
+		}
+		return search.Closer(metric, results[i].Score, results[j].Score)
+	})
+}
+
+// Scan calls fn with the newest live version of every key, chunks and
+// chunked documents included
+func (s *Store) Scan(fn func(key string, entry Entry)) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.scanLatest(fn)
+}
+
+// scanLatest calls fn with the newest live version of every key, reading
+// the memtable first and then the SSTables from newest to oldest. The
+// caller must hold the store lock.
+func (s *Store) scanLatest(fn func(key string, entry Entry)) error {
+	seen := make(map[string]bool)
+	s.scanMemtable(seen, fn)
+	return scanSSTables(s.sstables, seen, fn)
+}
+
+// scanMemtable calls fn with the live entries of the memtable, marking
+// every key it holds as seen. The caller must hold the store lock.
+func (s *Store) scanMemtable(seen map[string]bool, fn func(key string, entry Entry)) {
+	current := s.memtable.Data.head.next[0]
+	for current != nil {
+		entry, err := DeserializeEntry(current.value)
+		if err == nil && !seen[current.key] {
+			seen[current.key] = true
+			if !entry.Deleted {
+				fn(current.key, entry)
+			}
+		}
+		current = current.next[0]
+	}
+}
+
+// scanSSTables calls fn with the newest live version of every key of
+// sstables that isn't seen yet. SSTables never change once written, so
+// this needs no lock.
+func scanSSTables(sstables []*SSTable, seen map[string]bool, fn func(key string, entry Entry)) error {
+	for _, sstable := range sstables {
+		for _, key := range sstable.Index {
+			if seen[key] {
+				continue
+			}
+			entry, exists, err := sstable.Get(key)
+			if err != nil {
+				return fmt.Errorf("could not fetch key %s from sstable: %v", key, err)
+			}
+			seen[key] = true
+			if exists && !entry.Deleted {
+				fn(key, entry)
+			}
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"sort"
 )
@@ -86,39 +87,47 @@ func (sst *SSTable) Get(key string) (Entry, bool, error) {
 		return Entry{}, false, nil
 	}
 
-	_, err := sst.file.Seek(sst.positions[i], io.SeekStart)
+	valueBytes, err := sst.value(i)
 	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not seek within the file: %v", err)
+		return Entry{}, false, err
 	}
 
+	entry, err := DeserializeEntry(valueBytes)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("could not deserialize Value bytes: %v", err)
+	}
+
+	return entry, true, nil
+}
+
+// value reads the serialized entry of the i-th key. It reads at an offset
+// rather than seeking, so SSTables can be read concurrently.
+func (sst *SSTable) value(i int) ([]byte, error) {
+	r := io.NewSectionReader(sst.file, sst.positions[i], math.MaxInt64-sst.positions[i])
+
 	var keyLen int32
-	err = binary.Read(sst.file, binary.LittleEndian, &keyLen)
+	err := binary.Read(r, binary.LittleEndian, &keyLen)
 	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not read Key length: %v", err)
+		return nil, fmt.Errorf("could not read Key length: %v", err)
 	}
 
 	keyBytes := make([]byte, keyLen)
-	_, err = io.ReadFull(sst.file, keyBytes)
+	_, err = io.ReadFull(r, keyBytes)
 	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not read Key bytes: %v", err)
+		return nil, fmt.Errorf("could not read Key bytes: %v", err)
 	}
 
 	var valueLen int32
-	err = binary.Read(sst.file, binary.LittleEndian, &valueLen)
+	err = binary.Read(r, binary.LittleEndian, &valueLen)
 	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not read Value length: %v", err)
+		return nil, fmt.Errorf("could not read Value length: %v", err)
 	}
 
 	valueBytes := make([]byte, valueLen)
-	_, err = io.ReadFull(sst.file, valueBytes)
+	_, err = io.ReadFull(r, valueBytes)
 	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not read Value bytes: %v", err)
+		return nil, fmt.Errorf("could not read Value bytes: %v", err)
 	}
 
-	entry, err := DeserializeEntry(valueBytes)
-	if err != nil {
-		return Entry{}, false, fmt.Errorf("could not deserialize Value bytes: %v", err)
-	}
-
-	return entry, true, nil
+	return valueBytes, nil
 }
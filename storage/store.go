@@ -3,10 +3,12 @@ package storage
 import (
 	"fmt"
 	"github.com/ahhcash/ghastlydb/embed"
+	"github.com/ahhcash/ghastlydb/index"
 	"github.com/ahhcash/ghastlydb/search"
 	"math"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,6 +17,88 @@ type Result struct {
 	Key   string
 	Value string
 	Score float64
+
+	// Parent is the document a matching chunk belongs to
+	Parent string
+
+	Metadata map[string]string
+}
+
+func newResult(key string, entry Entry, score float64) Result {
+	return Result{
+		Key:      key,
+		Value:    entry.Value,
+		Score:    score,
+		Parent:   entry.Parent,
+		Metadata: entry.Metadata,
+	}
+}
+
+// MetricMaxSim selects late-interaction retrieval over per-token vectors
+const MetricMaxSim = "maxsim"
+
+type StoreOptions struct {
+	// MultiVector stores per-token vectors for every entry, which the
+	// model must provide by implementing embed.MultiVectorEmbedder
+	MultiVector bool
+
+	// CandidatesPerToken is how many nearest document tokens each query
+	// token looks up, the documents owning them are scored with MaxSim
+	CandidatesPerToken int
+
+	// Index is IndexFlat (the default) to scan every vector, IndexIVF to
+	// only scan the nearest posting lists of an IVF index configured by
+	// IVF, IndexPQ to scan product quantization codes of every vector,
+	// IndexHNSW to search an in-memory graph configured by HNSW, or
+	// IndexDiskANN to search a graph on disk configured by DiskANN. The
+	// graphs are built and searched by l2 distance, which ranks like cosine
+	// only on normalized vectors, and can't be searched by dot product
+	// (see CheckGraphMetric).
+	Index string
+	IVF   index.IVFConfig
+
+	// Rescore is how many of the best approximate matches of an IVF-PQ
+	// or flat PQ index are scored again against their full vectors, which are the
+	// only ones returned. Zero returns every match with its approximate
+	// score.
+	Rescore int
+
+	// HNSW configures the graph of an IndexHNSW store. An int8 quantized
+	// graph learns its value ranges once HNSWTrainAfter vectors are stored,
+	// every vector is scanned until then.
+	HNSW           index.HNSWConfig
+	HNSWTrainAfter int
+
+	// DiskANN configures the disk index of an IndexDiskANN store, whose
+	// delta graph is configured by HNSW
+	DiskANN index.DiskANNConfig
+
+	// Candidates is how many nearest neighbors an IndexHNSW or
+	// IndexDiskANN search scores
+	Candidates int
+
+	// Oversample multiplies Candidates for the approximate search of a
+	// quantized graph, whose matches are ranked again by their full vectors
+	Oversample int
+
+	// Precision is PrecisionFloat32 (the default), PrecisionFloat16 or
+	// PrecisionBFloat16, the format vectors are written to disk in
+	Precision string
+}
+
+func DefaultStoreOptions() StoreOptions {
+	return StoreOptions{
+		CandidatesPerToken: 64,
+		Index:              IndexFlat,
+		IVF:                index.DefaultIVFConfig(),
+		Rescore:            100,
+		HNSW:               index.DefaultHNSWConfig(),
+		HNSWTrainAfter:     1000,
+		DiskANN:            index.DefaultDiskANNConfig(),
+		Candidates:         100,
+		Oversample:         4,
+		Precision:          PrecisionFloat32,
+	}
 }
 
 type Store struct {
@@ -23,36 +107,277 @@ type Store struct {
 	lock     sync.RWMutex
 	destDir  string
 	model    embed.Embedder
+	options  StoreOptions
+	tokens   *index.TokenIndex
+	text     *index.TextIndex
+	sparse   *search.SparseIndex
+	ivf      *index.IVF
+	hnsw     *index.HNSW
+	disk     *index.DiskANN
+	delta    *index.HNSW
+
+	// hnswPending counts the writes while the graph waits to be trained
+	hnswPending int
+
+	// diskPending holds the keys written while a disk index is built, which
+	// its delta graph replays. It is nil when no build runs.
+	diskPending map[string]bool
 }
 
 func NewStore(maxSize int, desDir string, model embed.Embedder) *Store {
+	return NewStoreWithOptions(maxSize, desDir, model, DefaultStoreOptions())
+}
+
+func NewStoreWithOptions(maxSize int, desDir string, model embed.Embedder, options StoreOptions) *Store {
+	var ivf *index.IVF
+	if options.Index == IndexIVF || options.Index == IndexPQ {
+		ivf = openIVF(desDir, ivfConfig(options))
+	}
+	var hnsw *index.HNSW
+	if options.Index == IndexHNSW {
+		hnsw = newHNSW(options)
+	}
+	var disk *index.DiskANN
+	var delta *index.HNSW
+	if options.Index == IndexDiskANN {
+		disk = openDiskANN(desDir, options.DiskANN)
+		delta = newDelta(options)
+	}
+
+	memtable := NewMemtable(maxSize)
+	memtable.precision = options.Precision
+
 	return &Store{
-		memtable: NewMemtable(maxSize),
+		memtable: memtable,
 		sstables: []*SSTable{},
 		destDir:  desDir,
 		model:    model,
+		options:  options,
+		tokens:   index.NewTokenIndex(),
+		text:     index.NewTextIndex(),
+		sparse:   search.NewSparseIndex(),
+		ivf:      ivf,
+		hnsw:     hnsw,
+		disk:     disk,
+		delta:    delta,
 	}
 }
 
 func (s *Store) Put(key string, value string) error {
+	return s.PutWithOptions(key, value, PutOptions{})
+}
+
+// PutOptions carries the optional parts of an entry
+type PutOptions struct {
+	// Sparse is a sparse vector computed by the caller, stored next to the
+	// dense embedding
+	Sparse search.SparseVector
+
+	Metadata map[string]string
+}
+
+// PutSparse stores value with its dense embedding and a sparse vector
+// computed by the caller. An empty sparse vector stores a dense-only entry.
+func (s *Store) PutSparse(key string, value string, sparse search.SparseVector) error {
+	return s.PutWithOptions(key, value, PutOptions{Sparse: sparse})
+}
+
+func (s *Store) PutWithOptions(key string, value string, options PutOptions) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	sparse := options.Sparse
+	if len(sparse.Indices) > 0 || len(sparse.Values) > 0 {
+		var err error
+		sparse, err = search.NewSparseVector(sparse.Indices, sparse.Values)
+		if err != nil {
+			return fmt.Errorf("invalid sparse vector for %s: %v", key, err)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, err := s.embedEntry(value)
+	if err != nil {
+		return err
+	}
+	entry.Sparse = sparse
+	entry.Metadata = options.Metadata
+
+	err = s.deleteChunks(key, 0)
+	if err != nil {
+		return err
+	}
+
+	return s.putEntry(key, entry)
+}
+
+// SetMetadata replaces the metadata of a stored entry, keeping its vectors
+// so nothing is embedded again
+func (s *Store) SetMetadata(key string, metadata map[string]string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	vector, err := s.model.Embed(value)
+	entry, exists := s.get(key)
+	if !exists {
+		return fmt.Errorf("key %s does not exist", key)
+	}
+	entry.Metadata = metadata
+
+	return s.putEntry(key, entry)
+}
+
+// PutChunks stores a document as separately embedded chunks. Each chunk is
+// kept under ChunkKey(key, i) with a reference back to key and a copy of
+// the document's metadata, and the document itself keeps its full text
+// without a vector so searches only ever match its chunks.
+func (s *Store) PutChunks(key string, value string, chunks []string, metadata map[string]string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("document %s has no chunks", key)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, err := s.embedEntries(chunks)
+	if err != nil {
+		return err
+	}
+
+	// chunks past the new count would otherwise outlive the old version
+	err = s.deleteChunks(key, len(chunks))
 	if err != nil {
-		return fmt.Errorf("could not embed Value %s: %v", value, err)
+		return err
 	}
 
+	for i, entry := range entries {
+		entry.Parent = key
+		entry.Metadata = metadata
+		err = s.putEntry(ChunkKey(key, i), entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.putEntry(key, Entry{
+		Value:     value,
+		Timestamp: time.Now().UnixMilli(),
+		Chunks:    len(chunks),
+		Metadata:  metadata,
+	})
+}
+
+// ChunkKey is the key chunk i of a document is stored under
+func ChunkKey(key string, i int) string {
+	return fmt.Sprintf("%s#%d", key, i)
+}
+
+// checkKey rejects keys containing the '#' of chunk keys, which could
+// overwrite a document's chunk or have its chunks overwrite them
+func checkKey(key string) error {
+	if strings.Contains(key, "#") {
+		return fmt.Errorf("key %s cannot contain '#', which is reserved for chunk keys", key)
+	}
+	return nil
+}
+
+func (s *Store) embedEntry(value string) (Entry, error) {
 	entry := Entry{
 		Value:     value,
-		Vector:    vector,
 		Deleted:   false,
 		Timestamp: time.Now().UnixMilli(),
 	}
-	err = s.memtable.Put(key, entry, s.destDir)
+
+	var err error
+	if s.options.MultiVector {
+		entry.Vectors, err = s.embedMulti(value)
+		if err != nil {
+			return Entry{}, fmt.Errorf("could not embed Value %s: %v", value, err)
+		}
+		// a pooled vector keeps the single-vector metrics usable
+		entry.Vector = search.Normalize(search.Mean(entry.Vectors))
+	} else {
+		vector, err := s.model.Embed(value)
+		if err != nil {
+			return Entry{}, fmt.Errorf("could not embed Value %s: %v", value, err)
+		}
+		entry.Vector = search.ToFloat32(vector)
+	}
+
+	return entry, nil
+}
+
+// embedEntries embeds values in a single request when the model supports
+// batching
+func (s *Store) embedEntries(values []string) ([]Entry, error) {
+	batcher, ok := s.model.(embed.BatchEmbedder)
+	if s.options.MultiVector || !ok {
+		entries := make([]Entry, len(values))
+		for i, value := range values {
+			entry, err := s.embedEntry(value)
+			if err != nil {
+				return nil, err
+			}
+			entries[i] = entry
+		}
+		return entries, nil
+	}
+
+	vectors, err := batcher.EmbedBatch(values)
+	if err != nil {
+		return nil, fmt.Errorf("could not embed %d values: %v", len(values), err)
+	}
+	if len(vectors) != len(values) {
+		return nil, fmt.Errorf("embedding model returned %d vectors for %d values", len(vectors), len(values))
+	}
+
+	now := time.Now().UnixMilli()
+	entries := make([]Entry, len(values))
+	for i, value := range values {
+		entries[i] = Entry{
+			Value:     value,
+			Vector:    search.ToFloat32(vectors[i]),
+			Timestamp: now,
+		}
+	}
+	return entries, nil
+}
+
+func (s *Store) putEntry(key string, entry Entry) error {
+	err := s.memtable.Put(key, entry, s.destDir)
 	if err != nil {
 		return fmt.Errorf("could not Put data into memtable: %v", err)
 	}
+	if len(entry.Vectors) > 0 {
+		if err := s.tokens.Add(key, entry.Vectors); err != nil {
+			return err
+		}
+	} else {
+		s.tokens.Delete(key)
+	}
+	if entry.Sparse.Len() > 0 {
+		s.sparse.Add(key, entry.Sparse)
+	} else {
+		s.sparse.Delete(key)
+	}
+	// chunked documents are found through their chunks
+	if entry.Deleted || entry.Chunks > 0 {
+		s.text.Delete(key)
+	} else {
+		s.text.Add(key, entry.Value)
+	}
+
+	if s.ivf != nil {
+		if entry.Deleted || len(entry.Vector) == 0 {
+			s.ivf.Delete(key)
+		} else if err := s.ivf.Add(key, entry.Vector); err != nil {
+			return fmt.Errorf("could not index %s: %v", key, err)
+		}
+	}
 
 	// flushed to disk
 	if s.memtable.Size() == 0 {
@@ -60,7 +385,40 @@ func (s *Store) Put(key string, value string) error {
 		if err != nil {
 			return fmt.Errorf("failed to load SSTable: %v", err)
 		}
+		err = s.persistIVF()
+		if err != nil {
+			return err
+		}
+	}
+
+	// after loading a flushed SSTable, so a graph built now sees every entry
+	if s.options.Index == IndexHNSW {
+		return s.indexHNSW(key, entry)
+	}
+	if s.options.Index == IndexDiskANN {
+		return s.indexDelta(key, entry)
+	}
+	return nil
+}
+
+// deleteChunks tombstones the chunks of the document stored under key from
+// index keep onwards
+func (s *Store) deleteChunks(key string, keep int) error {
+	existing, exists := s.get(key)
+	if !exists {
+		return nil
+	}
+
+	for i := keep; i < existing.Chunks; i++ {
+		err := s.putEntry(ChunkKey(key, i), Entry{
+			Deleted:   true,
+			Timestamp: time.Now().UnixMilli(),
+		})
+		if err != nil {
+			return fmt.Errorf("could not delete chunk %d of %s: %v", i, key, err)
+		}
 	}
+
 	return nil
 }
 
@@ -74,12 +432,17 @@ func (s *Store) Delete(key string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	err := s.deleteChunks(key, 0)
+	if err != nil {
+		return err
+	}
+
 	tombstone := Entry{
 		Deleted:   true,
 		Timestamp: time.Now().UnixMilli(),
 	}
 
-	err := s.memtable.Put(key, tombstone, s.destDir)
+	err = s.putEntry(key, tombstone)
 	if err != nil {
 		return fmt.Errorf("could not delete key %s: %v", key, err)
 	}
@@ -87,10 +450,30 @@ func (s *Store) Delete(key string) error {
 	return nil
 }
 
+func (s *Store) embedMulti(text string) ([][]float32, error) {
+	multi, ok := s.model.(embed.MultiVectorEmbedder)
+	if !ok {
+		return nil, fmt.Errorf("embedding model does not produce per-token vectors")
+	}
+	vectors, err := multi.EmbedMulti(text)
+	if err != nil {
+		return nil, err
+	}
+	narrow := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		narrow[i] = search.ToFloat32(vector)
+	}
+	return narrow, nil
+}
+
 func (s *Store) Get(key string) (Entry, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
+	return s.get(key)
+}
+
+func (s *Store) get(key string) (Entry, bool) {
 	entry, exists := s.memtable.Get(key)
 	if exists {
 		if entry.Deleted {
@@ -112,19 +495,54 @@ func (s *Store) Get(key string) (Entry, bool) {
 }
 
 func (s *Store) Search(query string, metric string) ([]Result, error) {
+	return s.SearchWithProbes(query, metric, 0)
+}
+
+// SearchWithProbes is Search scanning nprobe posting lists when the store
+// has an IVF index, instead of its configured default
+func (s *Store) SearchWithProbes(query string, metric string, nprobe int) ([]Result, error) {
+	if metric == MetricMaxSim {
+		return s.searchLateInteraction(query)
+	}
+
 	queryVector, err := s.model.Embed(query)
 	if err != nil {
 		return nil, fmt.Errorf("could not embed query vector: %v", err)
 	}
 
-	var scoreFn func([]float64, []float64) float64
-	switch metric {
-	case "dot":
-		scoreFn = search.Dot
-	case "l2":
-		scoreFn = search.L2
-	case "cosine":
-		scoreFn = search.Cosine
+	return s.SearchVectorWithProbes(search.ToFloat32(queryVector), metric, nprobe)
+}
+
+// SearchVector scores every entry against an already computed vector, such
+// as one read back from a stored entry
+func (s *Store) SearchVector(queryVector []float32, metric string) ([]Result, error) {
+	return s.SearchVectorWithProbes(queryVector, metric, 0)
+}
+
+// SearchVectorWithProbes is SearchVector scanning nprobe posting lists
+// when the store has an IVF index
+func (s *Store) SearchVectorWithProbes(queryVector []float32, metric string, nprobe int) ([]Result, error) {
+	scorer, err := search.NewScorer(metric, queryVector)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckGraphMetric(s.options.Index, metric); err != nil {
+		return nil, err
+	}
+	if s.ivf != nil {
+		return s.searchIVF(queryVector, metric, scorer, nprobe)
+	}
+	if s.options.Index == IndexHNSW {
+		results, searched, err := s.searchHNSW(queryVector, scorer)
+		if searched || err != nil {
+			return results, err
+		}
+	}
+	if s.options.Index == IndexDiskANN {
+		results, searched, err := s.searchDiskANN(queryVector, scorer)
+		if searched || err != nil {
+			return results, err
+		}
 	}
 
 	results := make([]Result, 0)
@@ -136,14 +554,10 @@ func (s *Store) Search(query string, metric string) ([]Result, error) {
 			if err != nil {
 				return nil, fmt.Errorf("could not fetch key %s from sstable: %v", key, err)
 			}
-			if exists && !entry.Deleted { // Only process non-deleted entries
-				score := scoreFn(entry.Vector, queryVector)
+			if exists && !entry.Deleted && len(entry.Vector) == len(queryVector) { // Only process non-deleted entries of the query's size
+				score := scorer.Score(entry.Vector, entry.Norm)
 				if !math.IsNaN(score) && !math.IsInf(score, 0) {
-					results = append(results, Result{
-						Key:   key,
-						Value: entry.Value,
-						Score: score,
-					})
+					results = append(results, newResult(key, entry, score))
 				}
 			}
 		}
@@ -156,14 +570,10 @@ func (s *Store) Search(query string, metric string) ([]Result, error) {
 		if err != nil {
 			continue
 		}
-		if !entry.Deleted {
-			score := scoreFn(entry.Vector, queryVector)
+		if !entry.Deleted && len(entry.Vector) == len(queryVector) {
+			score := scorer.Score(entry.Vector, entry.Norm)
 			if !math.IsNaN(score) && !math.IsInf(score, 0) {
-				results = append(results, Result{
-					Key:   current.key,
-					Value: entry.Value,
-					Score: score,
-				})
+				results = append(results, newResult(current.key, entry, score))
 			}
 		}
 		current = current.next[0]
@@ -176,6 +586,35 @@ func (s *Store) Search(query string, metric string) ([]Result, error) {
 	return results, nil
 }
 
+// searchLateInteraction ranks documents by ColBERT's MaxSim over their
+// per-token vectors
+func (s *Store) searchLateInteraction(query string) ([]Result, error) {
+	if !s.options.MultiVector {
+		return nil, fmt.Errorf("metric %s requires a store opened with multi-vector storage", MetricMaxSim)
+	}
+
+	queryVectors, err := s.embedMulti(query)
+	if err != nil {
+		return nil, fmt.Errorf("could not embed query vectors: %v", err)
+	}
+
+	matches, err := s.tokens.Search(queryVectors, 0, s.options.CandidatesPerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(matches))
+	for _, match := range matches {
+		entry, exists := s.Get(match.ID)
+		if !exists {
+			continue
+		}
+		results = append(results, newResult(match.ID, entry, match.Score))
+	}
+
+	return results, nil
+}
+
 func (s *Store) Flush() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -190,6 +629,10 @@ func (s *Store) Flush() error {
 		if err != nil {
 			return fmt.Errorf("could not load SSTable: %v", err)
 		}
+		err = s.persistIVF()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -2,11 +2,16 @@ package storage
 
 import (
 	"fmt"
+	"github.com/ahhcash/ghastlydb/index"
 	"github.com/ahhcash/ghastlydb/mocks"
+	"github.com/ahhcash/ghastlydb/search"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 )
@@ -183,6 +188,612 @@ func (s *StoreTestSuite) TestSearchAcrossMemtableAndSSTables() {
 	assert.NotContains(s.T(), results, "key1")
 }
 
+func (s *StoreTestSuite) TestLateInteractionSearch() {
+	mockEmbedder := &mocks.MockMultiVectorEmbedder{}
+	mockEmbedder.On("EmbedMulti", "cats and dogs").Return([][]float64{{1, 0, 0}, {0, 1, 0}}, nil)
+	mockEmbedder.On("EmbedMulti", "dogs only").Return([][]float64{{0, 1, 0}}, nil)
+	mockEmbedder.On("EmbedMulti", "fish").Return([][]float64{{0, 0, 1}}, nil)
+	mockEmbedder.On("EmbedMulti", "cats dogs").Return([][]float64{{1, 0, 0}, {0, 1, 0}}, nil)
+
+	options := DefaultStoreOptions()
+	options.MultiVector = true
+	store := NewStoreWithOptions(64, s.testDestDir, mockEmbedder, options)
+
+	assert.NoError(s.T(), store.Put("both", "cats and dogs"))
+	assert.NoError(s.T(), store.Put("dogs", "dogs only"))
+	assert.NoError(s.T(), store.Put("fish", "fish"))
+
+	entry, exists := store.Get("both")
+	assert.True(s.T(), exists)
+	assert.Len(s.T(), entry.Vectors, 2)
+	assert.InDeltaSlice(s.T(), []float64{0.7071067, 0.7071067, 0}, entry.Vector, 0.00001)
+
+	results, err := store.Search("cats dogs", MetricMaxSim)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+	assert.Equal(s.T(), "both", results[0].Key)
+	assert.InDelta(s.T(), 2.0, results[0].Score, 0.000001)
+	assert.Equal(s.T(), "dogs", results[1].Key)
+	assert.InDelta(s.T(), 1.0, results[1].Score, 0.000001)
+	mockEmbedder.AssertNotCalled(s.T(), "Embed", mock.Anything)
+
+	assert.NoError(s.T(), store.Delete("both"))
+	results, err = store.Search("cats dogs", MetricMaxSim)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.Equal(s.T(), "dogs", results[0].Key)
+
+	// with one candidate per query token only the nearest document is scored
+	store.options.CandidatesPerToken = 1
+	results, err = store.Search("fish", MetricMaxSim)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 1)
+	assert.Equal(s.T(), "fish", results[0].Key)
+}
+
+func (s *StoreTestSuite) TestPutChunks() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "about cats.").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "about dogs.").Return([]float64{0, 1}, nil)
+	mockEmbedder.On("Embed", "cats").Return([]float64{1, 0}, nil)
+	store := NewStore(64, s.T().TempDir(), mockEmbedder)
+
+	err := store.PutChunks("doc", "about cats. about dogs.", []string{"about cats.", "about dogs."}, map[string]string{"lang": "en"})
+	assert.NoError(s.T(), err)
+
+	document, exists := store.Get("doc")
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), "about cats. about dogs.", document.Value)
+	assert.Equal(s.T(), 2, document.Chunks)
+	assert.Empty(s.T(), document.Vector)
+
+	chunk, exists := store.Get(ChunkKey("doc", 1))
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), "doc", chunk.Parent)
+	assert.Equal(s.T(), "en", chunk.Metadata["lang"])
+
+	// only chunks are scored, and they point back to their document
+	results, err := store.Search("cats", "cosine")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.Equal(s.T(), ChunkKey("doc", 0), results[0].Key)
+	assert.Equal(s.T(), "doc", results[0].Parent)
+
+	// a shorter version drops the chunks it no longer has
+	err = store.PutChunks("doc", "about cats.", []string{"about cats."}, nil)
+	assert.NoError(s.T(), err)
+	_, exists = store.Get(ChunkKey("doc", 1))
+	assert.False(s.T(), exists)
+
+	assert.NoError(s.T(), store.Delete("doc"))
+	_, exists = store.Get(ChunkKey("doc", 0))
+	assert.False(s.T(), exists)
+
+	err = store.PutChunks("empty", "", nil, nil)
+	assert.Error(s.T(), err)
+
+	// a key shaped like a chunk key would overwrite the chunk, and the
+	// document's next version would delete it
+	assert.NoError(s.T(), store.PutChunks("doc", "about dogs.", []string{"about dogs."}, nil))
+	assert.Error(s.T(), store.Put(ChunkKey("doc", 0), "cats"))
+	assert.Error(s.T(), store.PutChunks("doc#0", "about cats.", []string{"about cats."}, nil))
+	chunk, exists = store.Get(ChunkKey("doc", 0))
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), "about dogs.", chunk.Value)
+	assert.Equal(s.T(), "doc", chunk.Parent)
+}
+
+func (s *StoreTestSuite) TestHybridSearch() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "connection refused while dialing the database").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "error E4012 from the payment gateway").Return([]float64{0, 1}, nil)
+	mockEmbedder.On("Embed", "the database is unreachable").Return([]float64{0.9, 0.1}, nil)
+	mockEmbedder.On("Embed", "database E4012").Return([]float64{1, 0}, nil)
+	store := NewStore(64, s.T().TempDir(), mockEmbedder)
+
+	assert.NoError(s.T(), store.Put("dial", "connection refused while dialing the database"))
+	assert.NoError(s.T(), store.Put("payment", "error E4012 from the payment gateway"))
+	assert.NoError(s.T(), store.Put("unreachable", "the database is unreachable"))
+
+	keyword, err := store.SearchKeyword("database E4012")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), keyword, 3)
+	assert.Equal(s.T(), "payment", keyword[0].Key)
+
+	// vector search alone puts the payment error last
+	vector, err := store.Search("database E4012", "cosine")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "payment", vector[2].Key)
+
+	options := DefaultHybridOptions()
+	results, err := store.SearchHybrid("database E4012", "cosine", options)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+	assert.Equal(s.T(), "dial", results[0].Key)
+	assert.InDelta(s.T(), 1.0/61+1.0/63, results[0].Score, 0.000001)
+
+	// weighted blending with all weight on keywords follows BM25
+	options.Fusion = FusionWeighted
+	options.Alpha = 0
+	results, err = store.SearchHybrid("database E4012", "cosine", options)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "payment", results[0].Key)
+	assert.InDelta(s.T(), 1.0, results[0].Score, 0.000001)
+
+	options.Fusion = "max"
+	_, err = store.SearchHybrid("database E4012", "cosine", options)
+	assert.Error(s.T(), err)
+
+	// deleted entries leave the keyword index
+	assert.NoError(s.T(), store.Delete("payment"))
+	keyword, err = store.SearchKeyword("E4012")
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), keyword)
+}
+
+func (s *StoreTestSuite) TestSparseSearch() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "red apples").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "green pears").Return([]float64{0, 1}, nil)
+	mockEmbedder.On("Embed", "bananas").Return([]float64{0.6, 0.8}, nil)
+	mockEmbedder.On("Embed", "fruit").Return([]float64{1, 0}, nil)
+	store := NewStore(64, s.T().TempDir(), mockEmbedder)
+
+	assert.NoError(s.T(), store.PutSparse("apples", "red apples", search.SparseVector{Indices: []uint32{10, 20}, Values: []float64{1, 0.5}}))
+	assert.NoError(s.T(), store.PutSparse("pears", "green pears", search.SparseVector{Indices: []uint32{30}, Values: []float64{2}}))
+	assert.NoError(s.T(), store.Put("plain", "bananas"))
+
+	entry, exists := store.Get("pears")
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), []uint32{30}, entry.Sparse.Indices)
+
+	query := search.SparseVector{Indices: []uint32{20, 30}, Values: []float64{1, 1}}
+	results, err := store.SearchSparse(query)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.Equal(s.T(), "pears", results[0].Key)
+	assert.InDelta(s.T(), 2.0, results[0].Score, 0.000001)
+
+	// the dense side favors apples, the sparse side pears, and only apples
+	// are near the top of both
+	options := DefaultHybridOptions()
+	options.Fusion = FusionWeighted
+	options.Alpha = 0.8
+	results, err = store.SearchDenseSparse("fruit", query, "cosine", options)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+	assert.Equal(s.T(), "apples", results[0].Key)
+
+	err = store.PutSparse("bad", "red apples", search.SparseVector{Indices: []uint32{1}})
+	assert.Error(s.T(), err)
+
+	// overwriting without a sparse vector drops it from the sparse index
+	assert.NoError(s.T(), store.Put("pears", "green pears"))
+	results, err = store.SearchSparse(query)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 1)
+}
+
+func (s *StoreTestSuite) TestLateInteractionRequiresMultiVector() {
+	_, err := s.store.Search("query", MetricMaxSim)
+	assert.Error(s.T(), err)
+}
+
+func (s *StoreTestSuite) TestSearchRadius() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "near").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "nearer").Return([]float64{1, 0.1}, nil)
+	mockEmbedder.On("Embed", "away").Return([]float64{0, 1}, nil)
+	store := NewStore(32, s.T().TempDir(), mockEmbedder) // small size to force flushes
+
+	assert.NoError(s.T(), store.Put("a", "near"))
+	assert.NoError(s.T(), store.Put("b", "nearer"))
+	assert.NoError(s.T(), store.Put("c", "away"))
+	assert.NoError(s.T(), store.Flush())
+
+	// the newest version decides, older ones left in SSTables never match
+	assert.NoError(s.T(), store.Put("c", "near"))
+	assert.NoError(s.T(), store.Put("b", "away"))
+	assert.NoError(s.T(), store.Delete("a"))
+
+	results, err := store.SearchRadius([]float32{1, 0}, "l2", 0.5)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 1)
+	assert.Equal(s.T(), "c", results[0].Key)
+
+	results, err = store.SearchRadius([]float32{1, 0}, "cosine", -1)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 2)
+	assert.Equal(s.T(), "c", results[0].Key)
+
+	_, err = store.SearchRadius([]float32{1, 0}, "hamming", 1)
+	assert.Error(s.T(), err)
+}
+
+func (s *StoreTestSuite) TestSearchRadiusHNSW() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "near").Return([]float64{1, 0}, nil)
+	mockEmbedder.On("Embed", "nearer").Return([]float64{1, 0.1}, nil)
+	mockEmbedder.On("Embed", "away").Return([]float64{0, 1}, nil)
+	options := DefaultStoreOptions()
+	options.Index = IndexHNSW
+	store := NewStoreWithOptions(1024, s.T().TempDir(), mockEmbedder, options)
+
+	assert.NoError(s.T(), store.Put("a", "near"))
+	assert.NoError(s.T(), store.Put("b", "nearer"))
+	assert.NoError(s.T(), store.Put("c", "away"))
+	assert.NoError(s.T(), store.Put("d", "away"))
+	assert.NoError(s.T(), store.Put("d", "near"))
+	assert.NoError(s.T(), store.Delete("a"))
+
+	results, err := store.SearchRadius([]float32{1, 0}, "l2", 0.5)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"d", "b"}, resultKeys(results))
+	assert.InDelta(s.T(), 0.1, results[1].Score, 1e-6)
+
+	// l2 radii walk the graph, so an entry it lost is only found by the
+	// exact scan, as are cosine radii
+	assert.NoError(s.T(), store.hnsw.Delete("b"))
+	results, err = store.SearchRadius([]float32{1, 0}, "l2", 0.5)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"d"}, resultKeys(results))
+	results, err = store.SearchRadiusExact([]float32{1, 0}, "l2", 0.5)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"d", "b"}, resultKeys(results))
+	results, err = store.SearchRadius([]float32{1, 0}, "cosine", 0.9)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"d", "b"}, resultKeys(results))
+}
+
+func (s *StoreTestSuite) TestIVFIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "north").Return([]float64{0, 1}, nil)
+	mockEmbedder.On("Embed", "north east").Return([]float64{0.1, 1}, nil)
+	mockEmbedder.On("Embed", "south").Return([]float64{0, -1}, nil)
+	mockEmbedder.On("Embed", "south west").Return([]float64{-0.1, -1}, nil)
+	dir := s.T().TempDir()
+
+	options := DefaultStoreOptions()
+	options.Index = IndexIVF
+	options.IVF.NLists = 2
+	options.IVF.NProbe = 1
+	store := NewStoreWithOptions(1024, dir, mockEmbedder, options)
+
+	assert.Error(s.T(), NewStore(1024, s.T().TempDir(), mockEmbedder).TrainIndex())
+
+	assert.NoError(s.T(), store.Put("n", "north"))
+	assert.NoError(s.T(), store.Put("ne", "north east"))
+	assert.NoError(s.T(), store.Put("s", "south"))
+	assert.NoError(s.T(), store.Put("sw", "south west"))
+
+	// untrained, every vector is scanned
+	results, err := store.Search("north", "cosine")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 4)
+
+	assert.NoError(s.T(), store.TrainIndex())
+	results, err = store.Search("north", "cosine")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"n", "ne"}, resultKeys(results))
+
+	results, err = store.SearchWithProbes("north", "cosine", 2)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 4)
+
+	// deleted and overwritten entries leave their lists
+	assert.NoError(s.T(), store.Delete("ne"))
+	assert.NoError(s.T(), store.Put("sw", "north east"))
+	results, err = store.Search("north", "cosine")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"n", "sw"}, resultKeys(results))
+
+	// the index is saved next to the SSTables by training, a flush only
+	// logs what changed since
+	assert.NoError(s.T(), store.Flush())
+	assert.FileExists(s.T(), filepath.Join(dir, ivfFile))
+	assert.FileExists(s.T(), filepath.Join(dir, ivfFile+".log"))
+	reopened := NewStoreWithOptions(1024, dir, mockEmbedder, options)
+	assert.True(s.T(), reopened.ivf.Trained())
+	assert.Equal(s.T(), 3, reopened.ivf.Len())
+	nearest, err := reopened.ivf.Search([]float32{0.1, 1}, 2, 1)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "sw", nearest[0].ID)
+}
+
+func (s *StoreTestSuite) TestIVFPQRescoring() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	texts := []string{"a", "b", "c", "d", "e", "f"}
+	for i, text := range texts {
+		mockEmbedder.On("Embed", text).Return([]float64{float64(i), float64(i % 2), 1, 0}, nil)
+	}
+	mockEmbedder.On("Embed", "query").Return([]float64{2.2, 0, 1, 0}, nil)
+
+	options := DefaultStoreOptions()
+	options.Index = IndexIVF
+	options.IVF.NLists = 1
+	options.IVF.PQ.Subspaces = 2
+	options.Rescore = 2
+	store := NewStoreWithOptions(1024, s.T().TempDir(), mockEmbedder, options)
+	for _, text := range texts {
+		assert.NoError(s.T(), store.Put(text, text))
+	}
+	assert.NoError(s.T(), store.TrainIndex())
+	assert.True(s.T(), store.ivf.Quantized())
+
+	// only the best two codes are returned, with exact distances
+	results, err := store.Search("query", "l2")
+	assert.NoError(s.T(), err)
+	assert.ElementsMatch(s.T(), []string{"c", "d"}, resultKeys(results))
+	for _, result := range results {
+		entry, _ := store.Get(result.Key)
+		assert.InDelta(s.T(), search.L2(entry.Vector, []float32{2.2, 0, 1, 0}), result.Score, 1e-9)
+	}
+
+	// retraining rebuilds from the stored vectors
+	assert.NoError(s.T(), store.Put("g", "a"))
+	assert.NoError(s.T(), store.TrainIndex())
+	assert.Equal(s.T(), 7, store.ivf.Len())
+
+	store.options.Rescore = 0
+	results, err = store.Search("query", "l2")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 7)
+}
+
+func (s *StoreTestSuite) TestFlatPQIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	texts := []string{"a", "b", "c", "d", "e", "f"}
+	for i, text := range texts {
+		mockEmbedder.On("Embed", text).Return([]float64{float64(i), float64(i % 2), 1, 0}, nil)
+	}
+	mockEmbedder.On("Embed", "query").Return([]float64{2.2, 0, 1, 0}, nil)
+
+	// the default subspaces are capped at the 4 dimensions
+	options := DefaultStoreOptions()
+	options.Index = IndexPQ
+	options.IVF.NLists = 8
+	options.Rescore = 2
+	store := NewStoreWithOptions(1024, s.T().TempDir(), mockEmbedder, options)
+	for _, text := range texts {
+		assert.NoError(s.T(), store.Put(text, text))
+	}
+	assert.NoError(s.T(), store.TrainIndex())
+	assert.True(s.T(), store.ivf.Quantized())
+
+	// every code is scanned whatever the probes, and the best two are
+	// scored again by their vectors
+	results, err := store.SearchVectorWithProbes([]float32{2.2, 0, 1, 0}, "l2", 1)
+	assert.NoError(s.T(), err)
+	assert.ElementsMatch(s.T(), []string{"c", "d"}, resultKeys(results))
+	for _, result := range results {
+		entry, _ := store.Get(result.Key)
+		assert.InDelta(s.T(), search.L2(entry.Vector, []float32{2.2, 0, 1, 0}), result.Score, 1e-9)
+	}
+
+	store.options.Rescore = 0
+	results, err = store.Search("query", "l2")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 6)
+}
+
+func (s *StoreTestSuite) TestHNSWQuantizedIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "north").Return([]float64{0, 1, 0.5}, nil)
+	mockEmbedder.On("Embed", "north east").Return([]float64{0.2, 1, 0.4}, nil)
+	mockEmbedder.On("Embed", "south").Return([]float64{0, -1, -0.5}, nil)
+	mockEmbedder.On("Embed", "south west").Return([]float64{-0.2, -1, -0.4}, nil)
+	query := []float32{0, 1, 0.5}
+
+	for _, quantization := range []string{index.QuantizationInt8, index.QuantizationBinary} {
+		options := DefaultStoreOptions()
+		options.Index = IndexHNSW
+		options.HNSW.Quantization = quantization
+		options.HNSWTrainAfter = 3
+		options.Candidates = 2
+		options.Oversample = 2
+		store := NewStoreWithOptions(1024, s.T().TempDir(), mockEmbedder, options)
+
+		assert.NoError(s.T(), store.Put("n", "north"))
+		assert.NoError(s.T(), store.Put("s", "south"))
+		if quantization == index.QuantizationInt8 {
+			// until the ranges are trained every vector is scanned
+			assert.Nil(s.T(), store.hnsw)
+			results, err := store.Search("north", "l2")
+			assert.NoError(s.T(), err)
+			assert.Len(s.T(), results, 2)
+		}
+
+		assert.NoError(s.T(), store.Put("ne", "north east"))
+		assert.NoError(s.T(), store.Put("sw", "south west"))
+		assert.NotNil(s.T(), store.hnsw)
+
+		// the nearest neighbors come back with exact scores
+		results, err := store.Search("north", "l2")
+		assert.NoError(s.T(), err)
+		assert.ElementsMatch(s.T(), []string{"n", "ne"}, resultKeys(results), quantization)
+		for _, result := range results {
+			entry, _ := store.Get(result.Key)
+			assert.InDelta(s.T(), search.L2(entry.Vector, query), result.Score, 1e-9)
+		}
+
+		// deleted and overwritten entries leave the graph
+		assert.NoError(s.T(), store.Delete("ne"))
+		assert.NoError(s.T(), store.Put("sw", "north east"))
+		results, err = store.Search("north", "l2")
+		assert.NoError(s.T(), err)
+		assert.ElementsMatch(s.T(), []string{"n", "sw"}, resultKeys(results), quantization)
+
+		assert.NoError(s.T(), store.TrainIndex())
+		results, err = store.Search("north", "l2")
+		assert.NoError(s.T(), err)
+		assert.ElementsMatch(s.T(), []string{"n", "sw"}, resultKeys(results), quantization)
+
+		// the graph is walked by l2 distance, it can't rank by dot product
+		_, err = store.Search("north", "dot")
+		assert.Error(s.T(), err)
+	}
+}
+
+func (s *StoreTestSuite) TestDiskANNIndex() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	mockEmbedder.On("Embed", "north").Return([]float64{0, 1, 0.5}, nil)
+	mockEmbedder.On("Embed", "north east").Return([]float64{0.2, 1, 0.4}, nil)
+	mockEmbedder.On("Embed", "south").Return([]float64{0, -1, -0.5}, nil)
+	mockEmbedder.On("Embed", "south west").Return([]float64{-0.2, -1, -0.4}, nil)
+
+	dir := s.T().TempDir()
+	options := DefaultStoreOptions()
+	options.Index = IndexDiskANN
+	options.DiskANN.PQ.Subspaces = 3
+	options.Candidates = 2
+	store := NewStoreWithOptions(1024, dir, mockEmbedder, options)
+
+	// until the disk index is built every vector is scanned
+	assert.NoError(s.T(), store.Put("n", "north"))
+	assert.NoError(s.T(), store.Put("s", "south"))
+	assert.NoError(s.T(), store.Put("sw", "south west"))
+	assert.Nil(s.T(), store.disk)
+	results, err := store.Search("north", "l2")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results, 3)
+
+	assert.NoError(s.T(), store.TrainIndex())
+	assert.NotNil(s.T(), store.disk)
+	assert.Equal(s.T(), 3, store.disk.Len())
+	assert.FileExists(s.T(), filepath.Join(dir, diskANNFile))
+	// the vectors spilled for the build are gone once it is done
+	assert.NoFileExists(s.T(), filepath.Join(dir, diskANNFile+".vectors"))
+
+	// writes after the build are found through the delta graph, deleted
+	// and overwritten entries are scored by their current state
+	assert.NoError(s.T(), store.Put("ne", "north east"))
+	assert.NoError(s.T(), store.Delete("n"))
+	assert.NoError(s.T(), store.Put("s", "north"))
+	results, err = store.Search("north", "l2")
+	assert.NoError(s.T(), err)
+	assert.Subset(s.T(), resultKeys(results), []string{"ne", "s"})
+	assert.NotContains(s.T(), resultKeys(results), "n")
+	for _, result := range results {
+		entry, _ := store.Get(result.Key)
+		assert.InDelta(s.T(), search.L2(entry.Vector, []float32{0, 1, 0.5}), result.Score, 1e-9)
+	}
+
+	_, err = store.Search("north", "dot")
+	assert.Error(s.T(), err)
+
+	// a reopened store finds the disk index it built
+	reopened := NewStoreWithOptions(1024, dir, mockEmbedder, options)
+	assert.NotNil(s.T(), reopened.disk)
+	assert.Equal(s.T(), 3, reopened.disk.Len())
+}
+
+func (s *StoreTestSuite) TestDiskANNBuildAllowsWrites() {
+	mockEmbedder := &mocks.MockEmbedder{}
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 1000; i++ {
+		mockEmbedder.On("Embed", fmt.Sprintf("v%d", i)).Return([]float64{rng.Float64(), rng.Float64(), rng.Float64()}, nil)
+	}
+	mid := make([][]float32, 500)
+	for i := range mid {
+		mid[i] = []float32{10 + float32(i), 0, 0}
+		mockEmbedder.On("Embed", fmt.Sprintf("mid%d", i)).Return([]float64{10 + float64(i), 0, 0}, nil)
+	}
+
+	options := DefaultStoreOptions()
+	options.Index = IndexDiskANN
+	store := NewStoreWithOptions(4096, s.T().TempDir(), mockEmbedder, options)
+	for i := 0; i < 1000; i++ {
+		require.NoError(s.T(), store.Put(fmt.Sprintf("v%d", i), fmt.Sprintf("v%d", i)))
+	}
+
+	done := make(chan error)
+	go func() { done <- store.TrainIndex() }()
+
+	// the build doesn't hold the store lock, so writes go on meanwhile
+	written := 0
+	var buildErr error
+writes:
+	for ; written < len(mid); written++ {
+		select {
+		case buildErr = <-done:
+			break writes
+		default:
+		}
+		require.NoError(s.T(), store.Put(fmt.Sprintf("mid%d", written), fmt.Sprintf("mid%d", written)))
+	}
+	if written == len(mid) {
+		buildErr = <-done
+	}
+	require.NoError(s.T(), buildErr)
+	assert.Greater(s.T(), written, 1)
+
+	// only one build runs at a time
+	store.lock.Lock()
+	store.diskPending = map[string]bool{}
+	store.lock.Unlock()
+	assert.Error(s.T(), store.TrainIndex())
+	store.lock.Lock()
+	store.diskPending = nil
+	store.lock.Unlock()
+
+	// the writes made during the build are replayed into the new delta
+	// graph, every vector is indexed once
+	assert.Greater(s.T(), store.delta.Len(), 0)
+	assert.Equal(s.T(), 1000+written, store.disk.Len()+store.delta.Len())
+	results, err := store.SearchVector(mid[written-1], "l2")
+	require.NoError(s.T(), err)
+	assert.Contains(s.T(), resultKeys(results), fmt.Sprintf("mid%d", written-1))
+}
+
+func (s *StoreTestSuite) TestMigrate() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "old.sst")
+	file, err := os.Create(path)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), writeRecord(file, "a", version1Entry(false, "alpha", []float64{1, 0.5}, nil)))
+	assert.NoError(s.T(), writeRecord(file, "b", version1Entry(true, "", nil, nil)))
+	assert.NoError(s.T(), file.Close())
+	before, err := os.Stat(path)
+	assert.NoError(s.T(), err)
+
+	migrated, err := Migrate(dir, PrecisionFloat32)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, migrated)
+
+	// float32 vectors take half the space
+	after, err := os.Stat(path)
+	assert.NoError(s.T(), err)
+	assert.Less(s.T(), after.Size(), before.Size())
+
+	sstable, err := OpenSSTable(path)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"a", "b"}, sstable.Index)
+	raw, err := sstable.value(0)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), byte(FormatVersion), raw[0]>>1)
+	entry, exists, err := sstable.Get("a")
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), exists)
+	assert.Equal(s.T(), "alpha", entry.Value)
+	assert.Equal(s.T(), []float32{1, 0.5}, entry.Vector)
+	entry, _, err = sstable.Get("b")
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), entry.Deleted)
+	assert.NoError(s.T(), sstable.Close())
+
+	// current tables are left alone
+	migrated, err = Migrate(dir, PrecisionFloat32)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, migrated)
+
+	migrated, err = Migrate(dir, PrecisionBFloat16)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, migrated)
+
+	_, err = Migrate(dir, "float8")
+	assert.Error(s.T(), err)
+}
+
 func TestStoreSuite(t *testing.T) {
 	suite.Run(t, new(StoreTestSuite))
 }